@@ -114,7 +114,7 @@ clean_all() {
     
     # 清理嵌入的前端文件
     if [ -d "$FRONTEND_EMBED_DIR" ]; then
-        find "$FRONTEND_EMBED_DIR" -name "*.html" -o -name "*.js" -o -name "*.css" -o -name "*.json" -o -name "*.png" -o -name "*.jpg" -o -name "*.svg" -o -name "*.ico" | xargs rm -f 2>/dev/null || true
+        find "$FRONTEND_EMBED_DIR" -name "*.html" -o -name "*.js" -o -name "*.css" -o -name "*.json" -o -name "*.png" -o -name "*.jpg" -o -name "*.svg" -o -name "*.ico" -o -name "*.br" -o -name "*.gz" | xargs rm -f 2>/dev/null || true
     fi
     
     # 清理输出目录
@@ -191,6 +191,10 @@ copy_frontend_files() {
     # 复制所有文件
     if [ -d "$FRONTEND_OUT_DIR" ]; then
         cp -r "$FRONTEND_OUT_DIR"/* "$FRONTEND_EMBED_DIR/" 2>/dev/null || true
+
+        # 预先生成 brotli 压缩版本，gzip 版本由服务器启动时生成
+        print_verbose "生成 brotli 压缩文件..."
+        node "$FRONTEND_DIR/scripts/precompress.js" "$FRONTEND_EMBED_DIR"
         
         file_count=$(find "$FRONTEND_EMBED_DIR" -type f ! -name ".gitkeep" | wc -l)
         total_size=$(du -sh "$FRONTEND_EMBED_DIR" 2>/dev/null | cut -f1 || echo "未知")
@@ -0,0 +1,56 @@
+#!/usr/bin/env node
+/**
+ * 为静态导出的文本文件生成 brotli 压缩版本（同名加 .br 后缀）
+ * Go 服务器嵌入这些文件，按 Accept-Encoding 直接返回，gzip 版本由服务器启动时生成
+ *
+ * 用法：node scripts/precompress.js <目录>
+ */
+const fs = require('fs');
+const path = require('path');
+const zlib = require('zlib');
+
+const COMPRESSIBLE = new Set(['.html', '.css', '.js', '.json', '.txt', '.svg', '.ico']);
+const MIN_SIZE = 1024; // 与服务器的 minCompressSize 一致
+
+function walk(dir, files = []) {
+  for (const entry of fs.readdirSync(dir, { withFileTypes: true })) {
+    const full = path.join(dir, entry.name);
+    if (entry.isDirectory()) {
+      walk(full, files);
+    } else if (COMPRESSIBLE.has(path.extname(entry.name))) {
+      files.push(full);
+    }
+  }
+  return files;
+}
+
+const root = process.argv[2];
+if (!root || !fs.existsSync(root)) {
+  console.error('用法: node scripts/precompress.js <目录>');
+  process.exit(1);
+}
+
+let count = 0;
+let before = 0;
+let after = 0;
+for (const file of walk(root)) {
+  const content = fs.readFileSync(file);
+  if (content.length < MIN_SIZE) {
+    continue;
+  }
+  const compressed = zlib.brotliCompressSync(content, {
+    params: {
+      [zlib.constants.BROTLI_PARAM_QUALITY]: zlib.constants.BROTLI_MAX_QUALITY,
+      [zlib.constants.BROTLI_PARAM_SIZE_HINT]: content.length,
+    },
+  });
+  if (compressed.length >= content.length) {
+    continue;
+  }
+  fs.writeFileSync(file + '.br', compressed);
+  count++;
+  before += content.length;
+  after += compressed.length;
+}
+
+console.log(`已生成 ${count} 个 brotli 文件: ${(before / 1024).toFixed(0)} KB -> ${(after / 1024).toFixed(0)} KB`);
@@ -1,16 +1,31 @@
 "use client";
 
-import React from 'react';
+import React, { useEffect, useState } from 'react';
 import { Github } from 'lucide-react';
+import { getRuntimeConfig, RuntimeConfig } from '@/lib/config';
 
 export default function Hero() {
+  // 服务器注入的品牌信息在挂载后读取，避免与静态导出的 HTML 不一致
+  const [branding, setBranding] = useState<NonNullable<RuntimeConfig['branding']>>({});
+
+  useEffect(() => {
+    const injected = getRuntimeConfig().branding ?? {};
+    setBranding(injected);
+    if (injected.name) {
+      document.title = injected.name;
+    }
+  }, []);
+
   return (
     <div className="text-center mb-6 animate-fade-in-up">
+      {branding.logo_url && (
+        <img src={branding.logo_url} alt={branding.name || '文件快传'} className="h-12 w-auto mx-auto mb-2" />
+      )}
       <h1 className="text-2xl sm:text-3xl md:text-4xl font-bold bg-gradient-to-r from-blue-600 via-purple-600 to-indigo-600 bg-clip-text text-transparent mb-2">
-        文件快传
+        {branding.name || '文件快传'}
       </h1>
       <p className="text-sm sm:text-base text-slate-600 max-w-xl mx-auto leading-relaxed px-4 mb-3">
-        安全、快速、简单的传输服务
+        {branding.description || '安全、快速、简单的传输服务'}
         <br />
         <span className="text-xs sm:text-sm text-slate-500">基于WebRTC的端到端服务 - 无需注册，即传即用</span>
       </p>
@@ -90,13 +90,10 @@ export const WebRTCFileTransfer: React.FC = () => {
       
       const response = await fetch(`/api/room-info?code=${trimmedCode}`);
       
-      if (!response.ok) {
-        throw new Error(`HTTP ${response.status}: 无法检查房间状态`);
-      }
-      
       const result = await response.json();
       
-      if (!result.success) {
+      // 房间不存在等错误返回非 2xx 状态码，错误描述在 message 中
+      if (!response.ok || !result.success) {
         let errorMessage = result.message || '房间不存在或已过期';
         if (result.message?.includes('expired')) {
           errorMessage = '房间已过期，请联系发送方重新创建';
@@ -112,6 +109,12 @@ export const WebRTCFileTransfer: React.FC = () => {
         showToast('发送方不在线，请确认取件码是否正确或联系发送方', "error");
         return;
       }
+
+      // 取件码属于文字传输或桌面共享房间
+      if (result.type && result.type !== 'file') {
+        showToast('该取件码不是文件传输的取件码', "error");
+        return;
+      }
       
       console.log('房间状态检查通过，开始连接...');
       setPickupCode(trimmedCode);
@@ -185,13 +188,10 @@ export const WebRTCFileTransfer: React.FC = () => {
               console.log('检查房间状态...');
               const response = await fetch(`/api/room-info?code=${trimmedCode}`);
               
-              if (!response.ok) {
-                throw new Error(`HTTP ${response.status}: 无法检查房间状态`);
-              }
-              
               const result = await response.json();
               
-              if (!result.success) {
+              // 房间不存在等错误返回非 2xx 状态码，错误描述在 message 中
+              if (!response.ok || !result.success) {
                 let errorMessage = result.message || '房间不存在或已过期';
                 if (result.message?.includes('expired')) {
                   errorMessage = '房间已过期，请联系发送方重新创建';
@@ -206,6 +206,11 @@ export const WebRTCFileTransfer: React.FC = () => {
                 showToast('发送方不在线，请确认取件码是否正确或联系发送方', "error");
                 return;
               }
+
+              if (result.type && result.type !== 'file') {
+                showToast('该取件码不是文件传输的取件码', "error");
+                return;
+              }
               
               console.log('房间状态检查通过，开始连接...');
               setPickupCode(trimmedCode);
@@ -329,7 +334,7 @@ export const WebRTCFileTransfer: React.FC = () => {
       const data = await response.json();
       
       if (!response.ok) {
-        throw new Error(data.error || '创建房间失败');
+        throw new Error(data.details?.notice || data.message || data.error || '创建房间失败');
       }
 
       const code = data.code;
@@ -1,6 +1,14 @@
 "use client";
 
-import React, { createContext, useContext, useState, useCallback } from 'react';
+import React, { createContext, useContext, useState, useCallback, useEffect } from 'react';
+
+// 服务器公告事件，信令连接收到 server-notice 消息时在 window 上派发
+export const SERVER_NOTICE_EVENT = 'chuan:server-notice';
+
+export interface ServerNotice {
+  message: string;
+  level: 'info' | 'warning';
+}
 
 interface Toast {
   id: string;
@@ -37,6 +45,18 @@ export const ToastProvider: React.FC<{ children: React.ReactNode }> = ({ childre
     }, 3000);
   }, []);
 
+  // 服务器公告以提示的形式显示
+  useEffect(() => {
+    const onNotice = (event: Event) => {
+      const notice = (event as CustomEvent<ServerNotice>).detail;
+      if (notice?.message) {
+        showToast(notice.message, notice.level === 'warning' ? 'error' : 'info');
+      }
+    };
+    window.addEventListener(SERVER_NOTICE_EVENT, onNotice);
+    return () => window.removeEventListener(SERVER_NOTICE_EVENT, onNotice);
+  }, [showToast]);
+
   const removeToast = useCallback((id: string) => {
     setToasts(prev => prev.filter(toast => toast.id !== id));
   }, []);
@@ -47,7 +47,7 @@ export const WebRTCTextReceiver: React.FC<WebRTCTextReceiverProps> = ({
   const connectAll = useCallback(async (code: string, role: 'sender' | 'receiver') => {
     console.log('=== 连接所有传输通道 ===', { code, role });
     // 只需要连接一次，因为使用的是共享连接
-    await connection.connect(code, role);
+    await connection.connect(code, role, 'text');
   }, [connection]);
 
   // 是否有任何连接
@@ -141,7 +141,7 @@ export const WebRTCTextReceiver: React.FC<WebRTCTextReceiverProps> = ({
       const roomData = await response.json();
       
       if (!response.ok) {
-        throw new Error(roomData.error || '房间不存在或已过期');
+        throw new Error(roomData.message || roomData.error || '房间不存在或已过期');
       }
 
       console.log('=== 房间验证成功 ===', roomData);
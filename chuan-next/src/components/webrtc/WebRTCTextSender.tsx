@@ -41,7 +41,7 @@ export const WebRTCTextSender: React.FC<WebRTCTextSenderProps> = ({ onRestart, o
   const connectAll = useCallback(async (code: string, role: 'sender' | 'receiver') => {
     console.log('=== 连接所有传输通道 ===', { code, role });
     // 只需要连接一次，因为使用的是共享连接
-    await connection.connect(code, role);
+    await connection.connect(code, role, 'text');
   }, [connection]);
 
   // 是否有任何连接
@@ -121,7 +121,7 @@ export const WebRTCTextSender: React.FC<WebRTCTextSenderProps> = ({ onRestart, o
           'Content-Type': 'application/json',
         },
         body: JSON.stringify({
-          type: 'message',
+          type: 'text',
           initialText: currentText || '', 
           hasImages: false,
           maxFileSize: 5 * 1024 * 1024,
@@ -135,7 +135,7 @@ export const WebRTCTextSender: React.FC<WebRTCTextSenderProps> = ({ onRestart, o
       const data = await response.json();
       
       if (!response.ok) {
-        throw new Error(data.error || '创建房间失败');
+        throw new Error(data.details?.notice || data.message || data.error || '创建房间失败');
       }
 
       const code = data.code;
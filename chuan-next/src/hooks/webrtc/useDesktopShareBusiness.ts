@@ -183,7 +183,7 @@ export function useDesktopShareBusiness() {
 
       // 建立WebRTC连接（作为发送方）
       console.log('[DesktopShare] 📡 正在建立WebRTC连接...');
-      await webRTC.connect(roomCode, 'sender');
+      await webRTC.connect(roomCode, 'sender', 'desktop');
       console.log('[DesktopShare] ✅ WebSocket连接已建立');
 
       updateState({
@@ -326,7 +326,7 @@ export function useDesktopShareBusiness() {
 
       // 连接WebRTC
       console.log('[DesktopShare] 🔗 正在连接WebRTC作为接收方...');
-      await webRTC.connect(code, 'receiver');
+      await webRTC.connect(code, 'receiver', 'desktop');
       console.log('[DesktopShare] ✅ WebRTC连接建立完成');
 
       // 等待连接完全建立
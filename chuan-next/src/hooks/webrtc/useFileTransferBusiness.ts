@@ -214,6 +214,8 @@ export function useFileTransferBusiness(connection: WebRTCConnection) {
           }));
 
           fileReceivedCallbacks.current.forEach(cb => cb({ id: fileId, file }));
+          // 通知服务器传输完成，房间状态变为 completed
+          connection.sendSignal('transfer-complete', { fileId });
           receivingFiles.current.delete(fileId);
           receiveProgress.current.delete(fileId);
           
@@ -266,7 +268,7 @@ export function useFileTransferBusiness(connection: WebRTCConnection) {
         }
         break;
     }
-  }, [updateState]);
+  }, [updateState, connection]);
 
   // 处理文件块数据
   const handleData = useCallback((data: ArrayBuffer) => {
@@ -367,7 +369,7 @@ export function useFileTransferBusiness(connection: WebRTCConnection) {
 
   // 连接
   const connect = useCallback((roomCode: string, role: 'sender' | 'receiver') => {
-    return connection.connect(roomCode, role);
+    return connection.connect(roomCode, role, 'file');
   }, [connection]);
 
   // 安全发送单个文件块
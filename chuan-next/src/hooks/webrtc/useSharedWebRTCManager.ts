@@ -1,6 +1,7 @@
 import { useState, useRef, useCallback } from 'react';
-import { getWsUrl } from '@/lib/config';
+import { getDirectBackendUrl, getRuntimeConfig, getWsUrl } from '@/lib/config';
 import { useWebRTCStore } from './webRTCStore';
+import { SERVER_NOTICE_EVENT, type ServerNotice } from '@/components/ui/toast-simple';
 
 // 基础连接状态
 interface WebRTCState {
@@ -22,6 +23,9 @@ interface WebRTCMessage {
 type MessageHandler = (message: WebRTCMessage) => void;
 type DataHandler = (data: ArrayBuffer) => void;
 
+// 房间类型，加入时声明的类型必须与房间一致；shared 表示不限
+export type RoomType = 'file' | 'text' | 'desktop' | 'shared';
+
 // WebRTC 连接接口
 export interface WebRTCConnection {
   // 状态
@@ -32,10 +36,11 @@ export interface WebRTCConnection {
   error: string | null;
 
   // 操作方法
-  connect: (roomCode: string, role: 'sender' | 'receiver') => Promise<void>;
+  connect: (roomCode: string, role: 'sender' | 'receiver', roomType?: RoomType) => Promise<void>;
   disconnect: () => void;
   sendMessage: (message: WebRTCMessage, channel?: string) => boolean;
   sendData: (data: ArrayBuffer) => boolean;
+  sendSignal: (type: string, payload?: Record<string, unknown>) => boolean;  // 通过信令服务器发送，例如 transfer-complete
 
   // 处理器注册
   registerMessageHandler: (channel: string, handler: MessageHandler) => () => void;
@@ -76,14 +81,34 @@ export function useSharedWebRTCManager(): WebRTCConnection {
   const messageHandlers = useRef<Map<string, MessageHandler>>(new Map());
   const dataHandlers = useRef<Map<string, DataHandler>>(new Map());
 
-  // STUN 服务器配置 - 使用更稳定的服务器
-  const STUN_SERVERS = [
+  // 默认 STUN 服务器，无法从服务器获取 ICE 配置时使用
+  const STUN_SERVERS: RTCIceServer[] = [
     { urls: 'stun:stun.l.google.com:19302' },
     { urls: 'stun:stun1.l.google.com:19302' },
     { urls: 'stun:stun2.l.google.com:19302' },
     { urls: 'stun:global.stun.twilio.com:3478' },
   ];
 
+  // 从服务器获取 STUN/TURN 配置，服务器可以在不重启的情况下更新；获取失败时使用页面注入的配置
+  const loadIceServers = useCallback(async (): Promise<RTCIceServer[]> => {
+    try {
+      const response = await fetch(getDirectBackendUrl('/api/ice-servers'), { cache: 'no-store' });
+      if (response.ok) {
+        const data = await response.json();
+        if (Array.isArray(data.ice_servers) && data.ice_servers.length > 0) {
+          return data.ice_servers;
+        }
+      }
+    } catch (error) {
+      console.warn('[SharedWebRTC] ⚠️ 获取ICE服务器配置失败，使用默认配置:', error);
+    }
+    const injected = getRuntimeConfig().ice_servers;
+    if (Array.isArray(injected) && injected.length > 0) {
+      return injected;
+    }
+    return STUN_SERVERS;
+  }, []);
+
   const updateState = useCallback((updates: Partial<WebRTCState>) => {
     webrtcStore.updateState(updates);
   }, [webrtcStore]);
@@ -195,8 +220,8 @@ export function useSharedWebRTCManager(): WebRTCConnection {
   }, []);
 
   // 连接到房间
-  const connect = useCallback(async (roomCode: string, role: 'sender' | 'receiver') => {
-    console.log('[SharedWebRTC] 🚀 开始连接到房间:', roomCode, role);
+  const connect = useCallback(async (roomCode: string, role: 'sender' | 'receiver', roomType: RoomType = 'shared') => {
+    console.log('[SharedWebRTC] 🚀 开始连接到房间:', roomCode, role, roomType);
 
     // 如果正在连接中，避免重复连接
     if (webrtcStore.isConnecting) {
@@ -217,7 +242,7 @@ export function useSharedWebRTCManager(): WebRTCConnection {
       console.log('[SharedWebRTC] 🔧 创建PeerConnection...');
       // 创建 PeerConnection
       const pc = new RTCPeerConnection({
-        iceServers: STUN_SERVERS,
+        iceServers: await loadIceServers(),
         iceCandidatePoolSize: 10,
       });
       pcRef.current = pc;
@@ -229,7 +254,7 @@ export function useSharedWebRTCManager(): WebRTCConnection {
       }
       
       // 构建完整的WebSocket URL
-      const wsUrl = baseWsUrl.replace('/ws/p2p', `/ws/webrtc?code=${roomCode}&role=${role}&channel=shared`);
+      const wsUrl = baseWsUrl.replace('/ws/p2p', `/ws/webrtc?code=${roomCode}&role=${role}&channel=${roomType}`);
       console.log('[SharedWebRTC] 🌐 连接WebSocket:', wsUrl);
       const ws = new WebSocket(wsUrl);
       wsRef.current = ws;
@@ -324,6 +349,24 @@ export function useSharedWebRTCManager(): WebRTCConnection {
               }
               break;
 
+            case 'server-shutdown':
+              // 服务器即将重启，连接会在等待期结束后断开
+              console.warn('[SharedWebRTC] ⚠️ 服务器即将重启，建议', message.payload?.reconnect_after, '秒后重新连接');
+              updateState({ error: message.payload?.message || '服务器即将重启，请稍后重新连接' });
+              break;
+
+            case 'server-notice':
+              // 运营公告，由 ToastProvider 显示
+              console.log('[SharedWebRTC] 📢 服务器公告:', message.payload?.message);
+              window.dispatchEvent(new CustomEvent<ServerNotice>(SERVER_NOTICE_EVENT, {
+                detail: { message: message.payload?.message || '', level: message.payload?.level || 'info' },
+              }));
+              break;
+
+            case 'transfer-complete':
+              console.log('[SharedWebRTC] ✅ 对方确认传输完成');
+              break;
+
             case 'error':
               console.error('[SharedWebRTC] ❌ 信令服务器错误:', message.error);
               updateState({ error: message.error, isConnecting: false });
@@ -477,7 +520,7 @@ export function useSharedWebRTCManager(): WebRTCConnection {
         isConnecting: false
       });
     }
-  }, [updateState, cleanup, createOffer, handleDataChannelMessage, webrtcStore.isConnecting, webrtcStore.isConnected]);
+  }, [updateState, cleanup, createOffer, loadIceServers, handleDataChannelMessage, webrtcStore.isConnecting, webrtcStore.isConnected]);
 
   // 断开连接
   const disconnect = useCallback(() => {
@@ -505,6 +548,17 @@ export function useSharedWebRTCManager(): WebRTCConnection {
     }
   }, []);
 
+  // 通过信令服务器发送消息，服务器据此更新房间状态并转发给对方
+  const sendSignal = useCallback((type: string, payload?: Record<string, unknown>) => {
+    const ws = wsRef.current;
+    if (!ws || ws.readyState !== WebSocket.OPEN) {
+      console.warn('[SharedWebRTC] WebSocket未连接，无法发送信令:', type);
+      return false;
+    }
+    ws.send(JSON.stringify({ type, payload }));
+    return true;
+  }, []);
+
   // 发送二进制数据
   const sendData = useCallback((data: ArrayBuffer) => {
     const dataChannel = dcRef.current;
@@ -648,6 +702,7 @@ export function useSharedWebRTCManager(): WebRTCConnection {
     disconnect,
     sendMessage,
     sendData,
+    sendSignal,
 
     // 处理器注册
     registerMessageHandler,
@@ -95,7 +95,7 @@ export function useTextTransferBusiness(connection: WebRTCConnection) {
 
   // 连接
   const connect = useCallback((roomCode: string, role: 'sender' | 'receiver') => {
-    return connection.connect(roomCode, role);
+    return connection.connect(roomCode, role, 'text');
   }, [connection]);
 
   // 断开连接
@@ -8,9 +8,32 @@ import { config } from './config';
 interface ApiResponse {
   success: boolean;
   message?: string;
+  code?: string;
   data?: unknown;
 }
 
+/**
+ * API 错误，code 为服务器返回的错误码（例如 ROOM_NOT_FOUND）
+ */
+export class ApiError extends Error {
+  constructor(message: string, public status: number, public code?: string) {
+    super(message);
+    this.name = 'ApiError';
+  }
+}
+
+/**
+ * 从非 2xx 响应中读取错误描述，响应体不是 JSON 时使用状态码
+ */
+async function responseError(response: Response): Promise<ApiError> {
+  try {
+    const body = await response.json() as ApiResponse;
+    return new ApiError(body.message || `HTTP error! status: ${response.status}`, response.status, body.code);
+  } catch {
+    return new ApiError(`HTTP error! status: ${response.status}`, response.status);
+  }
+}
+
 interface CreateRoomData {
   type?: string;
   content?: string;
@@ -58,7 +81,7 @@ export class ClientAPI {
       });
 
       if (!response.ok) {
-        throw new Error(`HTTP error! status: ${response.status}`);
+        throw await responseError(response);
       }
 
       return await response.json() as ApiResponse;
@@ -83,7 +106,7 @@ export class ClientAPI {
       });
 
       if (!response.ok) {
-        throw new Error(`HTTP error! status: ${response.status}`);
+        throw await responseError(response);
       }
 
       return await response.json() as ApiResponse;
@@ -14,11 +14,43 @@ const getEnv = (key: string, defaultValue: string = '') => {
   return defaultValue;
 };
 
+/**
+ * 服务器注入页面的运行时配置（window.__CHUAN_CONFIG__）
+ * 同一份构建可以部署到不同环境，由服务器配置决定 API 地址、ICE 服务器和品牌信息
+ */
+export interface RuntimeConfig {
+  api_base_url?: string;
+  base_path?: string;
+  ice_servers?: RTCIceServer[];
+  branding?: {
+    name?: string;
+    description?: string;
+    logo_url?: string;
+  };
+  features?: Record<string, boolean>;
+}
+
+declare global {
+  interface Window {
+    __CHUAN_CONFIG__?: RuntimeConfig;
+  }
+}
+
+/**
+ * 获取服务器注入的运行时配置，服务器端渲染或未注入时返回空对象
+ */
+export function getRuntimeConfig(): RuntimeConfig {
+  if (typeof window !== 'undefined' && window.__CHUAN_CONFIG__) {
+    return window.__CHUAN_CONFIG__;
+  }
+  return {};
+}
+
 // 动态获取当前域名和协议
 const getCurrentBaseUrl = () => {
   if (typeof window !== 'undefined') {
-    // 客户端运行时，使用当前页面的 origin
-    return window.location.origin;
+    // 客户端运行时，使用当前页面的 origin，部署在子路径下时加上路径前缀
+    return window.location.origin + (getRuntimeConfig().base_path || '');
   }
   // 服务器端默认值
   return 'http://localhost:8080';
@@ -36,9 +68,15 @@ const getCurrentWsUrl = () => {
       return 'ws://localhost:8080/ws/p2p';
     }
     
+    // 服务器配置了 API 地址时，信令连接同一地址
+    const apiBaseUrl = getRuntimeConfig().api_base_url;
+    if (apiBaseUrl) {
+      return `${apiBaseUrl.replace(/^http/, 'ws').replace(/\/$/, '')}/ws/p2p`;
+    }
+
     // 生产模式或通过 Go 服务器访问：使用当前域名和端口
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-    return `${protocol}//${window.location.host}/ws/p2p`;
+    return `${protocol}//${window.location.host}${getRuntimeConfig().base_path || ''}/ws/p2p`;
   }
   // 服务器端返回空字符串，强制在客户端计算
   return '';
@@ -106,8 +144,8 @@ export function getApiUrl(path: string): string {
  * @returns 完整的API URL
  */
 export function getDirectBackendUrl(path: string): string {
-  // 实时获取当前域名（支持动态域名）
-  const baseUrl = (getEnv('NEXT_PUBLIC_BACKEND_URL') || getCurrentBaseUrl()).replace(/\/$/, '')
+  // 优先使用服务器注入的 API 地址，否则实时获取当前域名（支持动态域名）
+  const baseUrl = (getRuntimeConfig().api_base_url || getEnv('NEXT_PUBLIC_BACKEND_URL') || getCurrentBaseUrl()).replace(/\/$/, '')
   const apiPath = path.startsWith('/') ? path : `/${path}`
   return `${baseUrl}${apiPath}`
 }
@@ -143,6 +181,7 @@ export function getEnvInfo() {
     directBackendUrl: getDirectBackendUrl(''), // 实时获取
     wsUrl: getWsUrl(), // 实时获取
     currentOrigin: typeof window !== 'undefined' ? window.location.origin : 'server-side',
+    runtimeConfig: getRuntimeConfig(),
     isDev: config.isDev,
     isProd: config.isProd,
     isStatic: config.isStatic,
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"chuan/internal/apikey"
+	"chuan/internal/config"
+)
+
+// runAPIKeyCommand 处理 apikey 子命令：create / list / revoke
+//
+// 命令直接修改 Key 文件，运行中的服务器会自动加载变化。
+func runAPIKeyCommand(args []string) error {
+	if len(args) == 0 {
+		apiKeyUsage()
+		return fmt.Errorf("缺少子命令")
+	}
+
+	cfg := config.Default()
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ExitOnError)
+	configPath := fs.String("config", "", "JSON 配置文件路径，用于读取 Key 文件位置")
+	file := fs.String("file", "", "Key 文件路径，默认使用配置文件中的 api_keys.file")
+
+	switch args[0] {
+	case "create":
+		name := fs.String("name", "", "Key 名称，便于识别用途")
+		scopes := fs.String("scopes", apikey.ScopeCreateRoom, "权限范围，逗号分隔："+strings.Join(apikey.Scopes, ", "))
+		rateLimit := fs.Int("rate-limit", 0, "每分钟允许的请求数，0 表示使用服务器默认值")
+		fs.Parse(args[1:])
+
+		store, err := openKeyStore(cfg, *configPath, *file)
+		if err != nil {
+			return err
+		}
+		raw, key, err := store.Create(*name, splitList(*scopes), *rateLimit)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已创建 API Key %s (%s)\n", key.ID, strings.Join(key.Scopes, ","))
+		fmt.Println("请妥善保存，此 Key 不会再次显示：")
+		fmt.Println(raw)
+		return nil
+
+	case "list":
+		fs.Parse(args[1:])
+
+		store, err := openKeyStore(cfg, *configPath, *file)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t名称\t权限\t频率限制\t创建时间\t最近使用\t状态")
+		for _, key := range store.List() {
+			rate := "默认"
+			if key.RateLimit > 0 {
+				rate = fmt.Sprintf("%d/分钟", key.RateLimit)
+			}
+			status := "有效"
+			if key.Revoked() {
+				status = "已吊销 " + formatTime(key.RevokedAt)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, strings.Join(key.Scopes, ","),
+				rate, formatTime(&key.CreatedAt), formatTime(key.LastUsedAt), status)
+		}
+		return w.Flush()
+
+	case "revoke":
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("用法: apikey revoke [-config 路径] <Key ID>")
+		}
+
+		store, err := openKeyStore(cfg, *configPath, *file)
+		if err != nil {
+			return err
+		}
+		if err := store.Revoke(fs.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("已吊销 API Key %s\n", fs.Arg(0))
+		return nil
+
+	default:
+		apiKeyUsage()
+		return fmt.Errorf("未知的子命令: %s", args[0])
+	}
+}
+
+func apiKeyUsage() {
+	fmt.Println("API Key 管理")
+	fmt.Println("用法:")
+	fmt.Println("  apikey create [-config 路径] [-name 名称] [-scopes create-room,read-status] [-rate-limit 次数]")
+	fmt.Println("  apikey list   [-config 路径]")
+	fmt.Println("  apikey revoke [-config 路径] <Key ID>")
+}
+
+// openKeyStore 按命令行参数或配置文件确定 Key 文件并打开
+func openKeyStore(cfg *config.Config, configPath string, file string) (*apikey.Store, error) {
+	if configPath != "" {
+		if err := config.LoadFile(configPath, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if file == "" {
+		file = cfg.APIKeys.File
+	}
+	return apikey.Open(file, cfg.APIKeys.RateLimit)
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"chuan/internal/audit"
+	"chuan/internal/config"
+	"chuan/internal/services"
+)
+
+// runAuditCommand 处理 audit 子命令：verify
+func runAuditCommand(args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Println("审计日志")
+		fmt.Println("用法:")
+		fmt.Println("  audit verify [-config 路径] [-dir 目录] [-key 密钥文件] [-from 序号:哈希]")
+		return fmt.Errorf("缺少或未知的子命令")
+	}
+
+	cfg := config.Default()
+	fs := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	configPath := fs.String("config", "", "JSON 配置文件路径，用于读取审计日志目录")
+	dir := fs.String("dir", "", "审计日志目录，默认使用配置文件中的 audit.dir")
+	keyFile := fs.String("key", "", "哈希链密钥文件，默认使用配置文件中的 audit.key_file")
+	fromFlag := fs.String("from", "", "上次校验输出的检查点，更早的日志已归档时从这里开始校验")
+	fs.Parse(args[1:])
+
+	if *configPath != "" {
+		if err := config.LoadFile(*configPath, cfg); err != nil {
+			return err
+		}
+	}
+	if *dir == "" {
+		*dir = cfg.Audit.Dir
+	}
+	if *keyFile == "" {
+		*keyFile = cfg.Audit.KeyFile
+	}
+	key, err := audit.LoadKey(*keyFile, false)
+	if err != nil {
+		return err
+	}
+	var from audit.Checkpoint
+	if *fromFlag != "" {
+		if from, err = audit.ParseCheckpoint(*fromFlag); err != nil {
+			return err
+		}
+	}
+
+	result, err := audit.Verify(*dir, key, from)
+	var verifyErr *audit.VerifyError
+	if errors.As(err, &verifyErr) {
+		fmt.Printf("已校验 %d 条记录\n", result.Records)
+		return fmt.Errorf("审计日志校验失败: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("审计日志校验通过: %d 个文件, %d 条记录\n", result.Files, result.Records)
+	if result.FirstSeq > 1 {
+		fmt.Printf("注意: 第一条记录的序号为 %d，更早的日志不在此目录中\n", result.FirstSeq)
+	}
+	fmt.Printf("检查点（请保存在日志目录之外，下次使用 -from 校验）: %s\n", result.Last)
+	return nil
+}
+
+// auditEntry 将房间事件转换为审计记录
+func auditEntry(event services.RoomEvent) audit.Entry {
+	entry := audit.Entry{
+		Event:     event.Type,
+		Room:      event.Room,
+		Role:      event.Role,
+		ClientID:  event.ClientID,
+		Actor:     event.Actor.String(),
+		IP:        event.Actor.IP,
+		UserAgent: event.Actor.UserAgent,
+		Creator:   event.Creator,
+	}
+	if event.Type == services.EventRoomCreated || event.Type == services.EventRoomExtended {
+		entry.Details = map[string]string{"expires_at": event.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z")}
+	}
+	if event.Type == services.EventRoomStateChanged {
+		entry.Details = map[string]string{"from": event.PrevState, "to": event.State}
+	}
+	return entry
+}
+
+// adminAuditEntry 将管理操作事件转换为审计记录
+func adminAuditEntry(event services.AdminEvent) audit.Entry {
+	entry := audit.Entry{
+		Event:     event.Type,
+		Actor:     event.Actor.String(),
+		IP:        event.Actor.IP,
+		UserAgent: event.Actor.UserAgent,
+		Details:   event.Details,
+	}
+	if event.Actor.Source != "" {
+		entry.Details = make(map[string]string, len(event.Details)+1)
+		for k, v := range event.Details {
+			entry.Details[k] = v
+		}
+		entry.Details["source"] = event.Actor.Source
+	}
+	return entry
+}
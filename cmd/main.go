@@ -2,102 +2,548 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"chuan/internal/apikey"
+	"chuan/internal/audit"
+	"chuan/internal/auth"
+	"chuan/internal/basepath"
+	"chuan/internal/clientip"
+	"chuan/internal/config"
+	"chuan/internal/grpcapi"
 	"chuan/internal/handlers"
+	"chuan/internal/openapi"
+	"chuan/internal/quota"
+	"chuan/internal/services"
+	"chuan/internal/storage"
 	"chuan/internal/web"
+	"chuan/internal/webhook"
+	"chuan/pkg/api/chuanv1"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
+	// 管理子命令
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "apikey":
+			run = runAPIKeyCommand
+		case "audit":
+			run = runAuditCommand
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				log.Fatalf("%v", err)
+			}
+			return
+		}
+	}
+
+	cfg := config.Default()
+
 	// 定义命令行参数
-	var port = flag.Int("port", 7777, "服务器监听端口")
-	var help = flag.Bool("help", false, "显示帮助信息")
+	configPath, help := bindFlags(flag.CommandLine, cfg)
 	flag.Parse()
 
+	// 加载配置文件后重新解析命令行参数，使显式指定的参数覆盖配置文件
+	if *configPath != "" {
+		if err := config.LoadFile(*configPath, cfg); err != nil {
+			log.Fatalf("%v", err)
+		}
+		flag.Parse()
+	}
+
 	// 显示帮助信息
 	if *help {
 		fmt.Println("文件传输服务器")
 		fmt.Println("用法:")
 		flag.PrintDefaults()
+		fmt.Println("子命令:")
+		fmt.Println("  apikey create|list|revoke  管理 API Key")
+		fmt.Println("  audit verify               校验审计日志哈希链")
 		os.Exit(0)
 	}
 
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("配置无效: %v", err)
+	}
+
+	// 初始化服务
+	webrtcService := services.NewWebRTCService(cfg)
+
+	// 房间事件 Webhook
+	dispatcher := webhook.NewDispatcher(cfg.Webhook)
+	defer dispatcher.Close()
+	if dispatcher.Enabled() {
+		webrtcService.AddHook(func(event services.RoomEvent) {
+			dispatcher.Publish(event.Type, event)
+		})
+		log.Printf("已启用 %d 个Webhook端点", len(cfg.Webhook.Endpoints))
+	}
+
+	// 审计日志（默认关闭）
+	if cfg.Audit.Enabled {
+		auditKey, err := audit.LoadKey(cfg.Audit.KeyFile, true)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		auditLog, err := audit.Open(cfg.Audit.Dir, cfg.Audit.MaxSize, auditKey)
+		if err != nil {
+			log.Fatalf("打开审计日志失败: %v", err)
+		}
+		defer auditLog.Close()
+		webrtcService.AddHook(func(event services.RoomEvent) {
+			auditLog.Record(auditEntry(event))
+		})
+		webrtcService.AddAdminHook(func(event services.AdminEvent) {
+			auditLog.Record(adminAuditEntry(event))
+		})
+		log.Printf("已启用审计日志: %s", cfg.Audit.Dir)
+	}
+
+	// 文本片段存储
+	textStore, err := storage.OpenTextStore(cfg.Text.Storage, cfg.Text.Dir)
+	if err != nil {
+		log.Fatalf("初始化文本存储失败: %v", err)
+	}
+	textService := services.NewTextService(cfg.Text, textStore)
+
+	// 暂存转发文件模式（默认关闭）
+	var storeService *services.StoreService
+	if cfg.Store.Enabled {
+		fileStore, err := storage.NewFileStore(cfg.Store.Dir)
+		if err != nil {
+			log.Fatalf("初始化暂存文件存储失败: %v", err)
+		}
+		storeService, err = services.NewStoreService(cfg.Store, fileStore)
+		if err != nil {
+			log.Fatalf("初始化暂存文件服务失败: %v", err)
+		}
+	}
+
+	// tus 断点续传上传（默认关闭），上传随房间关闭或过期一起删除
+	var tusService *services.TusService
+	if cfg.Tus.Enabled {
+		tusService, err = services.NewTusService(cfg.Tus, webrtcService)
+		if err != nil {
+			log.Fatalf("初始化断点续传上传失败: %v", err)
+		}
+		webrtcService.AddHook(func(event services.RoomEvent) {
+			if event.Type == services.EventRoomClosed || event.Type == services.EventRoomExpired {
+				go tusService.DeleteRoom(event.Room, event.Time)
+			}
+		})
+	}
+
+	// HTTP 流式中继（默认关闭）
+	var relayService *services.RelayService
+	if cfg.Relay.Enabled {
+		relayService = services.NewRelayService(cfg.Relay, webrtcService)
+	}
+
+	// 身份认证（默认关闭）
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		authenticator, err = auth.New(context.Background(), cfg.Auth)
+		if err != nil {
+			log.Fatalf("初始化身份认证失败: %v", err)
+		}
+		log.Printf("已启用身份认证 (OIDC 登录: %v, 接收方需要登录: %v)", authenticator.LoginEnabled(), authenticator.RequireReceiver())
+	}
+
+	// API Key（默认关闭）
+	var keyGuard *apikey.Guard
+	var keyStore *apikey.Store
+	if cfg.APIKeys.Enabled {
+		keyStore, err = apikey.Open(cfg.APIKeys.File, cfg.APIKeys.RateLimit)
+		if err != nil {
+			log.Fatalf("加载 API Key 失败: %v", err)
+		}
+		keyStore.StartFlusher()
+		defer keyStore.Close()
+		keyGuard = apikey.NewGuard(keyStore, cfg.APIKeys.Required)
+		log.Printf("已启用 API Key (%d 个)", len(keyStore.List()))
+	}
+
+	// 配额，房间和文本片段创建时占用名额，房间关闭或过期时归还
+	limiter := quota.New(cfg.Quota, webrtcService.RoomCount)
+	webrtcService.SetQuota(limiter)
+	textService.SetQuota(limiter)
+	webrtcService.AddHook(func(event services.RoomEvent) {
+		if event.Type == services.EventRoomClosed || event.Type == services.EventRoomExpired {
+			limiter.ReleaseRoom(event.Room)
+		}
+	})
+
+	// 受信任的反向代理，只有来自这些地址的请求才按转发请求头确定客户端 IP
+	proxies, err := clientip.NewProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	warnQuotaWithoutProxies(cfg)
+
+	// SIGHUP 重新加载配置文件
+	rl := &reloader{
+		args:    os.Args[1:],
+		webrtc:  webrtcService,
+		quota:   limiter,
+		apiKeys: keyStore,
+		proxies: proxies,
+		current: cfg,
+	}
+
+	// OpenAPI 文档校验器，用于 /api/v1，JSON 请求体最大为创建文本的请求
+	apiValidator, err := openapi.New(textService.MaxRequestSize(), cfg.API.ValidateResponses)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// 初始化处理器
-	h := handlers.NewHandler()
+	h := handlers.NewHandler(webrtcService, textService, storeService, tusService, relayService, limiter)
 
 	// 创建路由
 	r := chi.NewRouter()
 
-	// 中间件
+	// 中间件，客户端 IP 需要在记录日志和统计配额之前确定
+	r.Use(proxies.Middleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Compress(5))
+	r.Use(compressAPI(middleware.Compress(5)))
 
 	// CORS 配置
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			return webrtcService.OriginAllowed(origin)
+		},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Owner-Token", "X-API-Key", "X-File-Meta", "X-File-Name", "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset", "Upload-Checksum"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Tus-Checksum-Algorithm", "Upload-Offset", "Upload-Length", "Upload-Expires", "Upload-Metadata", "X-File-Meta"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	// 嵌入式前端文件服务
-	r.Handle("/*", web.CreateFrontendHandler())
+	// 识别 API Key 和登录身份，未启用时不做任何处理
+	r.Use(keyGuard.Middleware)
+	r.Use(authenticator.Middleware)
 
-	// WebRTC信令WebSocket路由
-	r.Get("/ws/webrtc", h.HandleWebRTCWebSocket)
+	// 前端文件服务（嵌入的文件或 -web-dir 目录），页面注入运行时配置；未知的 API 路径返回 JSON 错误
+	webCtx, stopWeb := context.WithCancel(context.Background())
+	defer stopWeb()
+	frontend, err := web.CreateFrontendHandler(webCtx, web.Options{
+		Dir:      cfg.Web.Dir,
+		Dev:      cfg.Web.Dev,
+		BasePath: cfg.BasePath,
+		Config:   rl.Config,
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	r.Handle("/*", frontend)
+	r.Handle("/api/*", http.HandlerFunc(h.APINotFoundHandler))
 
-	// WebRTC房间API
-	r.Post("/api/create-room", h.CreateRoomHandler)
-	r.Get("/api/room-info", h.WebRTCRoomStatusHandler)
-	r.Get("/api/webrtc-room-status", h.WebRTCRoomStatusHandler)
+	// 登录相关路由，仅在启用认证时注册
+	if authenticator != nil {
+		r.Get("/auth/login", authenticator.LoginHandler)
+		r.Get("/auth/callback", authenticator.CallbackHandler)
+		r.Get("/auth/logout", authenticator.LogoutHandler)
+		r.Get("/auth/me", authenticator.MeHandler)
+	}
+
+	// 创建房间、在服务器上保存或转发内容的接口需要登录和 create-room 权限
+	requireCreate := chi.Chain(keyGuard.Require(apikey.ScopeCreateRoom), authenticator.Require)
+	// tus 上传只有创建、写入和删除需要权限，查询进度和下载与接收方相同
+	isTusWrite := func(r *http.Request) bool {
+		return r.Method == http.MethodPost || r.Method == http.MethodPatch || r.Method == http.MethodDelete
+	}
+	requireTusWrite := chi.Chain(keyGuard.RequireFor(func(r *http.Request) string {
+		if isTusWrite(r) {
+			return apikey.ScopeCreateRoom
+		}
+		return ""
+	}), authenticator.RequireIf(isTusWrite))
+
+	// WebRTC信令WebSocket路由，发送方加入时可能自动创建房间，因此与创建房间一样需要登录
+	r.With(keyGuard.RequireFor(func(r *http.Request) string {
+		if r.URL.Query().Get("role") != "receiver" {
+			return apikey.ScopeCreateRoom
+		}
+		return ""
+	}), authenticator.RequireIf(func(r *http.Request) bool {
+		return r.URL.Query().Get("role") != "receiver" || authenticator.RequireReceiver()
+	})).Get("/ws/webrtc", h.HandleWebRTCWebSocket)
+
+	// WebRTC房间API（旧路由，保留为 /api/v1 的别名）
+	r.With(requireCreate...).Post("/api/create-room", h.CreateRoomHandler)
+	r.Post("/api/extend-room", h.ExtendRoomHandler)
+	r.Get("/api/quota", h.QuotaHandler)
+	r.Get("/api/ice-servers", h.ICEServersHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(keyGuard.Require(apikey.ScopeReadStatus))
+		r.Get("/api/room-info", h.WebRTCRoomStatusHandler)
+		r.Get("/api/webrtc-room-status", h.WebRTCRoomStatusHandler)
+		r.Get("/api/room-events", h.RoomEventsHandler)
+	})
+
+	// 文本片段API
+	r.With(requireCreate...).Post("/api/create-text-room", h.CreateTextRoomHandler)
+	r.Get("/api/get-text-content", h.GetTextContentHandler)
+	r.Get("/api/get-text-content/{code}", h.GetTextContentHandler)
+
+	// 暂存转发文件API，仅在启用时注册
+	if storeService != nil {
+		r.With(requireCreate...).Post("/api/store", h.StoreUploadHandler)
+		r.Get("/api/store/{code}", h.StoreDownloadHandler)
+		r.Head("/api/store/{code}", h.StoreDownloadHandler)
+		r.Get("/api/store/{code}/info", h.StoreInfoHandler)
+		r.Delete("/api/store/{code}", h.StoreDeleteHandler)
+	}
+
+	// tus 断点续传上传，仅在启用时注册
+	if tusService != nil {
+		r.With(requireTusWrite...).Mount("/api/tus/{code}", h.TusRoutes())
+	}
+
+	// HTTP 流式中继，仅在启用时注册
+	if relayService != nil {
+		r.With(requireCreate...).Put("/api/stream/{code}", h.StreamUploadHandler)
+		r.Get("/api/stream/{code}", h.StreamDownloadHandler)
+	}
+
+	// 版本化 API，请求按 OpenAPI 文档校验，文档见 /api/v1/openapi.json
+	r.Route(apiValidator.BasePath(), func(r chi.Router) {
+		r.Use(apiValidator.Middleware)
+		r.NotFound(h.APINotFoundHandler)
+		r.MethodNotAllowed(h.APIMethodNotAllowedHandler)
+
+		r.Get("/openapi.json", apiValidator.SpecHandler)
+
+		r.With(requireCreate...).Post("/rooms", h.CreateRoomHandler)
+		r.Post("/rooms/{code}/extend", h.ExtendRoomHandler)
+		r.Get("/quota", h.QuotaHandler)
+		r.Get("/ice-servers", h.ICEServersHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(keyGuard.Require(apikey.ScopeReadStatus))
+			r.Get("/rooms/{code}", h.WebRTCRoomStatusHandler)
+			r.Get("/rooms/{code}/events", h.RoomEventsHandler)
+		})
+
+		r.With(requireCreate...).Post("/texts", h.CreateTextRoomHandler)
+		r.Get("/texts/{code}", h.GetTextContentHandler)
+
+		if storeService != nil {
+			r.With(requireCreate...).Post("/files", h.StoreUploadHandler)
+			r.Get("/files/{code}", h.StoreDownloadHandler)
+			r.Head("/files/{code}", h.StoreDownloadHandler)
+			r.Get("/files/{code}/info", h.StoreInfoHandler)
+			r.Delete("/files/{code}", h.StoreDeleteHandler)
+		}
+		if tusService != nil {
+			r.With(requireTusWrite...).Mount("/tus/{code}", h.TusRoutes())
+		}
+		if relayService != nil {
+			r.With(requireCreate...).Put("/stream/{code}", h.StreamUploadHandler)
+			r.Get("/stream/{code}", h.StreamDownloadHandler)
+		}
+
+		// 管理接口，必须使用拥有 admin 权限的 API Key
+		if keyGuard != nil {
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(keyGuard.RequireKey(apikey.ScopeAdmin))
+				r.Get("/maintenance", h.MaintenanceHandler)
+				r.Put("/maintenance", h.SetMaintenanceHandler)
+				r.Post("/notices", h.NoticeHandler)
+			})
+		}
+	})
 
 	// 构建服务器地址
-	addr := fmt.Sprintf(":%d", *port)
+	addr := fmt.Sprintf(":%d", cfg.Port)
 
-	// 启动服务器
+	// 启动服务器，部署在子路径下时去掉请求路径中的前缀再交给路由
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      r,
+		Handler:      basepath.Strip(cfg.BasePath)(r),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
+	// 断开开发模式的页面刷新连接，否则关闭时需要等待超时
+	srv.RegisterOnShutdown(stopWeb)
+
+	// gRPC 接口（默认关闭），认证和权限检查与对应的 HTTP 路由一致
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			log.Fatalf("gRPC 监听失败: %v", err)
+		}
+		var grpcOpts []grpc.ServerOption
+		if cfg.GRPC.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.GRPC.CertFile, cfg.GRPC.KeyFile)
+			if err != nil {
+				log.Fatalf("加载 gRPC TLS 证书失败: %v", err)
+			}
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(&tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			})))
+		}
+		grpcServer = grpc.NewServer(grpcOpts...)
+		chuanv1.RegisterChuanServiceServer(grpcServer, grpcapi.NewServer(webrtcService, grpcapi.Options{
+			Identify:   grpcapi.Chain(keyGuard.Middleware, authenticator.Middleware),
+			CreateRoom: grpcapi.Chain(keyGuard.Require(apikey.ScopeCreateRoom), authenticator.Require),
+			ReadStatus: keyGuard.Require(apikey.ScopeReadStatus),
+			JoinAsReceiver: authenticator.RequireIf(func(*http.Request) bool {
+				return authenticator.RequireReceiver()
+			}),
+		}))
+		go func() {
+			if cfg.GRPC.CertFile != "" {
+				log.Printf("gRPC 服务启动在端口 :%d (TLS)", cfg.GRPC.Port)
+			} else {
+				log.Printf("gRPC 服务启动在端口 :%d", cfg.GRPC.Port)
+			}
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("gRPC 服务启动失败: %v", err)
+			}
+		}()
+	}
 
 	// 优雅关闭
 	go func() {
 		log.Printf("服务器启动在端口 %s", addr)
+		if cfg.BasePath != "" {
+			log.Printf("路径前缀: %s", cfg.BasePath)
+		}
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("服务器启动失败: %v", err)
 		}
 	}()
 
+	// SIGUSR1 切换维护模式
+	watchMaintenanceSignal(webrtcService, func() string { return rl.Config().Maintenance.Message })
+
+	// SIGHUP 重新加载配置文件，不影响已有连接
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Println("收到 SIGHUP，重新加载配置")
+			rl.Reload()
+		}
+	}()
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("正在关闭服务器...")
 
+	// 通知信令客户端并等待其断开，期间继续处理 HTTP 请求但不再创建房间；再次收到信号时立即断开
+	drainCtx, cancelDrain := context.WithCancel(context.Background())
+	go func() {
+		<-quit
+		log.Println("再次收到中断信号，立即断开连接")
+		cancelDrain()
+	}()
+	webrtcService.Drain(drainCtx)
+	cancelDrain()
+
 	// 设置关闭超时
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// 信令流不会自行结束，超时后强制关闭 gRPC 服务
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("服务器强制关闭:", err)
 	}
 
 	log.Println("服务器已退出")
 }
+
+// compressAPI 只对 API 响应做运行时压缩，前端文件已经预先压缩并带有对应的 ETag
+func compressAPI(compress func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		compressed := compress(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				compressed.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bindFlags 定义命令行参数，参数值写入 cfg；重新加载配置时使用新的 FlagSet 再次解析
+func bindFlags(fs *flag.FlagSet, cfg *config.Config) (configPath *string, help *bool) {
+	configPath = fs.String("config", "", "JSON 配置文件路径，命令行参数优先于配置文件")
+	fs.IntVar(&cfg.Port, "port", cfg.Port, "服务器监听端口")
+	fs.Var(&cfg.RoomTTL, "room-ttl", "房间默认有效期")
+	fs.Var(&cfg.MinRoomTTL, "room-ttl-min", "客户端可申请的最短房间有效期")
+	fs.Var(&cfg.MaxRoomTTL, "room-ttl-max", "客户端可申请的最长房间有效期（含延期）")
+	fs.Var(&cfg.RoomExpiryWarning, "room-expiry-warning", "房间过期前多久发送提醒，0 表示不提醒")
+	fs.StringVar(&cfg.Text.Storage, "text-storage", cfg.Text.Storage, "文本存储后端：memory 或 disk")
+	fs.StringVar(&cfg.Text.Dir, "text-dir", cfg.Text.Dir, "文本存储目录（disk 后端）")
+	fs.IntVar(&cfg.Text.MaxSize, "text-max-size", cfg.Text.MaxSize, "单个文本的最大字节数")
+	fs.BoolVar(&cfg.Store.Enabled, "store", cfg.Store.Enabled, "启用暂存转发文件模式（服务器保存客户端加密后的文件）")
+	fs.StringVar(&cfg.Store.Dir, "store-dir", cfg.Store.Dir, "暂存文件存储目录")
+	fs.Int64Var(&cfg.Store.MaxFileSize, "store-max-file-size", cfg.Store.MaxFileSize, "单个暂存文件的最大字节数")
+	fs.Int64Var(&cfg.Store.MaxTotalSize, "store-quota", cfg.Store.MaxTotalSize, "所有暂存文件的总字节数上限")
+	fs.BoolVar(&cfg.Tus.Enabled, "tus", cfg.Tus.Enabled, "启用 tus 断点续传上传")
+	fs.StringVar(&cfg.Tus.Dir, "tus-dir", cfg.Tus.Dir, "断点续传上传存储目录")
+	fs.Int64Var(&cfg.Tus.MaxSize, "tus-max-size", cfg.Tus.MaxSize, "单个断点续传上传的最大字节数")
+	fs.BoolVar(&cfg.Relay.Enabled, "relay", cfg.Relay.Enabled, "启用 HTTP 流式中继（服务器转发但不保存文件）")
+	fs.IntVar(&cfg.Quota.MaxRooms, "max-rooms", cfg.Quota.MaxRooms, "服务器同时存在的房间总数上限，0 表示不限制")
+	fs.IntVar(&cfg.Quota.RoomsPerSubject, "rooms-per-client", cfg.Quota.RoomsPerSubject, "每个用户或 IP 同时占用的房间数上限，0 表示不限制")
+	fs.IntVar(&cfg.Quota.RoomsPerHour, "rooms-per-hour", cfg.Quota.RoomsPerHour, "每个用户或 IP 每小时创建的房间数上限，0 表示不限制")
+	fs.BoolVar(&cfg.APIKeys.Enabled, "api-keys", cfg.APIKeys.Enabled, "启用 API Key（通过 apikey 子命令管理）")
+	fs.StringVar(&cfg.APIKeys.File, "api-keys-file", cfg.APIKeys.File, "API Key 文件路径")
+	fs.BoolVar(&cfg.Audit.Enabled, "audit", cfg.Audit.Enabled, "启用审计日志")
+	fs.StringVar(&cfg.Audit.Dir, "audit-dir", cfg.Audit.Dir, "审计日志目录")
+	fs.BoolVar(&cfg.Auth.Enabled, "auth", cfg.Auth.Enabled, "启用身份认证，创建房间需要登录（OIDC 参数在配置文件中设置）")
+	fs.BoolVar(&cfg.Auth.RequireReceiver, "auth-require-receiver", cfg.Auth.RequireReceiver, "接收方加入房间也需要登录")
+	fs.BoolVar(&cfg.API.ValidateResponses, "api-validate-responses", cfg.API.ValidateResponses, "检查 /api/v1 的响应是否符合 OpenAPI 文档（开发用）")
+	fs.BoolVar(&cfg.GRPC.Enabled, "grpc", cfg.GRPC.Enabled, "启用 gRPC 接口（房间管理和信令流）")
+	fs.IntVar(&cfg.GRPC.Port, "grpc-port", cfg.GRPC.Port, "gRPC 接口监听端口")
+	fs.StringVar(&cfg.GRPC.CertFile, "grpc-cert", cfg.GRPC.CertFile, "gRPC 接口的 TLS 证书文件（PEM）")
+	fs.StringVar(&cfg.GRPC.KeyFile, "grpc-key", cfg.GRPC.KeyFile, "gRPC 接口的 TLS 私钥文件（PEM）")
+	fs.Var(&cfg.Shutdown.Drain, "shutdown-drain", "关闭时通知客户端后最长等待多久再断开连接")
+	fs.StringVar(&cfg.BasePath, "base-path", cfg.BasePath, "部署在反向代理的子路径下时的路径前缀，例如 /transfer")
+	fs.Func("trusted-proxies", "受信任的反向代理，逗号分隔的 CIDR 或 IP，只有来自这些地址的请求才使用 X-Forwarded-For/Forwarded 中的客户端 IP", func(s string) error {
+		cfg.TrustedProxies = splitList(s)
+		return nil
+	})
+	fs.StringVar(&cfg.Web.Dir, "web-dir", cfg.Web.Dir, "从磁盘目录提供前端文件（静态导出的构建结果），为空时使用编译时嵌入的文件")
+	fs.BoolVar(&cfg.Web.Dev, "web-dev", cfg.Web.Dev, "前端开发模式：-web-dir 中的文件变化后自动刷新已打开的页面")
+	help = fs.Bool("help", false, "显示帮助信息")
+	return configPath, help
+}
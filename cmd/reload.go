@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"chuan/internal/apikey"
+	"chuan/internal/clientip"
+	"chuan/internal/config"
+	"chuan/internal/quota"
+	"chuan/internal/services"
+)
+
+// reloader 收到 SIGHUP 时重新加载配置文件
+//
+// 新配置按启动时的顺序生成（默认值、配置文件、命令行参数），校验失败时保持当前配置不变；
+// 校验通过后依次交给各个服务，需要重启才能生效的部分保留当前值。
+type reloader struct {
+	args    []string // 启动时的命令行参数
+	webrtc  *services.WebRTCService
+	quota   *quota.Limiter
+	apiKeys *apikey.Store // 未启用 API Key 时为 nil
+	proxies *clientip.Proxies
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+// Config 当前生效的配置
+func (rl *reloader) Config() *config.Config {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	return rl.current
+}
+
+// Reload 重新加载配置文件并应用，结果记录到日志
+func (rl *reloader) Reload() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	next, err := loadConfig(rl.args)
+	if err != nil {
+		log.Printf("重新加载配置失败，继续使用当前配置: %v", err)
+		return
+	}
+
+	result := config.Reload(rl.current, next)
+	if len(result.Ignored) > 0 {
+		log.Printf("以下配置需要重启才能生效，本次已忽略: %s", strings.Join(result.Ignored, ", "))
+	}
+	if len(result.Changed) == 0 {
+		log.Printf("配置已重新加载，没有可以应用的变更")
+		return
+	}
+
+	cfg := result.Config
+	rl.webrtc.SetConfig(cfg)
+	rl.quota.SetConfig(cfg.Quota)
+	if rl.apiKeys != nil {
+		rl.apiKeys.SetDefaultRateLimit(cfg.APIKeys.RateLimit)
+	}
+	if err := rl.proxies.Set(cfg.TrustedProxies); err != nil {
+		log.Printf("更新受信任的代理失败: %v", err)
+	}
+	warnQuotaWithoutProxies(cfg)
+	rl.current = cfg
+	log.Printf("配置已重新加载，已应用: %s", strings.Join(result.Changed, ", "))
+}
+
+// warnQuotaWithoutProxies 设置了按 IP 统计的配额但没有配置受信任的代理时记录警告：
+// 部署在反向代理之后时所有客户端都会被识别为代理的 IP，共用同一份配额
+func warnQuotaWithoutProxies(cfg *config.Config) {
+	if cfg.Quota.PerSubject() && len(cfg.TrustedProxies) == 0 {
+		log.Printf("警告: 已设置按用户或 IP 统计的配额，但没有配置受信任的代理 (trusted_proxies)。" +
+			"如果部署在反向代理之后，所有未登录的客户端都会被识别为代理的 IP 并共用同一份配额")
+	}
+}
+
+// loadConfig 按启动时的顺序生成配置：默认值、配置文件、命令行参数，并校验
+func loadConfig(args []string) (*config.Config, error) {
+	cfg := config.Default()
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configPath, _ := bindFlags(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *configPath == "" {
+		return nil, fmt.Errorf("启动时没有指定配置文件")
+	}
+
+	if err := config.LoadFile(*configPath, cfg); err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("配置无效: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"chuan/internal/services"
+)
+
+// watchMaintenanceSignal 收到 SIGUSR1 时切换维护模式，进入维护模式时向在线客户端发送公告
+//
+// message 返回当前配置的维护提示，重新加载配置后使用新的提示。
+func watchMaintenanceSignal(ws *services.WebRTCService, message func() string) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	go func() {
+		// 信号由服务器所在主机上的用户发送，审计日志中以信号名作为操作来源
+		actor := services.Actor{Source: "SIGUSR1"}
+		for range usr1 {
+			text := message()
+			state := ws.SetMaintenance(!ws.Maintenance().Enabled, text, actor)
+			log.Printf("收到 SIGUSR1，维护模式: %v", state.Enabled)
+			if state.Enabled && text != "" {
+				ws.Broadcast(services.Notice{Message: text, Level: services.NoticeWarning}, actor)
+			}
+		}
+	}()
+}
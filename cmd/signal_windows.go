@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+import "chuan/internal/services"
+
+// watchMaintenanceSignal Windows 没有 SIGUSR1，请使用管理接口切换维护模式
+func watchMaintenanceSignal(ws *services.WebRTCService, message func() string) {}
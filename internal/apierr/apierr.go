@@ -0,0 +1,178 @@
+// Package apierr 定义统一的API错误模型
+//
+// 每个错误都有稳定的机器可读错误码、对应的HTTP状态码和本地化的错误描述。
+// HTTP 接口以 models.ErrorResponse 返回错误，WebSocket 以 error 消息返回同样的错误码。
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chuan/internal/models"
+)
+
+// Code 稳定的错误码，客户端可以据此判断错误类型
+type Code string
+
+// 错误码
+const (
+	CodeBadRequest        Code = "BAD_REQUEST"
+	CodeBodyTooLarge      Code = "BODY_TOO_LARGE"
+	CodeMethodNotAllowed  Code = "METHOD_NOT_ALLOWED"
+	CodeMissingCode       Code = "MISSING_CODE"
+	CodeInvalidParameter  Code = "INVALID_PARAMETER"
+	CodeNotFound          Code = "NOT_FOUND"
+	CodeRoomNotFound      Code = "ROOM_NOT_FOUND"
+	CodeRoomFull          Code = "ROOM_FULL"
+	CodeRoomTypeMismatch  Code = "ROOM_TYPE_MISMATCH"
+	CodeInvalidOwnerToken Code = "INVALID_OWNER_TOKEN"
+	CodeUnauthorized      Code = "UNAUTHORIZED"
+	CodeInvalidAPIKey     Code = "INVALID_API_KEY"
+	CodeForbidden         Code = "FORBIDDEN"
+	CodeRateLimited       Code = "RATE_LIMITED"
+	CodeQuotaExceeded     Code = "QUOTA_EXCEEDED"
+	CodeTextNotFound      Code = "TEXT_NOT_FOUND"
+	CodeTextEmpty         Code = "TEXT_EMPTY"
+	CodeTextTooLarge      Code = "TEXT_TOO_LARGE"
+	CodeFileNotFound      Code = "FILE_NOT_FOUND"
+	CodeFileEmpty         Code = "FILE_EMPTY"
+	CodeFileTooLarge      Code = "FILE_TOO_LARGE"
+	CodeStorageFull       Code = "STORAGE_FULL"
+	CodeRelayBusy         Code = "RELAY_BUSY"
+	CodeRelayTimeout      Code = "RELAY_TIMEOUT"
+	CodeRelayCanceled     Code = "RELAY_CANCELED"
+	CodeInvalidMessage    Code = "INVALID_MESSAGE"
+	CodeUnavailable       Code = "SERVICE_UNAVAILABLE"
+	CodeShuttingDown      Code = "SERVER_SHUTTING_DOWN"
+	CodeMaintenance       Code = "MAINTENANCE"
+	CodeInternal          Code = "INTERNAL_ERROR"
+)
+
+// Error 带错误码的API错误
+type Error struct {
+	Code       Code
+	Status     int                    // HTTP 状态码
+	Details    map[string]interface{} // 附加信息，原样返回给客户端
+	RetryAfter time.Duration          // 大于0时设置 Retry-After 响应头
+	Err        error                  // 原始错误，只用于日志
+
+	args []interface{} // 错误描述的格式化参数
+}
+
+// New 创建错误，args 用于格式化错误描述
+func New(code Code, args ...interface{}) *Error {
+	return &Error{Code: code, Status: lookup(code).status, args: args}
+}
+
+// Wrap 创建包装了原始错误的错误
+func Wrap(code Code, err error) *Error {
+	e := New(code)
+	e.Err = err
+	return e
+}
+
+// Error 返回默认语言的错误描述
+func (e *Error) Error() string {
+	return e.Message(DefaultLang)
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Message 返回指定语言的错误描述
+func (e *Error) Message(lang string) string {
+	format := lookup(e.Code).message(lang)
+	if len(e.args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, e.args...)
+}
+
+// WithDetail 添加附加信息
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// WithRetryAfter 设置客户端可以重试的等待时间
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = d
+	return e.WithDetail("retry_after", retryAfterSeconds(d))
+}
+
+// WithStatus 覆盖默认的HTTP状态码
+func (e *Error) WithStatus(status int) *Error {
+	e.Status = status
+	return e
+}
+
+// Converter 可以转换为 API 错误的错误类型，例如超出配额的错误
+type Converter interface {
+	APIError() *Error
+}
+
+// As 识别 *Error 或实现了 Converter 的错误
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	var c Converter
+	if errors.As(err, &c) {
+		return c.APIError(), true
+	}
+	return nil, false
+}
+
+// From 将任意错误转换为 *Error，无法识别的错误视为内部错误
+func From(err error) *Error {
+	if e, ok := As(err); ok {
+		return e
+	}
+	return Wrap(CodeInternal, err)
+}
+
+// Response 返回本地化的错误响应体
+func (e *Error) Response(lang string) models.ErrorResponse {
+	return models.ErrorResponse{
+		Success: false,
+		Message: e.Message(lang),
+		Code:    string(e.Code),
+		Details: e.Details,
+	}
+}
+
+// Write 以 JSON 写出错误响应，语言根据 Accept-Language 选择
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	if e.Code == CodeInternal && e.Err != nil {
+		log.Printf("请求处理失败: %s %s: %v", r.Method, r.URL.Path, e.Err)
+	}
+
+	if e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSeconds(e.RetryAfter), 10))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(e.Response(LangFromRequest(r)))
+}
+
+// retryAfterSeconds 向上取整为秒，至少1秒
+func retryAfterSeconds(d time.Duration) int64 {
+	seconds := int64(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
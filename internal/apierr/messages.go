@@ -0,0 +1,96 @@
+package apierr
+
+import (
+	"net/http"
+	"strings"
+)
+
+// 支持的语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+
+	// DefaultLang 客户端未指定或指定了不支持的语言时使用
+	DefaultLang = LangZH
+)
+
+// entry 错误码对应的状态码和各语言描述
+type entry struct {
+	status int
+	zh     string
+	en     string
+}
+
+// message 返回指定语言的描述
+func (e entry) message(lang string) string {
+	if lang == LangEN {
+		return e.en
+	}
+	return e.zh
+}
+
+// catalog 错误码表，新增错误码时必须同时提供中英文描述
+var catalog = map[Code]entry{
+	CodeBadRequest:        {http.StatusBadRequest, "请求格式错误", "Malformed request"},
+	CodeBodyTooLarge:      {http.StatusRequestEntityTooLarge, "请求体超过大小限制", "Request body exceeds the size limit"},
+	CodeMethodNotAllowed:  {http.StatusMethodNotAllowed, "方法不允许", "Method not allowed"},
+	CodeMissingCode:       {http.StatusBadRequest, "缺少取件码", "Missing code"},
+	CodeInvalidParameter:  {http.StatusBadRequest, "参数 %s 无效", "Invalid parameter %s"},
+	CodeNotFound:          {http.StatusNotFound, "资源不存在", "Not found"},
+	CodeRoomNotFound:      {http.StatusNotFound, "房间不存在或已过期", "Room not found or expired"},
+	CodeRoomFull:          {http.StatusConflict, "房间已满，%s已在线", "Room is full, the %s is already connected"},
+	CodeRoomTypeMismatch:  {http.StatusConflict, "该取件码用于%s，不能在这里使用", "This code is for %s and cannot be used here"},
+	CodeInvalidOwnerToken: {http.StatusForbidden, "房主令牌无效", "Invalid owner token"},
+	CodeUnauthorized:      {http.StatusUnauthorized, "需要登录", "Authentication required"},
+	CodeInvalidAPIKey:     {http.StatusUnauthorized, "API 密钥无效", "Invalid API key"},
+	CodeForbidden:         {http.StatusForbidden, "没有执行该操作的权限", "Permission denied"},
+	CodeRateLimited:       {http.StatusTooManyRequests, "请求过于频繁，请稍后重试", "Too many requests, please retry later"},
+	CodeQuotaExceeded:     {http.StatusTooManyRequests, "已超出配额 %s（上限 %d）", "Quota %s exceeded (limit %d)"},
+	CodeTextNotFound:      {http.StatusNotFound, "文本不存在或已过期", "Text not found or expired"},
+	CodeTextEmpty:         {http.StatusBadRequest, "文本内容不能为空", "Text must not be empty"},
+	CodeTextTooLarge:      {http.StatusRequestEntityTooLarge, "文本内容超过大小限制", "Text exceeds the size limit"},
+	CodeFileNotFound:      {http.StatusNotFound, "文件不存在或已过期", "File not found or expired"},
+	CodeFileEmpty:         {http.StatusBadRequest, "上传内容不能为空", "Upload must not be empty"},
+	CodeFileTooLarge:      {http.StatusRequestEntityTooLarge, "文件超过大小限制", "File exceeds the size limit"},
+	CodeStorageFull:       {http.StatusInsufficientStorage, "服务器暂存空间不足", "Server storage is full"},
+	CodeRelayBusy:         {http.StatusConflict, "该房间已有进行中的传输", "A transfer is already in progress in this room"},
+	CodeRelayTimeout:      {http.StatusGatewayTimeout, "等待对方连接超时", "Timed out waiting for the peer"},
+	CodeRelayCanceled:     {http.StatusGone, "对方已取消传输", "The peer canceled the transfer"},
+	CodeInvalidMessage:    {http.StatusBadRequest, "信令消息无效", "Invalid signaling message"},
+	CodeUnavailable:       {http.StatusServiceUnavailable, "服务暂时不可用", "Service temporarily unavailable"},
+	CodeMaintenance:       {http.StatusServiceUnavailable, "服务器维护中，暂时不能创建新房间，正在进行的传输不受影响", "The server is under maintenance; new rooms cannot be created right now, ongoing transfers are not affected"},
+	CodeShuttingDown:      {http.StatusServiceUnavailable, "服务器正在重启，请稍后重试", "Server is restarting, please retry shortly"},
+	CodeInternal:          {http.StatusInternalServerError, "服务器内部错误", "Internal server error"},
+}
+
+// lookup 查找错误码，未登记的错误码按内部错误处理
+func lookup(code Code) entry {
+	if e, ok := catalog[code]; ok {
+		return e
+	}
+	return catalog[CodeInternal]
+}
+
+// LangFromRequest 根据 Accept-Language 选择响应语言
+//
+// 按出现顺序取第一个支持的语言，忽略权重；都不支持时使用默认语言。
+func LangFromRequest(r *http.Request) string {
+	if r == nil {
+		return DefaultLang
+	}
+	return ParseLang(r.Header.Get("Accept-Language"))
+}
+
+// ParseLang 解析 Accept-Language 头
+func ParseLang(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case tag == LangZH || strings.HasPrefix(tag, LangZH+"-"):
+			return LangZH
+		case tag == LangEN || strings.HasPrefix(tag, LangEN+"-"):
+			return LangEN
+		}
+	}
+	return DefaultLang
+}
@@ -0,0 +1,451 @@
+// Package apikey 管理供脚本和自动化程序使用的 API Key。
+//
+// Key 的格式为 ck_<id>_<secret>，服务器只保存完整 Key 的 SHA-256 摘要。
+// 每个 Key 有独立的权限范围、令牌桶限流和最近使用时间。
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 权限范围
+const (
+	ScopeCreateRoom = "create-room" // 创建房间，以发送方身份加入房间，上传文本和文件
+	ScopeReadStatus = "read-status" // 查询房间状态
+	ScopeAdmin      = "admin"       // 管理接口，包含其他所有权限
+)
+
+// Scopes 所有合法的权限范围
+var Scopes = []string{ScopeCreateRoom, ScopeReadStatus, ScopeAdmin}
+
+// keyPrefix Key 前缀
+const keyPrefix = "ck_"
+
+// 内存中的最近使用时间定期写回文件；文件被管理命令修改后自动重新加载
+const (
+	flushInterval = 30 * time.Second
+	reloadCheck   = 5 * time.Second
+)
+
+var (
+	// ErrInvalidKey Key 格式错误、不存在或已吊销
+	ErrInvalidKey = errors.New("API Key 无效")
+	// ErrKeyNotFound 管理命令中指定的 Key ID 不存在
+	ErrKeyNotFound = errors.New("API Key 不存在")
+)
+
+// RateLimitError 超出 Key 的请求频率限制
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("API Key 请求过于频繁，请在 %s 后重试", e.RetryAfter.Round(time.Second))
+}
+
+// Key 一个 API Key 的元数据
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash"` // 完整 Key 的 SHA-256 十六进制摘要
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"` // 每分钟允许的请求数，0 表示使用服务器默认值
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope 是否拥有指定权限，admin 包含所有权限
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoked 是否已吊销
+func (k *Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// keyFile Key 文件格式
+type keyFile struct {
+	Keys []*Key `json:"keys"`
+}
+
+// Store 基于 JSON 文件的 Key 存储
+//
+// 文件是 Key 列表的唯一来源，服务器运行期间管理命令对文件的修改会被自动加载；
+// 服务器只在内存中累积最近使用时间，定期合并写回文件。
+type Store struct {
+	path             string
+	defaultRateLimit int // 由 mu 保护，重新加载配置时更新
+
+	mu        sync.Mutex
+	keys      map[string]*Key
+	buckets   map[string]*bucket
+	modTime   time.Time
+	checkedAt time.Time
+	dirty     bool
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// Open 打开 Key 文件，文件不存在时视为空列表
+func Open(path string, defaultRateLimit int) (*Store, error) {
+	s := &Store{
+		path:             path,
+		defaultRateLimit: defaultRateLimit,
+		keys:             make(map[string]*Key),
+		buckets:          make(map[string]*bucket),
+		done:             make(chan struct{}),
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetDefaultRateLimit 更新未单独设置限流的 Key 每分钟允许的请求数，重新加载配置时调用
+func (s *Store) SetDefaultRateLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.defaultRateLimit = limit
+}
+
+// StartFlusher 启动后台协程，定期把最近使用时间写回文件；服务器模式下调用
+func (s *Store) StartFlusher() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					log.Printf("保存 API Key 使用时间失败: %v", err)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止后台协程并写回未保存的使用时间
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.wg.Wait()
+	return s.Flush()
+}
+
+// Create 生成新 Key，返回明文 Key（只在此时可见）和元数据
+func (s *Store) Create(name string, scopes []string, rateLimit int) (string, *Key, error) {
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return "", nil, fmt.Errorf("未知的权限范围: %s", scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("至少需要一个权限范围")
+	}
+	if rateLimit < 0 {
+		return "", nil, fmt.Errorf("请求频率限制不能为负数")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return "", nil, err
+	}
+
+	id := randomHex(6)
+	for s.keys[id] != nil {
+		id = randomHex(6)
+	}
+	raw := keyPrefix + id + "_" + randomHex(32)
+
+	key := &Key{
+		ID:        id,
+		Name:      name,
+		Hash:      hashKey(raw),
+		Scopes:    scopes,
+		RateLimit: rateLimit,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.keys[id] = key
+	if err := s.writeLocked(); err != nil {
+		delete(s.keys, id)
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// Revoke 吊销 Key
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return err
+	}
+	key := s.keys[id]
+	if key == nil {
+		return ErrKeyNotFound
+	}
+	if key.Revoked() {
+		return nil
+	}
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+	return s.writeLocked()
+}
+
+// List 按创建时间列出所有 Key
+func (s *Store) List() []Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// Authenticate 校验明文 Key，扣减限流令牌并记录使用时间
+//
+// 超出频率限制时返回 *RateLimitError。
+func (s *Store) Authenticate(raw string) (*Key, error) {
+	id, ok := parseKey(raw)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checkedAt) >= reloadCheck {
+		if err := s.reloadLocked(); err != nil {
+			log.Printf("重新加载 API Key 文件失败: %v", err)
+		}
+	}
+
+	key := s.keys[id]
+	if key == nil || key.Revoked() {
+		return nil, ErrInvalidKey
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(raw)), []byte(key.Hash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+
+	limit := key.RateLimit
+	if limit == 0 {
+		limit = s.defaultRateLimit
+	}
+	if limit > 0 {
+		b := s.buckets[id]
+		if b == nil || b.limit != limit {
+			b = newBucket(limit)
+			s.buckets[id] = b
+		}
+		if wait := b.take(time.Now()); wait > 0 {
+			return nil, &RateLimitError{RetryAfter: wait}
+		}
+	}
+
+	now := time.Now().UTC()
+	key.LastUsedAt = &now
+	s.dirty = true
+
+	copied := *key
+	return &copied, nil
+}
+
+// Flush 将内存中的最近使用时间合并写回文件
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	if err := s.reloadLocked(); err != nil {
+		return err
+	}
+	if err := s.writeLocked(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// reload 加载 Key 文件
+func (s *Store) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reloadLocked()
+}
+
+// reloadLocked 文件有变化时重新加载，保留内存中较新的最近使用时间（调用方需持有 mu）
+func (s *Store) reloadLocked() error {
+	s.checkedAt = time.Now()
+
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取 API Key 文件失败: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("读取 API Key 文件失败: %w", err)
+	}
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析 API Key 文件失败: %w", err)
+	}
+
+	keys := make(map[string]*Key, len(file.Keys))
+	for _, key := range file.Keys {
+		if old := s.keys[key.ID]; old != nil && old.LastUsedAt != nil &&
+			(key.LastUsedAt == nil || old.LastUsedAt.After(*key.LastUsedAt)) {
+			key.LastUsedAt = old.LastUsedAt
+		}
+		keys[key.ID] = key
+	}
+	s.keys = keys
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// writeLocked 原子写入 Key 文件（调用方需持有 mu）
+func (s *Store) writeLocked() error {
+	file := keyFile{Keys: make([]*Key, 0, len(s.keys))}
+	for _, key := range s.keys {
+		file.Keys = append(file.Keys, key)
+	}
+	sort.Slice(file.Keys, func(i, j int) bool { return file.Keys[i].CreatedAt.Before(file.Keys[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("创建 API Key 目录失败: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".apikeys-*")
+	if err != nil {
+		return fmt.Errorf("保存 API Key 文件失败: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存 API Key 文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存 API Key 文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存 API Key 文件失败: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// parseKey 解析 ck_<id>_<secret>，返回 Key ID
+func parseKey(raw string) (string, bool) {
+	if !strings.HasPrefix(raw, keyPrefix) {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, keyPrefix), "_")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return id, true
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func hashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// bucket 令牌桶，容量等于每分钟的请求数
+type bucket struct {
+	limit  int
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit int) *bucket {
+	return &bucket{limit: limit, tokens: float64(limit), last: time.Now()}
+}
+
+// take 取一个令牌，令牌不足时返回需要等待的时间
+func (b *bucket) take(now time.Time) time.Duration {
+	rate := float64(b.limit) / 60 // 每秒补充的令牌数
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(b.limit) {
+		b.tokens = float64(b.limit)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
@@ -0,0 +1,151 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"chuan/internal/apierr"
+	"chuan/internal/auth"
+)
+
+// HeaderAPIKey 传递 API Key 的请求头，也可以使用 Authorization: Bearer ck_...
+const HeaderAPIKey = "X-API-Key"
+
+// QueryAPIKey WebSocket 握手时传递 API Key 的查询参数（浏览器无法设置 WebSocket 请求头）
+const QueryAPIKey = "api_key"
+
+type contextKey struct{}
+
+// FromContext 读取请求使用的 API Key，未使用时返回 nil
+func FromContext(ctx context.Context) *Key {
+	key, _ := ctx.Value(contextKey{}).(*Key)
+	return key
+}
+
+// Guard 校验请求中的 API Key 并检查权限
+type Guard struct {
+	store    *Store
+	required bool
+}
+
+// NewGuard 创建校验器，required 为 true 时创建房间必须提供 API Key（或已登录）
+func NewGuard(store *Store, required bool) *Guard {
+	return &Guard{store: store, required: required}
+}
+
+// Middleware 识别请求中的 API Key
+//
+// 携带了 Key 但 Key 无效时返回 401，超出频率限制时返回 429；
+// Key 有效时保存到上下文，并作为登录身份供创建房间等接口记录创建者。
+// 校验器为 nil（未启用 API Key）时直接放行。
+func (g *Guard) Middleware(next http.Handler) http.Handler {
+	if g == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := extractKey(r)
+		if raw == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := g.store.Authenticate(raw)
+		if err != nil {
+			var limited *RateLimitError
+			if errors.As(err, &limited) {
+				apierr.Write(w, r, apierr.Wrap(apierr.CodeRateLimited, err).WithRetryAfter(limited.RetryAfter))
+				return
+			}
+			apierr.Write(w, r, apierr.Wrap(apierr.CodeInvalidAPIKey, err))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextKey{}, key)
+		ctx = auth.WithIdentity(ctx, &auth.Identity{
+			Subject: "apikey:" + key.ID,
+			Issuer:  "apikey",
+			Name:    key.Name,
+		})
+		r = r.WithContext(ctx)
+
+		// 查询参数中的 Key 不再向后传递，避免出现在后续日志中
+		if r.URL.Query().Has(QueryAPIKey) {
+			u := *r.URL
+			query := u.Query()
+			query.Del(QueryAPIKey)
+			u.RawQuery = query.Encode()
+			r.URL = &u
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require 要求请求具备指定权限
+func (g *Guard) Require(scope string) func(http.Handler) http.Handler {
+	return g.RequireFor(func(*http.Request) string { return scope })
+}
+
+// RequireFor 按请求决定需要的权限，scopeOf 返回空字符串表示不需要权限
+//
+// 使用 API Key 的请求必须拥有该权限，否则返回 403；
+// 未使用 API Key 的请求只有在要求 Key 且未登录时才会被拒绝（查询状态始终允许匿名访问）。
+func (g *Guard) RequireFor(scopeOf func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if g == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := scopeOf(r)
+			if scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if key := FromContext(r.Context()); key != nil {
+				if !key.HasScope(scope) {
+					apierr.Write(w, r, apierr.New(apierr.CodeForbidden).WithDetail("scope", scope))
+					return
+				}
+			} else if g.required && scope != ScopeReadStatus && auth.FromContext(r.Context()) == nil {
+				apierr.Write(w, r, apierr.New(apierr.CodeUnauthorized).WithDetail("scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireKey 要求请求使用拥有指定权限的 API Key，登录身份不能代替 Key，未启用 API Key 时拒绝所有请求
+func (g *Guard) RequireKey(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := FromContext(r.Context())
+			if g == nil || key == nil {
+				apierr.Write(w, r, apierr.New(apierr.CodeUnauthorized).WithDetail("scope", scope))
+				return
+			}
+			if !key.HasScope(scope) {
+				apierr.Write(w, r, apierr.New(apierr.CodeForbidden).WithDetail("scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// extractKey 从 X-API-Key、Authorization: Bearer ck_... 或 WebSocket 查询参数中读取 Key
+func extractKey(r *http.Request) string {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		return strings.TrimSpace(key)
+	}
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok &&
+		strings.EqualFold(scheme, "Bearer") && strings.HasPrefix(strings.TrimSpace(token), keyPrefix) {
+		return strings.TrimSpace(token)
+	}
+	if r.Header.Get("Upgrade") != "" {
+		return r.URL.Query().Get(QueryAPIKey)
+	}
+	return ""
+}
@@ -0,0 +1,396 @@
+// Package audit 实现只追加、哈希链接的审计日志。
+//
+// 每条记录是 JSONL 文件中的一行，包含上一条记录的哈希（prev_hash）和本条记录的哈希（hash），
+// hash = HMAC-SHA256(密钥, 去掉 hash 字段后的记录 JSON)。密钥保存在日志目录之外，
+// 只能修改日志文件的人无法重新计算哈希；任何一条记录被修改、删除或调换顺序，都会导致后续校验失败。
+// 日志不记录传输内容，只记录房间、参与者和时间。
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文件命名：当前写入 audit.jsonl，轮转后的文件为 audit-<UTC时间>.jsonl
+const (
+	activeName   = "audit.jsonl"
+	rotatedGlob  = "audit-*.jsonl"
+	rotateLayout = "20060102T150405.000000000Z"
+)
+
+// genesisHash 第一条记录的 prev_hash
+var genesisHash = strings.Repeat("0", 64)
+
+// hashSuffix 每行以 ,"hash":"<64位十六进制>"} 结尾
+const hashSuffixLen = len(`,"hash":""}`) + 64
+
+// Entry 一条待写入的审计事件
+type Entry struct {
+	Event     string            `json:"event"`
+	Room      string            `json:"room,omitempty"`
+	Role      string            `json:"role,omitempty"`
+	ClientID  string            `json:"client_id,omitempty"`
+	Actor     string            `json:"actor,omitempty"` // 操作者的用户身份
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Creator   string            `json:"creator,omitempty"` // 房间创建者的用户身份
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// record 写入文件的记录（不含 hash 字段）
+type record struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	PrevHash string    `json:"prev_hash"`
+	Entry
+}
+
+// EventDropped 队列满时丢弃了事件，记录中的 details.count 为丢弃的数量
+const EventDropped = "audit.dropped"
+
+// Logger 审计日志写入器
+//
+// Record 只把事件放入队列，由后台协程按顺序计算哈希并写入文件。Record 在持有房间锁时调用，
+// 不能阻塞：队列满时丢弃事件并计数，后台协程随后写入一条 audit.dropped 记录，日志中不会留下无法察觉的缺口。
+type Logger struct {
+	dir     string
+	maxSize int64
+	key     []byte
+
+	queue chan Entry
+	done  chan struct{}
+
+	dropMu  sync.Mutex
+	dropped uint64 // 尚未写入日志的丢弃数量
+
+	// 以下字段只由后台协程访问
+	file     *os.File
+	size     int64
+	seq      uint64
+	prevHash string
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// LoadKey 读取十六进制编码的哈希密钥；create 为 true 且文件不存在时生成新的密钥
+func LoadKey(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, fmt.Errorf("创建审计日志密钥目录失败: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+			return nil, fmt.Errorf("保存审计日志密钥失败: %w", err)
+		}
+		log.Printf("已生成审计日志密钥: %s", path)
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取审计日志密钥失败: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) < 16 {
+		return nil, fmt.Errorf("审计日志密钥 %s 无效：需要至少 16 字节的十六进制字符串", path)
+	}
+	return key, nil
+}
+
+// Open 打开审计日志目录，从已有日志的最后一条记录继续哈希链
+//
+// 上次异常退出时写了一半的最后一条记录会被截断，并输出警告。
+func Open(dir string, maxSize int64, key []byte) (*Logger, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("创建审计日志目录失败: %w", err)
+	}
+
+	l := &Logger{
+		dir:      dir,
+		maxSize:  maxSize,
+		key:      key,
+		queue:    make(chan Entry, 4096),
+		done:     make(chan struct{}),
+		prevHash: genesisHash,
+	}
+	if err := l.resume(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, activeName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	l.file = file
+	l.size = info.Size()
+
+	go l.worker()
+	return l, nil
+}
+
+// Record 追加一条审计事件，不会阻塞；队列满时丢弃并计数，日志关闭后的事件被忽略
+func (l *Logger) Record(entry Entry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		return
+	}
+	select {
+	case l.queue <- entry:
+	default:
+		l.dropMu.Lock()
+		if l.dropped == 0 {
+			log.Printf("警告: 审计日志队列已满，开始丢弃事件")
+		}
+		l.dropped++
+		l.dropMu.Unlock()
+	}
+}
+
+// takeDropped 取出并清零丢弃计数
+func (l *Logger) takeDropped() uint64 {
+	l.dropMu.Lock()
+	defer l.dropMu.Unlock()
+
+	n := l.dropped
+	l.dropped = 0
+	return n
+}
+
+// Close 写完队列中的事件后关闭文件
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+
+	<-l.done
+	return nil
+}
+
+func (l *Logger) worker() {
+	defer close(l.done)
+	defer l.file.Close()
+
+	for entry := range l.queue {
+		if err := l.write(entry); err != nil {
+			log.Printf("写入审计日志失败: %v", err)
+		}
+		l.writeDropped()
+	}
+	l.writeDropped()
+}
+
+// writeDropped 有事件被丢弃时写入一条 audit.dropped 记录
+func (l *Logger) writeDropped() {
+	n := l.takeDropped()
+	if n == 0 {
+		return
+	}
+	log.Printf("警告: 审计日志队列已满，丢弃了 %d 条事件", n)
+	if err := l.write(Entry{Event: EventDropped, Details: map[string]string{"count": fmt.Sprint(n)}}); err != nil {
+		log.Printf("写入审计日志失败: %v", err)
+	}
+}
+
+// write 计算哈希并写入一条记录，必要时先轮转文件
+func (l *Logger) write(entry Entry) error {
+	line, hash, err := encode(l.key, record{
+		Seq:      l.seq + 1,
+		Time:     time.Now().UTC(),
+		PrevHash: l.prevHash,
+		Entry:    entry,
+	})
+	if err != nil {
+		return err
+	}
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	l.seq++
+	l.prevHash = hash
+	return nil
+}
+
+// rotate 将当前文件改名为带时间戳的文件并新建 audit.jsonl，哈希链跨文件延续
+func (l *Logger) rotate() error {
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	active := filepath.Join(l.dir, activeName)
+	rotated := filepath.Join(l.dir, "audit-"+time.Now().UTC().Format(rotateLayout)+".jsonl")
+	if err := os.Rename(active, rotated); err != nil {
+		return fmt.Errorf("轮转审计日志失败: %w", err)
+	}
+
+	file, err := os.OpenFile(active, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("轮转审计日志失败: %w", err)
+	}
+	l.file = file
+	l.size = 0
+	log.Printf("审计日志已轮转: %s", filepath.Base(rotated))
+	return nil
+}
+
+// resume 读取最新一条记录，恢复序号和哈希
+//
+// 当前文件末尾没有换行的记录是上次写入时中断留下的，截断到最后一条完整记录后继续；
+// 完整但无法校验的记录说明日志被修改，返回错误。
+func (l *Logger) resume() error {
+	files, err := Files(l.dir)
+	if err != nil {
+		return err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		last, offset, complete, err := lastLine(files[i])
+		if err != nil {
+			return err
+		}
+		if last != nil && !complete && filepath.Base(files[i]) == activeName {
+			if err := os.Truncate(files[i], offset); err != nil {
+				return fmt.Errorf("截断审计日志失败: %w", err)
+			}
+			log.Printf("警告: 审计日志 %s 最后一条记录不完整，已截断 %d 字节", filepath.Base(files[i]), len(last))
+			last, _, _, err = lastLine(files[i])
+			if err != nil {
+				return err
+			}
+		}
+		if last == nil {
+			continue
+		}
+		rec, hash, err := decode(l.key, last)
+		if err != nil {
+			return fmt.Errorf("审计日志 %s 最后一条记录无效: %w", filepath.Base(files[i]), err)
+		}
+		l.seq = rec.Seq
+		l.prevHash = hash
+		return nil
+	}
+	return nil
+}
+
+// Files 按时间顺序返回目录中的审计日志文件，当前写入的文件在最后
+func Files(dir string) ([]string, error) {
+	rotated, err := filepath.Glob(filepath.Join(dir, rotatedGlob))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rotated)
+	active := filepath.Join(dir, activeName)
+	if _, err := os.Stat(active); err == nil {
+		rotated = append(rotated, active)
+	}
+	return rotated, nil
+}
+
+// sign 计算记录的哈希
+func sign(key []byte, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// encode 编码记录并附加哈希，返回带换行的完整行
+func encode(key []byte, rec record) ([]byte, string, error) {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return nil, "", err
+	}
+	hash := sign(key, body)
+
+	line := make([]byte, 0, len(body)+hashSuffixLen+1)
+	line = append(line, body[:len(body)-1]...)
+	line = append(line, `,"hash":"`...)
+	line = append(line, hash...)
+	line = append(line, "\"}\n"...)
+	return line, hash, nil
+}
+
+// decode 校验一行记录自身的哈希，返回记录和哈希
+func decode(key []byte, line []byte) (*record, string, error) {
+	if len(line) < hashSuffixLen || !bytes.HasPrefix(line[len(line)-hashSuffixLen:], []byte(`,"hash":"`)) {
+		return nil, "", errors.New("缺少 hash 字段")
+	}
+	hash := string(line[len(line)-hashSuffixLen+len(`,"hash":"`) : len(line)-2])
+
+	body := make([]byte, 0, len(line)-hashSuffixLen+1)
+	body = append(body, line[:len(line)-hashSuffixLen]...)
+	body = append(body, '}')
+	if !hmac.Equal([]byte(sign(key, body)), []byte(hash)) {
+		return nil, "", errors.New("记录哈希不匹配，内容可能被修改或密钥不正确")
+	}
+
+	var rec record
+	if err := json.Unmarshal(body, &rec); err != nil {
+		return nil, "", err
+	}
+	return &rec, hash, nil
+}
+
+// lastLine 读取文件最后一个非空行（不含换行）和它在文件中的起始位置，complete 表示该行以换行结尾；
+// 文件为空时返回 nil
+func lastLine(path string) (line []byte, offset int64, complete bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64<<10)
+	var pos int64
+	for {
+		cur, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(cur)) > 0 {
+			complete = cur[len(cur)-1] == '\n'
+			line = append(line[:0], bytes.TrimSuffix(cur, []byte("\n"))...)
+			offset = pos
+		}
+		pos += int64(len(cur))
+		if errors.Is(err, io.EOF) {
+			return line, offset, complete, nil
+		}
+		if err != nil {
+			return nil, 0, false, err
+		}
+	}
+}
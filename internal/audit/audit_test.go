@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func writeEntries(t *testing.T, dir string, events ...string) {
+	t.Helper()
+	l, err := Open(dir, 0, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		l.Record(Entry{Event: event, Room: "123456"})
+	}
+	l.Close()
+}
+
+// 上次写入中断留下的半条记录被截断，哈希链从最后一条完整记录继续
+func TestOpenTruncatesTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	writeEntries(t, dir, "room.created", "peer.joined")
+
+	active := filepath.Join(dir, activeName)
+	file, err := os.OpenFile(active, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"seq":3,"time":"2026-01-01T00:00:00Z","prev_ha`)
+	file.Close()
+
+	writeEntries(t, dir, "room.closed")
+
+	result, err := Verify(dir, testKey, Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Records != 3 || result.Last.Seq != 3 {
+		t.Fatalf("校验结果 %+v", result)
+	}
+}
+
+// 删除开头的记录后，没有检查点无法通过校验；使用之前保存的检查点可以校验剩余的日志
+func TestVerifyRequiresCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	writeEntries(t, dir, "room.created")
+	first, err := Verify(dir, testKey, Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮转后删除第一个文件
+	if err := os.Rename(filepath.Join(dir, activeName), filepath.Join(dir, "archived.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(dir, 0, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.seq, l.prevHash = first.Last.Seq, first.Last.Hash
+	l.Record(Entry{Event: "peer.joined"})
+	l.Close()
+
+	if _, err := Verify(dir, testKey, Checkpoint{}); err == nil {
+		t.Fatal("开头被删除的日志不应通过校验")
+	}
+	if _, err := Verify(dir, testKey, first.Last); err != nil {
+		t.Fatalf("从检查点开始校验失败: %v", err)
+	}
+	if _, err := Verify(dir, []byte("another key, another key, ......"), first.Last); err == nil {
+		t.Fatal("密钥不正确时不应通过校验")
+	}
+}
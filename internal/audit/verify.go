@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Checkpoint 哈希链上已确认的位置：序号为 Seq 的记录的哈希为 Hash
+//
+// 校验从检查点开始，保存在日志目录之外，用于在更早的日志归档后确认开头没有被截掉。
+// 零值表示从第一条记录开始校验。
+type Checkpoint struct {
+	Seq  uint64
+	Hash string
+}
+
+// String 以 <序号>:<哈希> 的形式输出，可以由 ParseCheckpoint 解析
+func (c Checkpoint) String() string {
+	return fmt.Sprintf("%d:%s", c.Seq, c.Hash)
+}
+
+// ParseCheckpoint 解析 <序号>:<哈希> 形式的检查点
+func ParseCheckpoint(value string) (Checkpoint, error) {
+	seq, hash, ok := strings.Cut(value, ":")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if !ok || err != nil || len(hash) != 64 {
+		return Checkpoint{}, fmt.Errorf("无效的检查点 %q，格式为 <序号>:<哈希>", value)
+	}
+	return Checkpoint{Seq: n, Hash: hash}, nil
+}
+
+// VerifyResult 校验结果
+type VerifyResult struct {
+	Files    int        // 校验的文件数
+	Records  uint64     // 校验的记录数
+	FirstSeq uint64     // 第一条记录的序号，大于 1 说明更早的日志已被归档
+	Last     Checkpoint // 最后一条记录，可以另行保存作为以后校验的检查点
+}
+
+// VerifyError 哈希链校验失败的位置
+type VerifyError struct {
+	File string
+	Line int
+	Err  string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s 第 %d 行: %s", e.File, e.Line, e.Err)
+}
+
+// Verify 按顺序校验目录中所有审计日志的哈希链
+//
+// 检查每条记录自身的哈希、prev_hash 是否等于上一条记录的哈希，以及序号是否连续。
+// 哈希链必须经过检查点 from：第一条记录紧接在检查点之后，或者检查点本身在日志中。
+// from 为零值时第一条记录的序号必须为 1、prev_hash 为全零，开头被删除的日志无法通过校验。
+func Verify(dir string, key []byte, from Checkpoint) (*VerifyResult, error) {
+	files, err := Files(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("目录 %s 中没有审计日志", dir)
+	}
+
+	if from.Seq == 0 {
+		from.Hash = genesisHash
+	}
+
+	result := &VerifyResult{Files: len(files)}
+	var prevHash string
+	var prevSeq uint64
+	anchored := false
+
+	for _, path := range files {
+		name := filepath.Base(path)
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64<<10), 1<<20)
+		lineNo := 0
+		for scanner.Scan() {
+			lineNo++
+			line := scanner.Bytes()
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+
+			rec, hash, err := decode(key, line)
+			if err != nil {
+				file.Close()
+				return result, &VerifyError{File: name, Line: lineNo, Err: err.Error()}
+			}
+
+			if result.Records == 0 {
+				result.FirstSeq = rec.Seq
+				switch {
+				case rec.Seq == from.Seq+1:
+					if rec.PrevHash != from.Hash {
+						file.Close()
+						return result, &VerifyError{File: name, Line: lineNo, Err: "第一条记录的 prev_hash 与检查点不匹配"}
+					}
+					anchored = true
+				case rec.Seq > from.Seq+1:
+					file.Close()
+					return result, &VerifyError{File: name, Line: lineNo, Err: fmt.Sprintf("缺少序号 %d 到 %d 的记录，日志开头可能被删除；更早的日志已归档时请指定检查点", from.Seq+1, rec.Seq-1)}
+				}
+			} else {
+				if rec.Seq != prevSeq+1 {
+					file.Close()
+					return result, &VerifyError{File: name, Line: lineNo, Err: fmt.Sprintf("序号不连续：期望 %d，实际 %d", prevSeq+1, rec.Seq)}
+				}
+				if rec.PrevHash != prevHash {
+					file.Close()
+					return result, &VerifyError{File: name, Line: lineNo, Err: "prev_hash 与上一条记录不匹配，记录可能被删除或调换"}
+				}
+			}
+
+			if rec.Seq == from.Seq {
+				if hash != from.Hash {
+					file.Close()
+					return result, &VerifyError{File: name, Line: lineNo, Err: "记录的哈希与检查点不匹配"}
+				}
+				anchored = true
+			}
+
+			prevHash = hash
+			prevSeq = rec.Seq
+			result.Records++
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return result, &VerifyError{File: name, Line: lineNo + 1, Err: err.Error()}
+		}
+	}
+
+	if !anchored {
+		return result, fmt.Errorf("哈希链没有经过检查点 %s，日志末尾可能被删除", from)
+	}
+	result.Last = Checkpoint{Seq: prevSeq, Hash: prevHash}
+	return result, nil
+}
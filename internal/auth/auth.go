@@ -0,0 +1,181 @@
+// Package auth 实现可选的身份认证：Bearer JWT（通过 JWKS 校验）和 OIDC 授权码登录（PKCE）。
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/basepath"
+	"chuan/internal/config"
+)
+
+// ErrUnauthenticated 请求未携带有效的身份凭证
+var ErrUnauthenticated = errors.New("未登录或登录已失效")
+
+// Identity 已认证的用户身份
+type Identity struct {
+	Subject string `json:"sub"`
+	Issuer  string `json:"iss,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+// String 用于日志和事件的身份描述
+func (id *Identity) String() string {
+	if id == nil {
+		return ""
+	}
+	if id.Email != "" {
+		return id.Email
+	}
+	return id.Subject
+}
+
+type contextKey struct{}
+
+// WithIdentity 将身份保存到上下文
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext 从上下文读取身份，未认证时返回 nil
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey{}).(*Identity)
+	return id
+}
+
+// Authenticator 身份认证器
+type Authenticator struct {
+	cfg       config.AuthConfig
+	provider  *provider // 未配置 OIDC 登录时为 nil
+	verifier  *verifier
+	sessions  *sessionCodec
+	audiences []string
+}
+
+// New 创建认证器，配置了 issuer 时会通过 OIDC discovery 获取端点
+func New(ctx context.Context, cfg config.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg, audiences: cfg.Audience}
+	if len(a.audiences) == 0 {
+		a.audiences = []string{cfg.ClientID}
+	}
+
+	jwksURL := cfg.JWKSURL
+	if cfg.Issuer != "" {
+		p, err := discover(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		if jwksURL == "" {
+			jwksURL = p.JWKSURI
+		}
+		if cfg.ClientID != "" && cfg.RedirectURL != "" {
+			a.provider = p
+		}
+	}
+	a.verifier = newVerifier(jwksURL, cfg.Issuer)
+
+	secret := []byte(cfg.SessionSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+			return nil, err
+		}
+		if a.provider != nil {
+			log.Printf("未配置会话密钥，使用随机密钥，服务器重启后需要重新登录")
+		}
+	}
+	a.sessions = &sessionCodec{secret: secret}
+
+	return a, nil
+}
+
+// LoginEnabled 是否支持浏览器 OIDC 登录
+func (a *Authenticator) LoginEnabled() bool {
+	return a != nil && a.provider != nil
+}
+
+// RequireReceiver 接收方是否也需要认证
+func (a *Authenticator) RequireReceiver() bool {
+	return a != nil && a.cfg.RequireReceiver
+}
+
+// Identify 从 Authorization 头或会话 Cookie 中识别身份
+func (a *Authenticator) Identify(r *http.Request) (*Identity, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return nil, ErrUnauthenticated
+		}
+		claims, err := a.verifier.Verify(r.Context(), strings.TrimSpace(token), a.audiences)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		return claims.identity(), nil
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		var s session
+		if err := a.sessions.decode(sessionCookie, cookie.Value, &s); err == nil && s.Identity.Subject != "" && time.Now().Before(s.ExpiresAt) {
+			return &s.Identity, nil
+		}
+	}
+	return nil, ErrUnauthenticated
+}
+
+// Middleware 识别请求身份并保存到上下文，未认证的请求照常放行
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, err := a.Identify(r); err == nil {
+			r = r.WithContext(WithIdentity(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require 要求请求已认证，否则返回 401；认证器为 nil（未启用认证）时直接放行
+func (a *Authenticator) Require(next http.Handler) http.Handler {
+	return a.RequireIf(func(*http.Request) bool { return true })(next)
+}
+
+// RequireIf 仅当 cond 返回 true 时要求请求已认证
+func (a *Authenticator) RequireIf(cond func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cond(r) && FromContext(r.Context()) == nil {
+				id, err := a.Identify(r)
+				if err != nil {
+					a.unauthorized(w, r)
+					return
+				}
+				r = r.WithContext(WithIdentity(r.Context(), id))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unauthorized 返回 401 响应
+func (a *Authenticator) unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="chuan"`)
+
+	err := apierr.New(apierr.CodeUnauthorized)
+	if a.LoginEnabled() {
+		err.WithDetail("login_url", basepath.Join(r, "/auth/login"))
+	}
+	apierr.Write(w, r, err)
+}
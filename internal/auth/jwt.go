@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew 校验 exp/nbf 时允许的时钟偏差
+const clockSkew = time.Minute
+
+// jwksRefreshInterval JWKS 缓存有效期；遇到未知 kid 时最快 jwksMinRefresh 刷新一次
+const (
+	jwksRefreshInterval = time.Hour
+	jwksMinRefresh      = time.Minute
+)
+
+// claims JWT 中使用到的声明
+type claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	ExpiresAt         int64    `json:"exp"`
+	NotBefore         int64    `json:"nbf"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+}
+
+func (c *claims) identity() *Identity {
+	name := c.Name
+	if name == "" {
+		name = c.PreferredUsername
+	}
+	return &Identity{Subject: c.Subject, Issuer: c.Issuer, Email: c.Email, Name: name}
+}
+
+// audience aud 声明可以是字符串或字符串数组
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// verifier 使用 JWKS 公钥校验 JWT，支持 RS256 和 ES256
+type verifier struct {
+	jwksURL string
+	issuer  string
+	client  *http.Client
+
+	mu         sync.Mutex
+	keys       map[string]crypto.PublicKey
+	fetchedAt  time.Time
+	refreshing chan struct{} // 正在拉取 JWKS 时不为 nil，拉取结束后关闭
+}
+
+func newVerifier(jwksURL string, issuer string) *verifier {
+	return &verifier{
+		jwksURL: jwksURL,
+		issuer:  issuer,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify 校验签名、签发者、受众和有效期，返回声明
+func (v *verifier) Verify(ctx context.Context, token string, audiences []string) (*claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("JWT 格式无效")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("JWT 头无效: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("JWT 签名格式无效")
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch header.Alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("JWT 签名无效")
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return nil, errors.New("JWT 签名无效")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return nil, errors.New("JWT 签名无效")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的 JWT 算法: %s", header.Alg)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("JWT 声明无效: %w", err)
+	}
+	now := time.Now()
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(clockSkew)) {
+		return nil, errors.New("JWT 已过期")
+	}
+	if c.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(c.NotBefore, 0)) {
+		return nil, errors.New("JWT 尚未生效")
+	}
+	if v.issuer != "" && strings.TrimSuffix(c.Issuer, "/") != strings.TrimSuffix(v.issuer, "/") {
+		return nil, fmt.Errorf("JWT 签发者不匹配: %s", c.Issuer)
+	}
+	if !c.Audience.contains(audiences) {
+		return nil, errors.New("JWT 受众不匹配")
+	}
+	if c.Subject == "" {
+		return nil, errors.New("JWT 缺少 sub")
+	}
+	return &c, nil
+}
+
+func (a audience) contains(accepted []string) bool {
+	for _, aud := range a {
+		for _, want := range accepted {
+			if want != "" && aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key 查找签名公钥，缓存过期或 kid 未知时重新拉取 JWKS
+//
+// 拉取 JWKS 时不持有 mu，身份提供方响应慢时不影响使用缓存密钥的校验；
+// 同一时间只有一个请求拉取，其他需要刷新的请求等待其结果。
+func (v *verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	if key, ok := v.lookup(kid); ok && time.Since(v.fetchedAt) < jwksRefreshInterval {
+		v.mu.Unlock()
+		return key, nil
+	}
+	if time.Since(v.fetchedAt) >= jwksMinRefresh {
+		if done := v.refreshing; done != nil {
+			v.mu.Unlock()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			v.mu.Lock()
+		} else {
+			done := make(chan struct{})
+			v.refreshing = done
+			v.mu.Unlock()
+
+			keys, err := v.fetch(ctx)
+
+			v.mu.Lock()
+			if err == nil {
+				v.keys = keys
+				v.fetchedAt = time.Now()
+			}
+			v.refreshing = nil
+			close(done)
+			if err != nil {
+				v.mu.Unlock()
+				return nil, err
+			}
+		}
+	}
+	key, ok := v.lookup(kid)
+	v.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+	}
+	return key, nil
+}
+
+// lookup 按 kid 查找公钥，token 未指定 kid 且只有一个密钥时使用该密钥（调用方需持有 mu）
+func (v *verifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if key, ok := v.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// fetch 拉取 JWKS，调用方不能持有 mu
+func (v *verifier) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if v.jwksURL == "" {
+		return nil, errors.New("未配置 JWKS 地址")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取 JWKS 失败: HTTP %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 失败: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+// jwk JSON Web Key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA 指数无效")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("EC 公钥不在曲线上")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("密钥参数无效")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testAudience = "chuan"
+)
+
+var (
+	testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+// jwksServer 提供 JWKS 的测试服务器，keys 可以在测试中替换以模拟密钥轮换
+type jwksServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+
+	mu   sync.Mutex
+	keys []jwk
+}
+
+func newJWKSServer(t *testing.T, keys ...jwk) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...jwk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	return jwk{
+		Kty: "EC",
+		Kid: kid,
+		Use: "sig",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// validClaims 返回一组可以通过校验的声明
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss": testIssuer,
+		"sub": "user-1",
+		"aud": testAudience,
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken 使用 key 按 alg 签名；key 为 nil 时签名为空
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	signingInput := encodeSegment(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case nil:
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// replaceSignature 替换 JWT 的签名部分
+func replaceSignature(token string, signature []byte) string {
+	return token[:strings.LastIndex(token, ".")+1] + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func decodeSignature(t *testing.T, token string) []byte {
+	t.Helper()
+	sig, err := base64.RawURLEncoding.DecodeString(token[strings.LastIndex(token, ".")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestVerify(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa", &testRSAKey.PublicKey), ecJWK("ec", &testECKey.PublicKey))
+	v := newVerifier(server.URL, testIssuer)
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+	now := time.Now()
+
+	// ES256 要求 r||s 定长编码，DER 编码的签名即使数学上正确也必须拒绝
+	esToken := signToken(t, "ES256", "ec", testECKey, validClaims())
+	esSig := decodeSignature(t, esToken)
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{new(big.Int).SetBytes(esSig[:32]), new(big.Int).SetBytes(esSig[32:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsToken := signToken(t, "RS256", "rsa", testRSAKey, validClaims())
+
+	// 算法混淆：以 RSA 公钥为 HMAC 密钥签名的 HS256 令牌
+	hsToken := signToken(t, "HS256", "rsa", nil, validClaims())
+	mac := hmac.New(sha256.New, x509.MarshalPKCS1PublicKey(&testRSAKey.PublicKey))
+	mac.Write([]byte(hsToken[:strings.LastIndex(hsToken, ".")]))
+	hsToken = replaceSignature(hsToken, mac.Sum(nil))
+
+	tamperedSig := decodeSignature(t, rsToken)
+	tamperedSig[0] ^= 0xff
+	parts := strings.Split(rsToken, ".")
+	tamperedPayload := parts[0] + "." + encodeSegment(t, with("sub", "admin")) + "." + parts[2]
+
+	for _, c := range []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"RS256", rsToken, true},
+		{"ES256", esToken, true},
+		{"aud 数组", signToken(t, "RS256", "rsa", testRSAKey, with("aud", []string{"other", testAudience})), true},
+		{"iss 末尾斜杠", signToken(t, "RS256", "rsa", testRSAKey, with("iss", testIssuer+"/")), true},
+		{"时钟偏差内过期", signToken(t, "RS256", "rsa", testRSAKey, with("exp", now.Add(-clockSkew/2).Unix())), true},
+
+		{"alg none", signToken(t, "none", "rsa", nil, validClaims()), false},
+		{"alg none 无 kid", signToken(t, "none", "", nil, validClaims()), false},
+		{"alg HS256", hsToken, false},
+		{"alg 小写", signToken(t, "rs256", "rsa", testRSAKey, validClaims()), false},
+		{"RS256 使用 EC 密钥", signToken(t, "RS256", "ec", testRSAKey, validClaims()), false},
+		{"ES256 使用 RSA 密钥", signToken(t, "ES256", "rsa", testECKey, validClaims()), false},
+		{"未知 kid", signToken(t, "RS256", "unknown", testRSAKey, validClaims()), false},
+		{"多个密钥时缺少 kid", signToken(t, "RS256", "", testRSAKey, validClaims()), false},
+
+		{"已过期", signToken(t, "RS256", "rsa", testRSAKey, with("exp", now.Add(-2*clockSkew).Unix())), false},
+		{"缺少 exp", signToken(t, "RS256", "rsa", testRSAKey, with("exp", nil)), false},
+		{"尚未生效", signToken(t, "RS256", "rsa", testRSAKey, with("nbf", now.Add(2*clockSkew).Unix())), false},
+		{"错误的 iss", signToken(t, "RS256", "rsa", testRSAKey, with("iss", "https://evil.example.com")), false},
+		{"缺少 iss", signToken(t, "RS256", "rsa", testRSAKey, with("iss", nil)), false},
+		{"错误的 aud", signToken(t, "RS256", "rsa", testRSAKey, with("aud", "other")), false},
+		{"空 aud 数组", signToken(t, "RS256", "rsa", testRSAKey, with("aud", []string{})), false},
+		{"缺少 sub", signToken(t, "RS256", "rsa", testRSAKey, with("sub", nil)), false},
+
+		{"篡改签名", replaceSignature(rsToken, tamperedSig), false},
+		{"篡改声明", tamperedPayload, false},
+		{"缺少签名", replaceSignature(rsToken, nil), false},
+		{"ES256 DER 签名", replaceSignature(esToken, der), false},
+		{"ES256 签名过长", replaceSignature(esToken, append(esSig, 0)), false},
+		{"ES256 签名过短", replaceSignature(esToken, esSig[:63]), false},
+		{"格式错误", "a.b", false},
+	} {
+		_, err := v.Verify(context.Background(), c.token, []string{testAudience})
+		if c.ok && err != nil {
+			t.Errorf("%s: 期望通过，得到 %v", c.name, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("%s: 期望拒绝，但校验通过", c.name)
+		}
+	}
+}
+
+func TestVerifySingleKeyWithoutKid(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("rsa", &testRSAKey.PublicKey))
+	v := newVerifier(server.URL, testIssuer)
+
+	if _, err := v.Verify(context.Background(), signToken(t, "RS256", "", testRSAKey, validClaims()), []string{testAudience}); err != nil {
+		t.Fatalf("只有一个密钥时应使用该密钥: %v", err)
+	}
+}
+
+func TestVerifyRefreshesJWKSForUnknownKid(t *testing.T) {
+	server := newJWKSServer(t, rsaJWK("old", &testRSAKey.PublicKey))
+	v := newVerifier(server.URL, testIssuer)
+	ctx := context.Background()
+	audiences := []string{testAudience}
+
+	if _, err := v.Verify(ctx, signToken(t, "RS256", "old", testRSAKey, validClaims()), audiences); err != nil {
+		t.Fatal(err)
+	}
+
+	// 身份提供方轮换密钥，距上次拉取不足 jwksMinRefresh 时不重新拉取
+	server.setKeys(rsaJWK("old", &testRSAKey.PublicKey), ecJWK("new", &testECKey.PublicKey))
+	newToken := signToken(t, "ES256", "new", testECKey, validClaims())
+	if _, err := v.Verify(ctx, newToken, audiences); err == nil {
+		t.Fatal("最短刷新间隔内不应重新拉取 JWKS")
+	}
+	if n := server.fetches.Load(); n != 1 {
+		t.Fatalf("拉取次数 = %d，期望 1", n)
+	}
+
+	v.mu.Lock()
+	v.fetchedAt = time.Now().Add(-jwksMinRefresh)
+	v.mu.Unlock()
+	if _, err := v.Verify(ctx, newToken, audiences); err != nil {
+		t.Fatalf("未知 kid 应触发刷新: %v", err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("拉取次数 = %d，期望 2", n)
+	}
+
+	// 已缓存的密钥不再触发拉取
+	if _, err := v.Verify(ctx, newToken, audiences); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("拉取次数 = %d，期望 2", n)
+	}
+}
+
+func TestVerifyDoesNotWaitForSlowJWKS(t *testing.T) {
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jwk{rsaJWK("rsa", &testRSAKey.PublicKey)}})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	v := newVerifier(server.URL, testIssuer)
+	ctx := context.Background()
+	audiences := []string{testAudience}
+	known := signToken(t, "RS256", "rsa", testRSAKey, validClaims())
+	if _, err := v.Verify(ctx, known, audiences); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未知 kid 触发的拉取被阻塞时，使用已缓存密钥的校验不受影响
+	v.mu.Lock()
+	v.fetchedAt = time.Now().Add(-jwksMinRefresh)
+	v.mu.Unlock()
+	go v.Verify(ctx, signToken(t, "RS256", "unknown", testRSAKey, validClaims()), audiences)
+	for fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(ctx, known, audiences)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("校验被正在进行的 JWKS 拉取阻塞")
+	}
+}
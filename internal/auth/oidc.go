@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"chuan/internal/basepath"
+)
+
+// loginTimeout 从跳转到身份提供方到回调的最长时间
+const loginTimeout = 10 * time.Minute
+
+// provider OIDC discovery 返回的端点
+type provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discover 读取 issuer 的 /.well-known/openid-configuration
+func discover(ctx context.Context, issuer string) (*provider, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OIDC discovery 失败: HTTP %d", resp.StatusCode)
+	}
+
+	var p provider
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("解析 OIDC discovery 失败: %w", err)
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("OIDC issuer 不匹配: %s", p.Issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery 缺少必要端点")
+	}
+	return &p, nil
+}
+
+// LoginHandler 跳转到身份提供方登录（授权码模式 + PKCE）
+//
+// 可选参数 redirect 指定登录完成后返回的站内路径。
+func (a *Authenticator) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.LoginEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	state := loginState{
+		State:     randomString(),
+		Verifier:  randomString(),
+		Nonce:     randomString(),
+		Redirect:  safeRedirect(r, r.URL.Query().Get("redirect")),
+		ExpiresAt: time.Now().Add(loginTimeout),
+	}
+	value, err := a.sessions.encode(loginCookie, state)
+	if err != nil {
+		http.Error(w, "登录失败", http.StatusInternalServerError)
+		return
+	}
+	a.setCookie(w, r, loginCookie, value, loginTimeout)
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", a.cfg.ClientID)
+	query.Set("redirect_uri", a.cfg.RedirectURL)
+	query.Set("scope", strings.Join(a.cfg.Scopes, " "))
+	query.Set("state", state.State)
+	query.Set("nonce", state.Nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	target := a.provider.AuthorizationEndpoint
+	if strings.Contains(target, "?") {
+		target += "&" + query.Encode()
+	} else {
+		target += "?" + query.Encode()
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// CallbackHandler 处理身份提供方回调：校验 state，用授权码和 PKCE verifier 换取 ID Token，建立会话
+func (a *Authenticator) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.LoginEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		log.Printf("OIDC 登录失败: %s %s", e, query.Get("error_description"))
+		http.Error(w, "登录失败: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	var state loginState
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil || a.sessions.decode(loginCookie, cookie.Value, &state) != nil || time.Now().After(state.ExpiresAt) {
+		http.Error(w, "登录状态已失效，请重新登录", http.StatusBadRequest)
+		return
+	}
+	a.clearCookie(w, r, loginCookie)
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state.State)) != 1 {
+		http.Error(w, "登录状态不匹配", http.StatusBadRequest)
+		return
+	}
+
+	idToken, err := a.exchangeCode(r.Context(), query.Get("code"), state.Verifier)
+	if err != nil {
+		log.Printf("OIDC 换取令牌失败: %v", err)
+		http.Error(w, "登录失败", http.StatusBadGateway)
+		return
+	}
+	c, err := a.verifier.Verify(r.Context(), idToken, []string{a.cfg.ClientID})
+	if err != nil {
+		log.Printf("OIDC ID Token 无效: %v", err)
+		http.Error(w, "登录失败", http.StatusUnauthorized)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Nonce), []byte(state.Nonce)) != 1 {
+		http.Error(w, "登录失败: nonce 不匹配", http.StatusUnauthorized)
+		return
+	}
+
+	value, err := a.sessions.encode(sessionCookie, session{
+		Identity:  *c.identity(),
+		ExpiresAt: time.Now().Add(a.cfg.SessionTTL.Duration),
+	})
+	if err != nil {
+		http.Error(w, "登录失败", http.StatusInternalServerError)
+		return
+	}
+	a.setCookie(w, r, sessionCookie, value, a.cfg.SessionTTL.Duration)
+	log.Printf("用户登录: %s", c.identity())
+
+	http.Redirect(w, r, state.Redirect, http.StatusFound)
+}
+
+// LogoutHandler 清除登录会话
+func (a *Authenticator) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	a.clearCookie(w, r, sessionCookie)
+	http.Redirect(w, r, safeRedirect(r, r.URL.Query().Get("redirect")), http.StatusFound)
+}
+
+// MeHandler 返回当前登录用户
+func (a *Authenticator) MeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := a.Identify(r)
+	if err != nil {
+		a.unauthorized(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"identity": id,
+	})
+}
+
+// exchangeCode 用授权码换取 ID Token
+func (a *Authenticator) exchangeCode(ctx context.Context, code string, verifier string) (string, error) {
+	if code == "" {
+		return "", fmt.Errorf("缺少授权码")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", a.cfg.RedirectURL)
+	form.Set("client_id", a.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(a.cfg.ClientID), url.QueryEscape(a.cfg.ClientSecret))
+	}
+
+	resp, err := a.verifier.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("响应中没有 id_token")
+	}
+	return token.IDToken, nil
+}
+
+// setCookie 设置 HttpOnly Cookie，路径为部署的路径前缀，回调地址为 https 时附加 Secure
+func (a *Authenticator) setCookie(w http.ResponseWriter, r *http.Request, name string, value string, maxAge time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     basepath.Join(r, "/"),
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(a.cfg.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (a *Authenticator) clearCookie(w http.ResponseWriter, r *http.Request, name string) {
+	a.setCookie(w, r, name, "", -time.Second)
+}
+
+// safeRedirect 只允许跳转到站内路径，防止开放重定向；未指定时跳转到首页
+//
+// 浏览器会忽略 URL 中的制表符和换行，并把反斜杠当作斜杠，"/\t/evil" 和 "/\evil" 都等同于 "//evil"，
+// 因此含控制字符或反斜杠的路径一律拒绝。
+func safeRedirect(r *http.Request, target string) string {
+	if target == "" || !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") ||
+		strings.ContainsRune(target, '\\') || strings.ContainsFunc(target, unicode.IsControl) {
+		return basepath.Join(r, "/")
+	}
+	return target
+}
+
+// randomString 生成 32 字节随机数的 base64url 编码
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
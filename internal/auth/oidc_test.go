@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSafeRedirect(t *testing.T) {
+	r := httptest.NewRequest("GET", "/auth/login", nil)
+	for _, c := range []struct {
+		target string
+		want   string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/text?code=1#x", "/text?code=1#x"},
+		{"/a//b", "/a//b"},
+		{"//evil.com", "/"},
+		{"///evil.com", "/"},
+		{`/\evil.com`, "/"},
+		{`/a\..\\evil.com`, "/"},
+		{"/\t/evil.com", "/"},
+		{"/\n/evil.com", "/"},
+		{"https://evil.com", "/"},
+		{"http:/evil.com", "/"},
+		{"javascript:alert(1)", "/"},
+		{"evil.com", "/"},
+	} {
+		if got := safeRedirect(r, c.target); got != c.want {
+			t.Errorf("safeRedirect(%q) = %q，期望 %q", c.target, got, c.want)
+		}
+	}
+}
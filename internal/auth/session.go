@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Cookie 名称
+const (
+	sessionCookie = "chuan_session" // 登录会话
+	loginCookie   = "chuan_oidc"    // 登录过程中的 state / PKCE verifier / nonce
+)
+
+// session 登录会话
+type session struct {
+	Identity  Identity  `json:"identity"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// loginState 登录跳转前保存的状态
+type loginState struct {
+	State     string    `json:"state"`
+	Verifier  string    `json:"verifier"`
+	Nonce     string    `json:"nonce"`
+	Redirect  string    `json:"redirect"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// sessionCodec 将数据编码为 HMAC 签名的 Cookie 值：base64url(json).base64url(mac)
+//
+// MAC 覆盖 Cookie 名称，一种 Cookie 的值不能冒充另一种，例如把登录状态当作会话使用。
+type sessionCodec struct {
+	secret []byte
+}
+
+func (c *sessionCodec) encode(name string, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(c.sign(name, payload)), nil
+}
+
+func (c *sessionCodec) decode(name string, value string, v interface{}) error {
+	payload, mac, ok := strings.Cut(value, ".")
+	if !ok {
+		return errors.New("Cookie 格式无效")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(mac)
+	if err != nil || !hmac.Equal(signature, c.sign(name, payload)) {
+		return errors.New("Cookie 签名无效")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// sign 计算 HMAC(secret, name || 0x00 || payload)
+func (c *sessionCodec) sign(name string, payload string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
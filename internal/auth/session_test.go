@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chuan/internal/config"
+)
+
+func TestSessionCookie(t *testing.T) {
+	a := &Authenticator{
+		verifier: newVerifier("", ""),
+		sessions: &sessionCodec{secret: []byte("test-secret")},
+	}
+	other := &sessionCodec{secret: []byte("other-secret")}
+	id := Identity{Subject: "user-1", Issuer: testIssuer}
+
+	encode := func(c *sessionCodec, s session) string {
+		value, err := c.encode(sessionCookie, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	valid := encode(a.sessions, session{Identity: id, ExpiresAt: time.Now().Add(time.Hour)})
+	payload, mac, _ := strings.Cut(valid, ".")
+	tamperedMAC, _ := base64.RawURLEncoding.DecodeString(mac)
+	tamperedMAC[0] ^= 0xff
+	forged, _, _ := strings.Cut(encode(a.sessions, session{Identity: Identity{Subject: "admin"}, ExpiresAt: time.Now().Add(time.Hour)}), ".")
+
+	for _, c := range []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"有效", valid, true},
+		{"已过期", encode(a.sessions, session{Identity: id, ExpiresAt: time.Now().Add(-time.Second)}), false},
+		{"篡改内容", forged + "." + mac, false},
+		{"篡改签名", payload + "." + base64.RawURLEncoding.EncodeToString(tamperedMAC), false},
+		{"其他密钥签名", encode(other, session{Identity: id, ExpiresAt: time.Now().Add(time.Hour)}), false},
+		{"缺少 sub", encode(a.sessions, session{ExpiresAt: time.Now().Add(time.Hour)}), false},
+		{"缺少签名", payload, false},
+		{"空签名", payload + ".", false},
+		{"空值", "", false},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: sessionCookie, Value: c.value})
+		got, err := a.Identify(r)
+		if c.ok && (err != nil || got.Subject != id.Subject) {
+			t.Errorf("%s: 期望识别为 %s，得到 %v, %v", c.name, id.Subject, got, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("%s: 期望拒绝，得到 %v", c.name, got)
+		}
+	}
+}
+
+func TestLoginCookieIsNotSession(t *testing.T) {
+	a := &Authenticator{
+		cfg:      config.AuthConfig{ClientID: "chuan", RedirectURL: "https://transfer.example.com/auth/callback"},
+		provider: &provider{AuthorizationEndpoint: "https://idp.example.com/authorize"},
+		verifier: newVerifier("", ""),
+		sessions: &sessionCodec{secret: []byte("test-secret")},
+	}
+
+	// 匿名访问登录地址得到签名的登录状态 Cookie
+	w := httptest.NewRecorder()
+	a.LoginHandler(w, httptest.NewRequest("GET", "/auth/login", nil))
+	var login *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == loginCookie {
+			login = c
+		}
+	}
+	if login == nil {
+		t.Fatal("登录时没有设置登录状态 Cookie")
+	}
+
+	// 把登录状态 Cookie 的值当作会话 Cookie 发送，不能通过认证
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: login.Value})
+	if id, err := a.Identify(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("登录状态 Cookie 被当作会话接受: %v, %v", id, err)
+	}
+}
@@ -0,0 +1,59 @@
+// Package basepath 支持将服务部署在反向代理的子路径下，例如 https://tools.example.com/transfer/。
+//
+// Strip 去掉请求路径中的前缀后再交给路由，各处理器按根路径处理请求；需要返回给客户端的
+// 站内地址（跳转、Location 等）通过 Join 加上前缀。
+package basepath
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Strip 去掉请求路径中的前缀，不在前缀下的请求返回 404，访问前缀本身时跳转到 prefix/
+//
+// prefix 为空时不做任何处理。
+func Strip(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if prefix == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == prefix {
+				target := prefix + "/"
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, http.StatusMovedPermanently)
+				return
+			}
+			if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+				http.NotFound(w, r)
+				return
+			}
+
+			r2 := r.WithContext(context.WithValue(r.Context(), contextKey{}, prefix))
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			if r.URL.RawPath != "" {
+				r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+			}
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// From 请求所在的路径前缀，部署在根路径时为空
+func From(r *http.Request) string {
+	prefix, _ := r.Context().Value(contextKey{}).(string)
+	return prefix
+}
+
+// Join 在站内路径前加上请求所在的路径前缀
+func Join(r *http.Request, path string) string {
+	return From(r) + path
+}
@@ -0,0 +1,179 @@
+// Package clientip 确定 HTTP 请求的客户端 IP，供配额统计、审计日志等使用。
+//
+// 服务部署在反向代理之后时，只有直接来自受信任代理的请求才按 Forwarded 或 X-Forwarded-For
+// 确定客户端 IP，其他请求的这些请求头可以被客户端伪造，一律忽略。
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type contextKey struct{}
+
+// From 返回请求的客户端 IP，经过 Proxies.Middleware 时为代理转发前的地址
+func From(r *http.Request) string {
+	if ip, ok := r.Context().Value(contextKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// Proxies 受信任的反向代理地址，可以在运行时更新
+type Proxies struct {
+	mu   sync.RWMutex
+	nets []*net.IPNet
+}
+
+// NewProxies 根据 CIDR 或 IP 列表创建受信任代理，列表为空时不信任任何代理
+func NewProxies(list []string) (*Proxies, error) {
+	p := &Proxies{}
+	if err := p.Set(list); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Set 替换受信任代理列表，列表无效时保持不变
+func (p *Proxies) Set(list []string) error {
+	nets, err := ParseNets(list)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nets = nets
+	return nil
+}
+
+// ParseNets 解析 CIDR 或 IP 列表，单个 IP 视为只包含该地址的网段
+func ParseNets(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if strings.Contains(item, "/") {
+			_, ipNet, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, fmt.Errorf("无效的代理地址 %q", item)
+			}
+			nets = append(nets, ipNet)
+			continue
+		}
+		ip := net.ParseIP(item)
+		if ip == nil {
+			return nil, fmt.Errorf("无效的代理地址 %q", item)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets, nil
+}
+
+// Middleware 确定客户端 IP 并保存在请求上下文中，之后通过 From 读取
+func (p *Proxies) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := p.resolve(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, ip)))
+	})
+}
+
+// resolve 从直接连接的地址开始，沿转发链从后向前跳过受信任的代理，第一个不受信任的地址即为客户端
+//
+// 同时存在 Forwarded 和 X-Forwarded-For 时使用 Forwarded。转发链中出现无法解析的地址时，
+// 之前的部分不可信，使用最后一个受信任代理的地址。
+func (p *Proxies) resolve(r *http.Request) string {
+	ip := remoteIP(r)
+	if !p.trusted(ip) {
+		return ip
+	}
+
+	chain := forwardedFor(r.Header.Values("Forwarded"))
+	if len(chain) == 0 {
+		chain = xForwardedFor(r.Header.Values("X-Forwarded-For"))
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := net.ParseIP(chain[i])
+		if hop == nil {
+			break
+		}
+		ip = hop.String()
+		if !p.trusted(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// trusted 地址是否属于受信任的代理
+func (p *Proxies) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, n := range p.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP 直接连接的地址
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// xForwardedFor 解析 X-Forwarded-For，多个请求头按出现顺序连接
+func xForwardedFor(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(hop))
+		}
+	}
+	return chain
+}
+
+// forwardedFor 解析 RFC 7239 Forwarded 中各节点的 for 参数，去掉引号、方括号和端口
+//
+// 没有 for 参数的节点记为空字符串，使转发链在该处中断。
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+					continue
+				}
+				hop = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+			if strings.HasPrefix(hop, "[") {
+				// [2001:db8::1]:4711
+				if end := strings.IndexByte(hop, ']'); end > 0 {
+					hop = hop[1:end]
+				}
+			} else if host, _, err := net.SplitHostPort(hop); err == nil {
+				hop = host
+			}
+			chain = append(chain, hop)
+		}
+	}
+	return chain
+}
@@ -0,0 +1,574 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"chuan/internal/models"
+)
+
+// Config 服务器运行配置，可以通过 JSON 配置文件加载，命令行参数优先
+type Config struct {
+	Port int `json:"port"` // 监听端口
+
+	// 房间生命周期
+	RoomTTL           Duration `json:"room_ttl"`            // 创建房间时未指定有效期时使用的默认值
+	MinRoomTTL        Duration `json:"room_ttl_min"`        // 允许客户端申请的最短有效期
+	MaxRoomTTL        Duration `json:"room_ttl_max"`        // 允许客户端申请（含延期）的最长有效期
+	RoomExpiryWarning Duration `json:"room_expiry_warning"` // 过期前多久向客户端发送 expiring-soon 提醒
+
+	RoomTypes map[string]RoomTypeConfig `json:"room_types"` // 按房间类型（file、text、desktop）覆盖有效期
+
+	AllowedOrigins []string    `json:"allowed_origins"` // 允许跨域请求和建立信令连接的来源，例如 https://transfer.example.com；为空或包含 * 时不限制
+	ICEServers     []ICEServer `json:"ice_servers"`     // 浏览器建立 P2P 连接使用的 STUN/TURN 服务器
+
+	BasePath       string   `json:"base_path"`       // 部署在反向代理的子路径下时的路径前缀，例如 /transfer；为空时部署在根路径
+	TrustedProxies []string `json:"trusted_proxies"` // 受信任的反向代理（CIDR 或 IP），只有来自这些地址的请求才按 Forwarded/X-Forwarded-For 确定客户端 IP
+
+	Webhook     WebhookConfig     `json:"webhook"`     // 房间事件 Webhook
+	Text        TextConfig        `json:"text"`        // 文本片段存储
+	Store       StoreConfig       `json:"store"`       // 暂存转发文件模式
+	Tus         TusConfig         `json:"tus"`         // tus 断点续传上传
+	Relay       RelayConfig       `json:"relay"`       // HTTP 流式中继
+	Auth        AuthConfig        `json:"auth"`        // 身份认证
+	APIKeys     APIKeyConfig      `json:"api_keys"`    // 脚本访问使用的 API Key
+	Quota       QuotaConfig       `json:"quota"`       // 配额
+	Audit       AuditConfig       `json:"audit"`       // 审计日志
+	API         APIConfig         `json:"api"`         // 版本化 REST API
+	GRPC        GRPCConfig        `json:"grpc"`        // gRPC 接口
+	Shutdown    ShutdownConfig    `json:"shutdown"`    // 优雅关闭
+	Maintenance MaintenanceConfig `json:"maintenance"` // 维护模式
+	Web         WebConfig         `json:"web"`         // 前端页面
+}
+
+// WebConfig 前端页面配置，除 dir 和 dev 外的内容在页面中以运行时配置注入，同一份前端构建可以用于不同环境
+type WebConfig struct {
+	Dir        string   `json:"dir"`          // 从磁盘目录提供前端文件，为空时使用编译时嵌入的文件
+	Dev        bool     `json:"dev"`          // 开发模式：监视 dir 中的文件变化，重新加载并刷新已打开的页面
+	APIBaseURL string   `json:"api_base_url"` // 页面访问 API 和信令使用的地址，为空时使用页面所在的地址
+	Branding   Branding `json:"branding"`     // 页面显示的名称和标志
+}
+
+// Branding 页面显示的名称和标志，为空的字段使用前端的默认值
+type Branding struct {
+	Name        string `json:"name,omitempty"`        // 站点名称
+	Description string `json:"description,omitempty"` // 名称下方的说明
+	LogoURL     string `json:"logo_url,omitempty"`    // 标志图片地址
+}
+
+// ICEServer STUN/TURN 服务器，格式与浏览器的 RTCIceServer 一致
+type ICEServer struct {
+	URLs       []string `json:"urls"`                 // stun:、stuns:、turn: 或 turns: 地址
+	Username   string   `json:"username,omitempty"`   // TURN 用户名
+	Credential string   `json:"credential,omitempty"` // TURN 密码
+}
+
+// MaintenanceConfig 维护模式配置，运行时通过管理接口或 SIGUSR1 切换
+type MaintenanceConfig struct {
+	Message string `json:"message"` // 通过 SIGUSR1 进入维护模式时的提示，同时作为公告发送给在线客户端
+}
+
+// ShutdownConfig 优雅关闭配置：先通知客户端，等待其自行断开后再关闭连接
+type ShutdownConfig struct {
+	Drain          Duration `json:"drain"`           // 通知客户端后最长等待多久，0 表示立即关闭连接
+	ReconnectDelay Duration `json:"reconnect_delay"` // 建议客户端断开后等待多久重新连接
+}
+
+// GRPCConfig gRPC 接口配置，在独立端口上提供房间管理和信令流
+//
+// 启用身份认证或 API Key 时必须配置 TLS 证书，否则令牌和 Key 会以明文传输。
+type GRPCConfig struct {
+	Enabled  bool   `json:"enabled"`   // 是否启用，默认关闭
+	Port     int    `json:"port"`      // 监听端口，不能与 HTTP 端口相同
+	CertFile string `json:"cert_file"` // TLS 证书文件（PEM），为空时不使用 TLS
+	KeyFile  string `json:"key_file"`  // TLS 私钥文件（PEM）
+}
+
+// APIConfig 版本化 REST API（/api/v1）配置，请求始终按 OpenAPI 文档校验
+type APIConfig struct {
+	ValidateResponses bool `json:"validate_responses"` // 检查响应是否符合文档，不一致时记录日志，用于开发和测试
+}
+
+// AuditConfig 审计日志配置：记录房间创建、加入、离开、过期、房主操作和管理操作，不记录传输内容
+type AuditConfig struct {
+	Enabled bool   `json:"enabled"`  // 是否启用，默认关闭
+	Dir     string `json:"dir"`      // 日志目录
+	MaxSize int64  `json:"max_size"` // 单个文件的最大字节数，超过后轮转，0 表示不轮转
+	KeyFile string `json:"key_file"` // 哈希链的 HMAC 密钥文件，不存在时自动生成，不能放在日志目录中
+}
+
+// QuotaConfig 配额配置，所有上限为 0 表示不限制，默认全部关闭
+//
+// 按用户统计（已登录或使用 API Key 时），否则按客户端 IP 统计。
+type QuotaConfig struct {
+	MaxRooms          int   `json:"max_rooms"`            // 服务器同时存在的房间总数上限
+	RoomsPerSubject   int   `json:"rooms_per_subject"`    // 每个用户或 IP 同时占用的房间数上限
+	RoomsPerHour      int   `json:"rooms_per_hour"`       // 每个用户或 IP 每小时创建的房间数上限
+	RelayBytesPerDay  int64 `json:"relay_bytes_per_day"`  // 每个用户或 IP 每天通过流式中继发送的字节数上限
+	StoredBytesPerDay int64 `json:"stored_bytes_per_day"` // 每个用户或 IP 每天上传暂存的字节数上限（含断点续传）
+}
+
+// PerSubject 是否设置了按用户或 IP 统计的配额
+func (q QuotaConfig) PerSubject() bool {
+	return q.RoomsPerSubject > 0 || q.RoomsPerHour > 0 || q.RelayBytesPerDay > 0 || q.StoredBytesPerDay > 0
+}
+
+// APIKeyConfig API Key 配置，Key 通过 apikey 子命令管理
+type APIKeyConfig struct {
+	Enabled   bool   `json:"enabled"`    // 是否启用，默认关闭
+	File      string `json:"file"`       // Key 文件路径，只保存 Key 的摘要
+	Required  bool   `json:"required"`   // 创建房间是否必须提供 API Key（已登录的用户除外）
+	RateLimit int    `json:"rate_limit"` // 未单独设置时每个 Key 每分钟允许的请求数，0 表示不限制
+}
+
+// AuthConfig 身份认证配置：启用后创建房间（以及发送方加入房间）需要登录
+//
+// 支持两种凭证：Authorization: Bearer <JWT>（通过 JWKS 校验签名），
+// 以及浏览器通过 OIDC 授权码模式（PKCE）登录后获得的会话 Cookie。
+type AuthConfig struct {
+	Enabled         bool     `json:"enabled"`          // 是否启用，默认关闭
+	Issuer          string   `json:"issuer"`           // OIDC issuer，用于 discovery 和校验 iss
+	ClientID        string   `json:"client_id"`        // OIDC 客户端 ID
+	ClientSecret    string   `json:"client_secret"`    // OIDC 客户端密钥，公共客户端可留空
+	RedirectURL     string   `json:"redirect_url"`     // 登录回调地址，例如 https://transfer.example.com/auth/callback
+	Scopes          []string `json:"scopes"`           // 登录时申请的 scope
+	JWKSURL         string   `json:"jwks_url"`         // JWKS 地址，为空时使用 discovery 返回的地址
+	Audience        []string `json:"audience"`         // Bearer JWT 接受的 aud，为空时使用 client_id
+	SessionSecret   string   `json:"session_secret"`   // 会话 Cookie 签名密钥，为空时每次启动随机生成
+	SessionTTL      Duration `json:"session_ttl"`      // 登录会话有效期
+	RequireReceiver bool     `json:"require_receiver"` // 接收方加入房间是否也需要登录
+}
+
+// RelayConfig HTTP 流式中继配置：发送方 PUT 的同时接收方 GET，服务器只在内存中转发
+type RelayConfig struct {
+	Enabled     bool     `json:"enabled"`      // 是否启用，默认关闭
+	BufferSize  int      `json:"buffer_size"`  // 每个中继在内存中缓冲的最大字节数
+	WaitTimeout Duration `json:"wait_timeout"` // 等待另一端连接的最长时间
+}
+
+// TusConfig tus 断点续传上传配置，上传的文件与房间绑定，随房间过期删除
+type TusConfig struct {
+	Enabled bool   `json:"enabled"`  // 是否启用，默认关闭
+	Dir     string `json:"dir"`      // 上传文件存储目录
+	MaxSize int64  `json:"max_size"` // 单个上传的最大字节数
+}
+
+// StoreConfig 暂存转发文件模式配置：发送方上传客户端加密后的文件，接收方稍后通过 HTTP 下载
+type StoreConfig struct {
+	Enabled         bool     `json:"enabled"`           // 是否启用，默认关闭
+	Dir             string   `json:"dir"`               // 文件存储目录
+	MaxFileSize     int64    `json:"max_file_size"`     // 单个文件的最大字节数
+	MaxTotalSize    int64    `json:"max_total_size"`    // 所有暂存文件的总字节数上限
+	DefaultTTL      Duration `json:"default_ttl"`       // 未指定有效期时使用的默认值
+	MaxTTL          Duration `json:"max_ttl"`           // 允许的最长有效期
+	DefaultDownload int      `json:"default_downloads"` // 未指定时的最大下载次数
+	MaxDownloads    int      `json:"max_downloads"`     // 允许设置的最大下载次数
+}
+
+// TextConfig 文本片段存储配置
+type TextConfig struct {
+	Storage    string   `json:"storage"`     // 存储后端：memory 或 disk
+	Dir        string   `json:"dir"`         // disk 后端的存储目录
+	MaxSize    int      `json:"max_size"`    // 单个文本的最大字节数
+	DefaultTTL Duration `json:"default_ttl"` // 未指定有效期时使用的默认值
+	MaxTTL     Duration `json:"max_ttl"`     // 允许的最长有效期
+}
+
+// WebhookConfig Webhook 投递配置
+type WebhookConfig struct {
+	Endpoints  []WebhookEndpoint `json:"endpoints"`
+	MaxRetries int               `json:"max_retries"` // 失败后的最大重试次数
+	Timeout    Duration          `json:"timeout"`     // 单次请求超时
+	QueueSize  int               `json:"queue_size"`  // 每个端点的待投递队列长度，队列满时丢弃新事件
+}
+
+// RoomTypeConfig 某一类型房间的有效期，0 表示使用全局的 room_ttl 和 room_ttl_max
+type RoomTypeConfig struct {
+	RoomTTL    Duration `json:"room_ttl"`     // 默认有效期
+	MaxRoomTTL Duration `json:"room_ttl_max"` // 最长有效期（含延期）
+}
+
+// WebhookEndpoint 单个 Webhook 接收端
+type WebhookEndpoint struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"` // HMAC-SHA256 签名密钥
+	Events []string `json:"events"` // 订阅的事件类型，为空表示全部事件
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Port:              7777,
+		RoomTTL:           Duration{time.Hour},
+		MinRoomTTL:        Duration{5 * time.Minute},
+		MaxRoomTTL:        Duration{24 * time.Hour},
+		RoomExpiryWarning: Duration{5 * time.Minute},
+		ICEServers: []ICEServer{
+			{URLs: []string{"stun:stun.l.google.com:19302"}},
+			{URLs: []string{"stun:stun1.l.google.com:19302"}},
+			{URLs: []string{"stun:stun2.l.google.com:19302"}},
+			{URLs: []string{"stun:global.stun.twilio.com:3478"}},
+		},
+		RoomTypes: map[string]RoomTypeConfig{
+			// 桌面共享通常持续较长时间
+			models.RoomTypeDesktop: {
+				RoomTTL:    Duration{4 * time.Hour},
+				MaxRoomTTL: Duration{72 * time.Hour},
+			},
+		},
+		Webhook: WebhookConfig{
+			MaxRetries: 5,
+			Timeout:    Duration{10 * time.Second},
+			QueueSize:  1024,
+		},
+		Text: TextConfig{
+			Storage:    "memory",
+			Dir:        "data/text",
+			MaxSize:    64 << 10,
+			DefaultTTL: Duration{time.Hour},
+			MaxTTL:     Duration{24 * time.Hour},
+		},
+		Store: StoreConfig{
+			Enabled:         false,
+			Dir:             "data/store",
+			MaxFileSize:     1 << 30,
+			MaxTotalSize:    10 << 30,
+			DefaultTTL:      Duration{24 * time.Hour},
+			MaxTTL:          Duration{7 * 24 * time.Hour},
+			DefaultDownload: 1,
+			MaxDownloads:    100,
+		},
+		Tus: TusConfig{
+			Enabled: false,
+			Dir:     "data/tus",
+			MaxSize: 4 << 30,
+		},
+		Relay: RelayConfig{
+			Enabled:     false,
+			BufferSize:  1 << 20,
+			WaitTimeout: Duration{10 * time.Minute},
+		},
+		GRPC: GRPCConfig{
+			Port: 7778,
+		},
+		Maintenance: MaintenanceConfig{
+			Message: "服务器即将维护，正在进行的传输不受影响，暂时不能创建新房间",
+		},
+		Shutdown: ShutdownConfig{
+			Drain:          Duration{10 * time.Second},
+			ReconnectDelay: Duration{3 * time.Second},
+		},
+		Audit: AuditConfig{
+			Dir:     "data/audit",
+			MaxSize: 64 << 20,
+			KeyFile: "data/audit.key",
+		},
+		APIKeys: APIKeyConfig{
+			File:      "data/api_keys.json",
+			RateLimit: 60,
+		},
+		Auth: AuthConfig{
+			Scopes:     []string{"openid", "profile", "email"},
+			SessionTTL: Duration{12 * time.Hour},
+		},
+	}
+}
+
+// LoadFile 从 JSON 配置文件加载配置，文件中未出现的字段保持原值
+func LoadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	return nil
+}
+
+// Validate 校验配置是否合法
+func (c *Config) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("端口无效: %d", c.Port)
+	}
+	if c.MinRoomTTL.Duration <= 0 {
+		return fmt.Errorf("房间最短有效期必须大于0")
+	}
+	if c.MaxRoomTTL.Duration < c.MinRoomTTL.Duration {
+		return fmt.Errorf("房间最长有效期 %s 小于最短有效期 %s", c.MaxRoomTTL, c.MinRoomTTL)
+	}
+	if c.RoomTTL.Duration < c.MinRoomTTL.Duration || c.RoomTTL.Duration > c.MaxRoomTTL.Duration {
+		return fmt.Errorf("房间默认有效期 %s 不在 [%s, %s] 范围内", c.RoomTTL, c.MinRoomTTL, c.MaxRoomTTL)
+	}
+	for t := range c.RoomTypes {
+		if !models.ValidRoomType(t) {
+			return fmt.Errorf("未知的房间类型: %s", t)
+		}
+		if max := c.MaxRoomTTLFor(t); max < c.MinRoomTTL.Duration {
+			return fmt.Errorf("%s 房间最长有效期 %s 小于最短有效期 %s", t, max, c.MinRoomTTL)
+		}
+		if ttl, max := c.roomTTLFor(t), c.MaxRoomTTLFor(t); ttl < c.MinRoomTTL.Duration || ttl > max {
+			return fmt.Errorf("%s 房间默认有效期 %s 不在 [%s, %s] 范围内", t, ttl, c.MinRoomTTL, max)
+		}
+	}
+	if c.RoomExpiryWarning.Duration < 0 {
+		return fmt.Errorf("过期提醒时间不能为负数")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			return fmt.Errorf("允许的来源无效: %q，格式应为 https://example.com", origin)
+		}
+	}
+	if c.BasePath != "" && (!strings.HasPrefix(c.BasePath, "/") || path.Clean(c.BasePath) != c.BasePath || c.BasePath == "/" || strings.ContainsAny(c.BasePath, "?#")) {
+		return fmt.Errorf("路径前缀无效: %q，格式应为 /transfer（以 / 开头，不以 / 结尾）", c.BasePath)
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("受信任的代理无效: %q，格式应为 10.0.0.0/8 或 10.0.0.1", proxy)
+		}
+	}
+	if c.Web.Dev && c.Web.Dir == "" {
+		return fmt.Errorf("前端开发模式需要指定前端目录")
+	}
+	if c.Web.APIBaseURL != "" {
+		u, err := url.Parse(c.Web.APIBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("前端 API 地址无效: %q", c.Web.APIBaseURL)
+		}
+	}
+	for i, server := range c.ICEServers {
+		if err := server.validate(); err != nil {
+			return fmt.Errorf("第 %d 个 ICE 服务器无效: %w", i+1, err)
+		}
+	}
+	if err := c.Webhook.validate(); err != nil {
+		return err
+	}
+	if err := c.Text.validate(); err != nil {
+		return err
+	}
+	if err := c.Store.validate(); err != nil {
+		return err
+	}
+	if c.Tus.Enabled {
+		if c.Tus.Dir == "" {
+			return fmt.Errorf("tus 上传必须指定存储目录")
+		}
+		if c.Tus.MaxSize <= 0 {
+			return fmt.Errorf("tus 上传大小限制必须大于0")
+		}
+	}
+	if err := c.Auth.validate(); err != nil {
+		return err
+	}
+	if c.Quota.MaxRooms < 0 || c.Quota.RoomsPerSubject < 0 || c.Quota.RoomsPerHour < 0 ||
+		c.Quota.RelayBytesPerDay < 0 || c.Quota.StoredBytesPerDay < 0 {
+		return fmt.Errorf("配额不能为负数")
+	}
+	if c.Shutdown.Drain.Duration < 0 || c.Shutdown.ReconnectDelay.Duration < 0 {
+		return fmt.Errorf("优雅关闭的等待时间不能为负数")
+	}
+	if c.GRPC.Enabled {
+		if c.GRPC.Port <= 0 || c.GRPC.Port > 65535 {
+			return fmt.Errorf("gRPC 端口无效: %d", c.GRPC.Port)
+		}
+		if c.GRPC.Port == c.Port {
+			return fmt.Errorf("gRPC 端口不能与 HTTP 端口相同: %d", c.Port)
+		}
+		if (c.GRPC.CertFile == "") != (c.GRPC.KeyFile == "") {
+			return fmt.Errorf("gRPC TLS 证书和私钥必须同时指定")
+		}
+		if c.GRPC.CertFile == "" && (c.Auth.Enabled || c.APIKeys.Enabled) {
+			return fmt.Errorf("启用身份认证或 API Key 时 gRPC 接口必须配置 TLS 证书（grpc.cert_file、grpc.key_file）")
+		}
+	}
+	if c.Audit.Enabled {
+		if c.Audit.Dir == "" {
+			return fmt.Errorf("审计日志必须指定目录")
+		}
+		if c.Audit.MaxSize < 0 {
+			return fmt.Errorf("审计日志文件大小不能为负数")
+		}
+		if c.Audit.KeyFile == "" {
+			return fmt.Errorf("审计日志必须指定密钥文件")
+		}
+		if rel, err := filepath.Rel(c.Audit.Dir, c.Audit.KeyFile); err == nil && !strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("审计日志密钥文件不能放在日志目录中")
+		}
+	}
+	if c.APIKeys.Enabled {
+		if c.APIKeys.File == "" {
+			return fmt.Errorf("API Key 必须指定文件路径")
+		}
+		if c.APIKeys.RateLimit < 0 {
+			return fmt.Errorf("API Key 请求频率限制不能为负数")
+		}
+	}
+	if c.Relay.Enabled {
+		if c.Relay.BufferSize <= 0 {
+			return fmt.Errorf("中继缓冲区大小必须大于0")
+		}
+		if c.Relay.WaitTimeout.Duration <= 0 {
+			return fmt.Errorf("中继等待时间必须大于0")
+		}
+	}
+	return nil
+}
+
+func (s *ICEServer) validate() error {
+	if len(s.URLs) == 0 {
+		return fmt.Errorf("缺少地址")
+	}
+	for _, u := range s.URLs {
+		scheme, _, _ := strings.Cut(u, ":")
+		switch scheme {
+		case "stun", "stuns":
+		case "turn", "turns":
+			if s.Username == "" || s.Credential == "" {
+				return fmt.Errorf("TURN 服务器 %s 缺少用户名或密码", u)
+			}
+		default:
+			return fmt.Errorf("不支持的地址 %q，应以 stun:、stuns:、turn: 或 turns: 开头", u)
+		}
+	}
+	return nil
+}
+
+func (c *WebhookConfig) validate() error {
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("Webhook 重试次数不能为负数")
+	}
+	if c.Timeout.Duration <= 0 {
+		return fmt.Errorf("Webhook 超时时间必须大于0")
+	}
+	if c.QueueSize <= 0 {
+		return fmt.Errorf("Webhook 队列长度必须大于0")
+	}
+	for i, ep := range c.Endpoints {
+		u, err := url.Parse(ep.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("第 %d 个 Webhook 地址无效: %q", i+1, ep.URL)
+		}
+		if ep.Secret == "" {
+			return fmt.Errorf("Webhook %s 缺少签名密钥", ep.URL)
+		}
+	}
+	return nil
+}
+
+func (c *AuthConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Issuer == "" && c.JWKSURL == "" {
+		return fmt.Errorf("身份认证必须配置 issuer 或 jwks_url")
+	}
+	if c.ClientID == "" && len(c.Audience) == 0 {
+		return fmt.Errorf("身份认证必须配置 client_id 或 audience")
+	}
+	if c.RedirectURL != "" {
+		if c.Issuer == "" || c.ClientID == "" {
+			return fmt.Errorf("OIDC 登录必须配置 issuer 和 client_id")
+		}
+		u, err := url.Parse(c.RedirectURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("OIDC 回调地址无效: %q", c.RedirectURL)
+		}
+	}
+	if c.SessionTTL.Duration <= 0 {
+		return fmt.Errorf("登录会话有效期必须大于0")
+	}
+	return nil
+}
+
+func (c *TextConfig) validate() error {
+	switch c.Storage {
+	case "memory":
+	case "disk":
+		if c.Dir == "" {
+			return fmt.Errorf("文本存储使用 disk 后端时必须指定目录")
+		}
+	default:
+		return fmt.Errorf("未知的文本存储后端: %s", c.Storage)
+	}
+	if c.MaxSize <= 0 {
+		return fmt.Errorf("文本最大长度必须大于0")
+	}
+	if c.DefaultTTL.Duration <= 0 || c.MaxTTL.Duration < c.DefaultTTL.Duration {
+		return fmt.Errorf("文本有效期配置无效: 默认 %s, 最长 %s", c.DefaultTTL, c.MaxTTL)
+	}
+	return nil
+}
+
+func (c *StoreConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Dir == "" {
+		return fmt.Errorf("暂存文件模式必须指定存储目录")
+	}
+	if c.MaxFileSize <= 0 || c.MaxTotalSize < c.MaxFileSize {
+		return fmt.Errorf("暂存文件大小限制无效: 单个 %d, 总计 %d", c.MaxFileSize, c.MaxTotalSize)
+	}
+	if c.DefaultTTL.Duration <= 0 || c.MaxTTL.Duration < c.DefaultTTL.Duration {
+		return fmt.Errorf("暂存文件有效期配置无效: 默认 %s, 最长 %s", c.DefaultTTL, c.MaxTTL)
+	}
+	if c.DefaultDownload < 1 || c.MaxDownloads < c.DefaultDownload {
+		return fmt.Errorf("暂存文件下载次数配置无效: 默认 %d, 最多 %d", c.DefaultDownload, c.MaxDownloads)
+	}
+	return nil
+}
+
+// ClampRoomTTL 将客户端申请的有效期限制在该类型房间允许的范围内，0 表示使用默认值
+func (c *Config) ClampRoomTTL(roomType string, ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return c.roomTTLFor(roomType)
+	}
+	if ttl < c.MinRoomTTL.Duration {
+		return c.MinRoomTTL.Duration
+	}
+	if max := c.MaxRoomTTLFor(roomType); ttl > max {
+		return max
+	}
+	return ttl
+}
+
+// MaxRoomTTLFor 该类型房间的最长有效期，未单独配置时使用 room_ttl_max
+func (c *Config) MaxRoomTTLFor(roomType string) time.Duration {
+	if t, ok := c.RoomTypes[roomType]; ok && t.MaxRoomTTL.Duration > 0 {
+		return t.MaxRoomTTL.Duration
+	}
+	return c.MaxRoomTTL.Duration
+}
+
+// roomTTLFor 该类型房间的默认有效期，未单独配置时使用 room_ttl
+func (c *Config) roomTTLFor(roomType string) time.Duration {
+	if t, ok := c.RoomTypes[roomType]; ok && t.RoomTTL.Duration > 0 {
+		return t.RoomTTL.Duration
+	}
+	return c.RoomTTL.Duration
+}
+
+// OriginAllowed 浏览器请求的 Origin 是否在允许的来源中，未配置时允许所有来源
+func (c *Config) OriginAllowed(origin string) bool {
+	if len(c.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
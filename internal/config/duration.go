@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration 在 JSON 中以 "30s"、"1h" 形式表示的时长，同时可以作为命令行参数使用
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，同时兼容以秒为单位的数字
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		d.Duration = time.Duration(value * float64(time.Second))
+		return nil
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("无效的时长 %q: %w", value, err)
+		}
+		d.Duration = parsed
+		return nil
+	default:
+		return fmt.Errorf("无效的时长: %s", string(data))
+	}
+}
+
+// Set 实现 flag.Value
+func (d *Duration) Set(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// restartOnly 需要重启才能生效的配置项（JSON 名称），重新加载时保留当前值
+//
+// 这些配置决定监听端口、存储目录和各功能是否启用，服务启动后不能安全地替换。
+// api_keys 只有 rate_limit、web 只有 dir 和 dev 以外的部分可以在运行时调整，单独处理。
+var restartOnly = map[string]bool{
+	"port":    true,
+	"webhook": true,
+	"text":    true,
+	"store":   true,
+	"tus":     true,
+	"relay":   true,
+	"auth":    true,
+	"audit":   true,
+	"api":     true,
+	"grpc":    true,
+
+	// 路由在启动时按前缀注册
+	"base_path": true,
+}
+
+// ReloadResult 重新加载配置的结果
+type ReloadResult struct {
+	Config  *Config  // 生效的配置
+	Changed []string // 已生效的变更（JSON 名称）
+	Ignored []string // 需要重启才能生效、本次被忽略的变更
+}
+
+// Reload 合并当前配置和重新加载的配置，next 需要已经通过校验
+//
+// 房间有效期、配额、API Key 默认限流、允许的来源、ICE 服务器、受信任的代理、维护和关闭提示采用新值，
+// 其余配置保留当前值并在 Ignored 中列出。current 和 next 都不会被修改。
+func Reload(current, next *Config) ReloadResult {
+	merged := *next
+	var result ReloadResult
+
+	// API Key 只有默认限流可以在运行时调整
+	apiKeys := current.APIKeys
+	apiKeys.RateLimit = next.APIKeys.RateLimit
+	if !reflect.DeepEqual(apiKeys, next.APIKeys) {
+		result.Ignored = append(result.Ignored, "api_keys")
+	}
+	if apiKeys.RateLimit != current.APIKeys.RateLimit {
+		result.Changed = append(result.Changed, "api_keys.rate_limit")
+	}
+	merged.APIKeys = apiKeys
+
+	// 前端目录和开发模式需要重启
+	web := next.Web
+	web.Dir, web.Dev = current.Web.Dir, current.Web.Dev
+	if web.Dir != next.Web.Dir {
+		result.Ignored = append(result.Ignored, "web.dir")
+	}
+	if web.Dev != next.Web.Dev {
+		result.Ignored = append(result.Ignored, "web.dev")
+	}
+	if !reflect.DeepEqual(web, current.Web) {
+		result.Changed = append(result.Changed, "web")
+	}
+	merged.Web = web
+
+	cv := reflect.ValueOf(current).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	mv := reflect.ValueOf(&merged).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		name := jsonName(cv.Type().Field(i))
+		if name == "api_keys" || name == "web" || reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		if restartOnly[name] {
+			mv.Field(i).Set(cv.Field(i))
+			result.Ignored = append(result.Ignored, name)
+		} else {
+			result.Changed = append(result.Changed, name)
+		}
+	}
+
+	result.Config = &merged
+	return result
+}
+
+// jsonName 字段在配置文件中的名称
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,112 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/models"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Middleware HTTP 认证中间件，gRPC 调用复用 HTTP 路由上的同一套认证和权限检查
+type Middleware func(http.Handler) http.Handler
+
+// Chain 按顺序组合中间件，nil 中间件被忽略
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if middlewares[i] != nil {
+				next = middlewares[i](next)
+			}
+		}
+		return next
+	}
+}
+
+// authorize 将 gRPC 调用转换为 HTTP 请求后执行认证中间件
+//
+// metadata 作为请求头（authorization、x-api-key、user-agent、accept-language 等），
+// 对端地址作为 RemoteAddr。中间件放行时返回带有身份信息的请求，拒绝时返回对应的 gRPC 错误。
+func authorize(ctx context.Context, method string, mw Middleware) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, method, nil)
+	if err != nil {
+		return nil, toStatus(apierr.Wrap(apierr.CodeInternal, err), apierr.DefaultLang)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			if strings.HasPrefix(key, ":") {
+				continue
+			}
+			for _, v := range values {
+				r.Header.Add(key, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	if mw == nil {
+		return r, nil
+	}
+
+	var authorized *http.Request
+	rec := &responseRecorder{header: make(http.Header)}
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized = r
+	})).ServeHTTP(rec, r)
+
+	if authorized == nil {
+		return nil, rec.status(apierr.LangFromRequest(r))
+	}
+	return authorized, nil
+}
+
+// responseRecorder 记录中间件写出的错误响应
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.code == 0 {
+		rec.code = code
+	}
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if rec.code == 0 {
+		rec.code = http.StatusOK
+	}
+	return rec.body.Write(p)
+}
+
+// status 将中间件的错误响应转换为 gRPC 错误
+func (rec *responseRecorder) status(lang string) error {
+	var resp models.ErrorResponse
+	json.Unmarshal(rec.body.Bytes(), &resp)
+
+	e := apierr.New(apierr.Code(resp.Code)).WithStatus(rec.code)
+	if resp.Code == "" {
+		e = apierr.New(apierr.CodeUnauthorized).WithStatus(rec.code)
+	}
+	for k, v := range resp.Details {
+		e.WithDetail(k, v)
+	}
+	if seconds, err := strconv.Atoi(rec.header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return toStatus(e, lang)
+}
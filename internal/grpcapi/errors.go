@@ -0,0 +1,61 @@
+package grpcapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"chuan/internal/services"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain ErrorInfo 的 domain
+const errorDomain = "chuan"
+
+// grpcCodes HTTP 状态码对应的 gRPC 状态码
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusMethodNotAllowed:      codes.Unimplemented,
+	http.StatusConflict:              codes.FailedPrecondition,
+	http.StatusGone:                  codes.Aborted,
+	http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusInsufficientStorage:   codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+	http.StatusGatewayTimeout:        codes.DeadlineExceeded,
+}
+
+// toStatus 将错误转换为 gRPC 状态
+//
+// 错误码放在 ErrorInfo.Reason 中，与 HTTP 接口的 code 字段一致；需要等待时附带 RetryInfo。
+func toStatus(err error, lang string) error {
+	e := services.ToAPIError(err)
+
+	code, ok := grpcCodes[e.Status]
+	if !ok {
+		code = codes.Internal
+	}
+	info := &errdetails.ErrorInfo{Reason: string(e.Code), Domain: errorDomain}
+	for k, v := range e.Details {
+		if info.Metadata == nil {
+			info.Metadata = make(map[string]string)
+		}
+		info.Metadata[k] = fmt.Sprint(v)
+	}
+
+	st := status.New(code, e.Message(lang))
+	if e.RetryAfter > 0 {
+		if withDetails, err := st.WithDetails(info, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)}); err == nil {
+			return withDetails.Err()
+		}
+	} else if withDetails, err := st.WithDetails(info); err == nil {
+		return withDetails.Err()
+	}
+	return st.Err()
+}
@@ -0,0 +1,308 @@
+// Package grpcapi 在独立端口上提供 gRPC 接口：房间管理和与 /ws/webrtc 等价的信令双向流
+//
+// gRPC 与 HTTP 接口共用 WebRTCService，gRPC 客户端和浏览器可以加入同一个房间；
+// 认证、权限和配额检查也与 HTTP 路由一致。
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/auth"
+	"chuan/internal/clientip"
+	"chuan/internal/services"
+	"chuan/pkg/api/chuanv1"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RPC 方法名，作为认证中间件看到的请求路径
+const (
+	methodCreateRoom    = "/chuan.v1.ChuanService/CreateRoom"
+	methodGetRoomStatus = "/chuan.v1.ChuanService/GetRoomStatus"
+	methodCloseRoom     = "/chuan.v1.ChuanService/CloseRoom"
+	methodSignal        = "/chuan.v1.ChuanService/Signal"
+)
+
+// Options 各个 RPC 使用的认证中间件，与对应的 HTTP 路由保持一致，nil 表示不检查
+type Options struct {
+	Identify       Middleware // 识别 API Key 和登录身份，所有 RPC 都会执行
+	CreateRoom     Middleware // 创建房间，发送方加入信令流时也会执行
+	ReadStatus     Middleware // 查询房间状态
+	JoinAsReceiver Middleware // 接收方加入信令流
+}
+
+// Server ChuanService 的实现
+type Server struct {
+	chuanv1.UnimplementedChuanServiceServer
+
+	webrtc *services.WebRTCService
+	opts   Options
+}
+
+// NewServer 创建 gRPC 服务
+func NewServer(webrtc *services.WebRTCService, opts Options) *Server {
+	return &Server{webrtc: webrtc, opts: opts}
+}
+
+// CreateRoom 创建房间，返回取件码和房主令牌
+func (s *Server) CreateRoom(ctx context.Context, req *chuanv1.CreateRoomRequest) (*chuanv1.CreateRoomResponse, error) {
+	r, err := authorize(ctx, methodCreateRoom, Chain(s.opts.Identify, s.opts.CreateRoom))
+	if err != nil {
+		return nil, err
+	}
+	lang := apierr.LangFromRequest(r)
+	if req.TtlSeconds < 0 {
+		return nil, toStatus(apierr.New(apierr.CodeInvalidParameter, "ttl_seconds"), lang)
+	}
+
+	roomType, err := services.ParseRoomType("type", req.Type)
+	if err != nil {
+		return nil, toStatus(err, lang)
+	}
+
+	room, err := s.webrtc.CreateNewRoom(services.CreateRoomOptions{
+		TTL:     time.Duration(req.TtlSeconds) * time.Second,
+		Type:    roomType,
+		Creator: requestActor(r),
+	})
+	if err != nil {
+		return nil, toStatus(err, lang)
+	}
+	log.Printf("gRPC 创建房间成功: %s", room.Code)
+
+	return &chuanv1.CreateRoomResponse{
+		Code:       room.Code,
+		OwnerToken: room.OwnerToken,
+		ExpiresAt:  timestamppb.New(room.ExpiresAt),
+		Type:       room.Type,
+	}, nil
+}
+
+// GetRoomStatus 查询房间状态
+func (s *Server) GetRoomStatus(ctx context.Context, req *chuanv1.GetRoomStatusRequest) (*chuanv1.RoomStatus, error) {
+	r, err := authorize(ctx, methodGetRoomStatus, Chain(s.opts.Identify, s.opts.ReadStatus))
+	if err != nil {
+		return nil, err
+	}
+	lang := apierr.LangFromRequest(r)
+	if req.Code == "" {
+		return nil, toStatus(apierr.New(apierr.CodeMissingCode), lang)
+	}
+
+	status, err := s.webrtc.RoomStatus(req.Code)
+	if err != nil {
+		return nil, toStatus(err, lang)
+	}
+	return &chuanv1.RoomStatus{
+		Code:           status.Code,
+		SenderOnline:   status.SenderOnline,
+		ReceiverOnline: status.ReceiverOnline,
+		CreatedAt:      timestamppb.New(status.CreatedAt),
+		ExpiresAt:      timestamppb.New(status.ExpiresAt),
+		Type:           status.Type,
+		State:          status.State,
+		StateTimes:     stateTimes(status.StateTimes),
+	}, nil
+}
+
+// CloseRoom 使用房主令牌关闭房间
+func (s *Server) CloseRoom(ctx context.Context, req *chuanv1.CloseRoomRequest) (*chuanv1.CloseRoomResponse, error) {
+	r, err := authorize(ctx, methodCloseRoom, s.opts.Identify)
+	if err != nil {
+		return nil, err
+	}
+	lang := apierr.LangFromRequest(r)
+	if req.Code == "" {
+		return nil, toStatus(apierr.New(apierr.CodeMissingCode), lang)
+	}
+	if req.OwnerToken == "" {
+		return nil, toStatus(apierr.New(apierr.CodeInvalidParameter, "owner_token"), lang)
+	}
+
+	if err := s.webrtc.CloseRoom(req.Code, req.OwnerToken, requestActor(r)); err != nil {
+		return nil, toStatus(err, lang)
+	}
+	log.Printf("gRPC 关闭房间: %s", req.Code)
+	return &chuanv1.CloseRoomResponse{}, nil
+}
+
+// Signal 信令双向流，第一条请求必须是 join
+//
+// 与 /ws/webrtc 相同，发送方加入不存在的房间时会自动创建房间并占用房间配额。
+// 房间被关闭或过期时流以 OK 结束，trailer 中的 close-reason 给出原因。
+func (s *Server) Signal(stream chuanv1.ChuanService_SignalServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	join := first.GetJoin()
+	if join == nil {
+		return toStatus(apierr.New(apierr.CodeInvalidParameter, "join"), metadataLang(ctx))
+	}
+	role := roleOf(join.Role)
+
+	// 只有发送方加入不存在的房间（会自动创建房间）时才需要创建房间的权限；
+	// 加入已有房间的发送方不能在房间恰好关闭后借此创建房间
+	mw := s.opts.JoinAsReceiver
+	joinOnly := false
+	if role == services.RoleSender {
+		if _, err := s.webrtc.RoomExpiresAt(join.Code); err == nil {
+			mw, joinOnly = nil, true
+		} else {
+			mw = s.opts.CreateRoom
+		}
+	}
+	r, err := authorize(ctx, methodSignal, Chain(s.opts.Identify, mw))
+	if err != nil {
+		return err
+	}
+	lang := apierr.LangFromRequest(r)
+	actor := requestActor(r)
+
+	peer := &streamPeer{stream: stream, closed: make(chan struct{})}
+	client, err := s.webrtc.Join(join.Code, role, join.Type, services.NewPeerConn(peer), services.PeerInfo{
+		Identity:  actor.Identity,
+		IP:        actor.IP,
+		UserAgent: actor.UserAgent,
+		Lang:      lang,
+		JoinOnly:  joinOnly,
+	})
+	if err != nil {
+		log.Printf("gRPC %s加入房间失败: %s: %v", role, join.Code, err)
+		return toStatus(err, lang)
+	}
+	defer s.webrtc.Leave(client)
+
+	// 读取客户端消息，流结束或出错时通知主循环
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			msg := req.GetMessage()
+			if msg == nil {
+				recvErr <- toStatus(apierr.New(apierr.CodeInvalidMessage), lang)
+				return
+			}
+			s.webrtc.Receive(client, fromProto(msg))
+		}
+	}()
+
+	select {
+	case err := <-recvErr:
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	case <-peer.closed:
+		stream.SetTrailer(metadata.Pairs("close-reason", peer.reason))
+		return nil
+	}
+}
+
+// streamPeer gRPC 信令流连接，由 services.NewPeerConn 的写协程调用 Write
+type streamPeer struct {
+	stream chuanv1.ChuanService_SignalServer
+	closed chan struct{}
+	once   sync.Once
+	reason string
+}
+
+func (p *streamPeer) Write(msg *services.WebRTCMessage) error {
+	select {
+	case <-p.closed:
+		return errors.New("信令流已关闭")
+	default:
+	}
+	return p.stream.Send(toProto(msg))
+}
+
+// Close 结束 Signal 调用，阻塞在 Send 中的写协程随流的上下文取消而返回
+func (p *streamPeer) Close(code int, reason string) error {
+	p.once.Do(func() {
+		p.reason = reason
+		close(p.closed)
+	})
+	return nil
+}
+
+// toProto 将信令消息转换为 protobuf 消息，负载保持 JSON 编码
+func toProto(msg *services.WebRTCMessage) *chuanv1.SignalMessage {
+	return &chuanv1.SignalMessage{
+		Type:     msg.Type,
+		From:     msg.From,
+		To:       msg.To,
+		Payload:  string(msg.Payload),
+		Envelope: msg.Envelope,
+		Error:    msg.Error,
+		Code:     msg.Code,
+	}
+}
+
+// fromProto 将 protobuf 消息转换为信令消息，无效的 JSON 负载按字符串处理
+func fromProto(msg *chuanv1.SignalMessage) *services.WebRTCMessage {
+	var payload json.RawMessage
+	if msg.Payload != "" {
+		if json.Valid([]byte(msg.Payload)) {
+			payload = json.RawMessage(msg.Payload)
+		} else {
+			payload, _ = json.Marshal(msg.Payload)
+		}
+	}
+	return &services.WebRTCMessage{
+		Type:     msg.Type,
+		To:       msg.To,
+		Payload:  payload,
+		Envelope: msg.Envelope,
+	}
+}
+
+// stateTimes 转换各状态的进入时间
+func stateTimes(times map[string]time.Time) map[string]*timestamppb.Timestamp {
+	out := make(map[string]*timestamppb.Timestamp, len(times))
+	for state, t := range times {
+		out[state] = timestamppb.New(t)
+	}
+	return out
+}
+
+// roleOf 将 protobuf 角色转换为服务层角色，未指定时返回空字符串
+func roleOf(role chuanv1.Role) string {
+	switch role {
+	case chuanv1.Role_ROLE_SENDER:
+		return services.RoleSender
+	case chuanv1.Role_ROLE_RECEIVER:
+		return services.RoleReceiver
+	}
+	return ""
+}
+
+// requestActor 认证后的调用方信息
+func requestActor(r *http.Request) services.Actor {
+	return services.Actor{
+		Identity:  auth.FromContext(r.Context()),
+		IP:        clientip.From(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// metadataLang 认证前根据 accept-language metadata 选择错误消息的语言
+func metadataLang(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("accept-language"); len(values) > 0 {
+		return apierr.ParseLang(values[0])
+	}
+	return apierr.DefaultLang
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"chuan/internal/apierr"
+	"chuan/internal/services"
+)
+
+// maintenanceRequest 切换维护模式的请求
+type maintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+// noticeRequest 发送公告的请求
+type noticeRequest struct {
+	Message string `json:"message"`
+	Level   string `json:"level"`
+}
+
+// MaintenanceHandler 查询维护模式：GET /api/v1/admin/maintenance
+func (h *Handler) MaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"maintenance": h.webrtcService.Maintenance(),
+	})
+}
+
+// SetMaintenanceHandler 进入或退出维护模式：PUT /api/v1/admin/maintenance
+//
+// 维护期间不再创建房间，已有房间继续工作。
+func (h *Handler) SetMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req maintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apierr.New(apierr.CodeBadRequest))
+		return
+	}
+
+	state := h.webrtcService.SetMaintenance(req.Enabled, strings.TrimSpace(req.Message), requestActor(r))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"maintenance": state,
+	})
+}
+
+// NoticeHandler 向所有在线客户端发送公告：POST /api/v1/admin/notices
+func (h *Handler) NoticeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req noticeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, apierr.New(apierr.CodeBadRequest))
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" {
+		writeError(w, r, apierr.New(apierr.CodeInvalidParameter, "message"))
+		return
+	}
+	switch req.Level {
+	case "":
+		req.Level = services.NoticeInfo
+	case services.NoticeInfo, services.NoticeWarning:
+	default:
+		writeError(w, r, apierr.New(apierr.CodeInvalidParameter, "level"))
+		return
+	}
+
+	delivered := h.webrtcService.Broadcast(services.Notice{Message: req.Message, Level: req.Level}, requestActor(r))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"delivered": delivered,
+	})
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chuan/internal/apierr"
+	"chuan/internal/services"
+)
+
+// writeError 以统一的错误格式和对应的HTTP状态码返回错误
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	apierr.Write(w, r, services.ToAPIError(err))
+}
+
+// methodNotAllowed 返回 405
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, apierr.New(apierr.CodeMethodNotAllowed))
+}
+
+// APIMethodNotAllowedHandler API 路径存在但方法不支持时返回 405
+func (h *Handler) APIMethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	methodNotAllowed(w, r)
+}
+
+// APINotFoundHandler 未知的 API 路径返回 404，避免落到前端页面
+func (h *Handler) APINotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, apierr.New(apierr.CodeNotFound))
+}
@@ -2,23 +2,84 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"chuan/internal/apierr"
+	"chuan/internal/quota"
 	"chuan/internal/services"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
 	webrtcService *services.WebRTCService
+	textService   *services.TextService
+	storeService  *services.StoreService // 未启用暂存转发模式时为 nil
+	tusService    *services.TusService   // 未启用断点续传上传时为 nil
+	relayService  *services.RelayService // 未启用流式中继时为 nil
+	quota         *quota.Limiter
 }
 
-func NewHandler() *Handler {
+func NewHandler(webrtcService *services.WebRTCService, textService *services.TextService, storeService *services.StoreService, tusService *services.TusService, relayService *services.RelayService, limiter *quota.Limiter) *Handler {
 	return &Handler{
-		webrtcService: services.NewWebRTCService(),
+		webrtcService: webrtcService,
+		textService:   textService,
+		storeService:  storeService,
+		tusService:    tusService,
+		relayService:  relayService,
+		quota:         limiter,
+	}
+}
+
+// createRoomRequest 创建房间请求体
+type createRoomRequest struct {
+	TTL  int64  `json:"ttl"`  // 有效期（秒），可选
+	Type string `json:"type"` // 房间类型，可选
+}
+
+// extendRoomRequest 延长房间有效期请求体
+type extendRoomRequest struct {
+	Code       string `json:"code"`
+	OwnerToken string `json:"owner_token"`
+	TTL        int64  `json:"ttl"` // 延长的时长（秒），可选
+}
+
+// decodeJSON 解码 JSON 请求体，大小上限与 /api/v1 校验器相同，超过时返回 BODY_TOO_LARGE
+//
+// 请求体为空时返回 io.EOF，由调用方决定是否允许。
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	limit := h.textService.MaxRequestSize()
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return apierr.New(apierr.CodeBodyTooLarge).WithDetail("limit", limit)
+		}
+		if errors.Is(err, io.EOF) {
+			return err
+		}
+		return apierr.New(apierr.CodeBadRequest)
+	}
+	return nil
+}
+
+// requestCode 读取取件码，支持 /{code} 路径参数（/api/v1）和 ?code= 查询参数（旧路由）
+func requestCode(r *http.Request) string {
+	if code := strings.TrimSpace(chi.URLParam(r, "code")); code != "" {
+		return code
 	}
+	return strings.TrimSpace(r.URL.Query().Get("code"))
 }
 
 // HandleWebRTCWebSocket 处理WebRTC信令WebSocket连接
+//
+// 发送方加入不存在的房间时自动创建房间，房间服务在创建时占用房间配额。
 func (h *Handler) HandleWebRTCWebSocket(w http.ResponseWriter, r *http.Request) {
 	h.webrtcService.HandleWebSocket(w, r)
 }
@@ -29,52 +90,123 @@ func (h *Handler) CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodPost {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"message": "方法不允许",
-		})
+		methodNotAllowed(w, r)
 		return
 	}
 
-	// 创建新房间
-	code := h.webrtcService.CreateNewRoom()
-	log.Printf("创建房间成功: %s", code)
+	// 解析可选的有效期参数，请求体为空时使用默认值
+	var req createRoomRequest
+	if r.Body != nil {
+		if err := h.decodeJSON(w, r, &req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, r, err)
+			return
+		}
+	}
+	if ttl := r.URL.Query().Get("ttl"); ttl != "" {
+		seconds, err := strconv.ParseInt(ttl, 10, 64)
+		if err != nil {
+			writeError(w, r, apierr.New(apierr.CodeInvalidParameter, "ttl"))
+			return
+		}
+		req.TTL = seconds
+	}
+
+	roomType, err := services.ParseRoomType("type", req.Type)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// 创建新房间，房间服务同时检查创建者的配额
+	room, err := h.webrtcService.CreateNewRoom(services.CreateRoomOptions{
+		TTL:     time.Duration(req.TTL) * time.Second,
+		Type:    roomType,
+		Creator: requestActor(r),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	log.Printf("创建房间成功: %s", room.Code)
 
 	// 构建响应
 	response := map[string]interface{}{
-		"success": true,
-		"code":    code,
-		"message": "房间创建成功",
+		"success":     true,
+		"code":        room.Code,
+		"owner_token": room.OwnerToken,
+		"type":        room.Type,
+		"expires_at":  room.ExpiresAt,
+		"message":     "房间创建成功",
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
+// ExtendRoomHandler 延长房间有效期API，需要房主令牌
+func (h *Handler) ExtendRoomHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	var req extendRoomRequest
+	if err := h.decodeJSON(w, r, &req); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = apierr.New(apierr.CodeBadRequest)
+		}
+		writeError(w, r, err)
+		return
+	}
+	if code := requestCode(r); code != "" {
+		req.Code = code
+	}
+	if token := r.Header.Get("X-Owner-Token"); token != "" {
+		req.OwnerToken = token
+	}
+	if req.Code == "" {
+		writeError(w, r, apierr.New(apierr.CodeMissingCode))
+		return
+	}
+
+	expiresAt, err := h.webrtcService.ExtendRoom(req.Code, req.OwnerToken, time.Duration(req.TTL)*time.Second, requestActor(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"code":       req.Code,
+		"expires_at": expiresAt,
+		"message":    "房间有效期已延长",
+	})
+}
+
 // WebRTCRoomStatusHandler WebRTC房间状态API
 func (h *Handler) WebRTCRoomStatusHandler(w http.ResponseWriter, r *http.Request) {
 	// 设置响应为JSON格式
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodGet {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"message": "方法不允许",
-		})
+		methodNotAllowed(w, r)
 		return
 	}
 
-	// 从查询参数获取房间代码
-	code := r.URL.Query().Get("code")
+	// 从路径或查询参数获取房间代码
+	code := requestCode(r)
 	if code == "" {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"message": "缺少房间代码",
-		})
+		writeError(w, r, apierr.New(apierr.CodeMissingCode))
 		return
 	}
 
 	// 获取房间状态
-	status := h.webrtcService.GetRoomStatus(code)
+	status, err := h.webrtcService.GetRoomStatus(code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	json.NewEncoder(w).Encode(status)
 }
@@ -84,24 +216,33 @@ func (h *Handler) GetRoomStatusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodGet {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"message": "方法不允许",
-		})
+		methodNotAllowed(w, r)
 		return
 	}
 
 	// 获取房间码
 	code := r.URL.Query().Get("code")
 	if code == "" {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"message": "房间码不能为空",
-		})
+		writeError(w, r, apierr.New(apierr.CodeMissingCode))
 		return
 	}
 
 	// 获取房间状态
-	status := h.webrtcService.GetRoomStatus(code)
+	status, err := h.webrtcService.GetRoomStatus(code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 	json.NewEncoder(w).Encode(status)
 }
+
+// ICEServersHandler 获取浏览器建立 P2P 连接使用的 STUN/TURN 服务器：GET /api/ice-servers
+func (h *Handler) ICEServersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"ice_servers": h.webrtcService.ICEServers(),
+	})
+}
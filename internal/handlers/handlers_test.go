@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chuan/internal/config"
+	"chuan/internal/services"
+	"chuan/internal/storage"
+)
+
+// 旧路由的请求体大小上限与 /api/v1 相同
+func TestLegacyRoomHandlersLimitBody(t *testing.T) {
+	cfg := config.Default()
+	textService := services.NewTextService(cfg.Text, storage.NewMemoryTextStore())
+	h := NewHandler(services.NewWebRTCService(cfg), textService, nil, nil, nil, nil)
+	huge := `{"ttl":60,"pad":"` + strings.Repeat("x", int(textService.MaxRequestSize())) + `"}`
+
+	for _, c := range []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		body    string
+		status  int
+	}{
+		{"创建房间，请求体过大", h.CreateRoomHandler, "/api/create-room", huge, http.StatusRequestEntityTooLarge},
+		{"延长房间，请求体过大", h.ExtendRoomHandler, "/api/extend-room", huge, http.StatusRequestEntityTooLarge},
+		{"创建房间，空请求体", h.CreateRoomHandler, "/api/create-room", "", http.StatusOK},
+		{"延长房间，空请求体", h.ExtendRoomHandler, "/api/extend-room", "", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		c.handler(rec, httptest.NewRequest(http.MethodPost, c.path, strings.NewReader(c.body)))
+		if rec.Code != c.status {
+			t.Errorf("%s: 状态码 %d，期望 %d: %s", c.name, rec.Code, c.status, rec.Body.String())
+		}
+	}
+}
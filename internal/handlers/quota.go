@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"chuan/internal/auth"
+	"chuan/internal/clientip"
+	"chuan/internal/quota"
+	"chuan/internal/services"
+)
+
+// QuotaHandler 查询当前用户或 IP 的配额用量：GET /api/quota
+func (h *Handler) QuotaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"quota":   h.quota.Report(quotaSubject(r)),
+	})
+}
+
+// quotaSubject 配额统计主体：已认证时为用户标识，否则为客户端 IP
+func quotaSubject(r *http.Request) string {
+	return quota.Subject(auth.FromContext(r.Context()), clientIP(r))
+}
+
+// clientIP 客户端 IP
+func clientIP(r *http.Request) string {
+	return clientip.From(r)
+}
+
+// requestActor 请求对应的操作者信息，用于记录房间创建者和审计
+func requestActor(r *http.Request) services.Actor {
+	return services.Actor{
+		Identity:  auth.FromContext(r.Context()),
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
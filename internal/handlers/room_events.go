@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"chuan/internal/apierr"
+)
+
+// sseHeartbeatInterval SSE 心跳间隔，防止代理因空闲断开连接
+const sseHeartbeatInterval = 15 * time.Second
+
+// RoomEventsHandler 以 Server-Sent Events 推送房间状态变化
+//
+// 每次状态变化发送一条 status 事件，数据与房间状态API一致；
+// 房间关闭或过期时发送最后一条 closed 为 true 的状态后结束。
+func (h *Handler) RoomEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	code := requestCode(r)
+	if code == "" {
+		writeError(w, r, apierr.New(apierr.CodeMissingCode))
+		return
+	}
+
+	updates, cancel, err := h.webrtcService.SubscribeRoom(code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer cancel()
+
+	// 长连接不受服务器写超时限制
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("SSE取消写超时失败: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case status, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(status)
+			if err != nil {
+				log.Printf("序列化房间状态失败: %v", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if status.Closed {
+				rc.Flush()
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/quota"
+	"chuan/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxStoreMetaLength 客户端加密文件信息的最大长度
+const maxStoreMetaLength = 4096
+
+// StoreUploadHandler 上传客户端加密后的文件，返回取件码和房主令牌
+//
+// 请求体为文件密文，可选参数：?ttl=秒数&max_downloads=次数，
+// 请求头 X-File-Meta 携带客户端加密后的文件信息。
+func (h *Handler) StoreUploadHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	meta := r.Header.Get("X-File-Meta")
+	if len(meta) > maxStoreMetaLength {
+		writeError(w, r, apierr.New(apierr.CodeInvalidParameter, "X-File-Meta"))
+		return
+	}
+
+	query := r.URL.Query()
+	ttl, err1 := parseOptionalInt(query.Get("ttl"))
+	maxDownloads, err2 := parseOptionalInt(query.Get("max_downloads"))
+	if err1 != nil || err2 != nil {
+		writeError(w, r, apierr.New(apierr.CodeBadRequest))
+		return
+	}
+
+	subject := quotaSubject(r)
+	if err := h.quota.CheckBytes(subject, quota.KindStored, r.ContentLength); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// 大文件上传不受服务器读写超时限制
+	disableDeadlines(w)
+
+	upload, err := h.storeService.Upload(h.quota.Reader(subject, quota.KindStored, r.Body), services.StoreUploadOptions{
+		Size:         r.ContentLength,
+		Meta:         meta,
+		TTL:          time.Duration(ttl) * time.Second,
+		MaxDownloads: int(maxDownloads),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":       true,
+		"code":          upload.File.Code,
+		"owner_token":   upload.OwnerToken,
+		"size":          upload.File.Size,
+		"expires_at":    upload.File.ExpiresAt,
+		"max_downloads": upload.File.MaxDownloads,
+		"message":       "文件上传成功",
+	})
+}
+
+// StoreInfoHandler 获取暂存文件信息
+func (h *Handler) StoreInfoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	file, err := h.storeService.Info(chi.URLParam(r, "code"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":             true,
+		"code":                file.Code,
+		"meta":                file.Meta,
+		"size":                file.Size,
+		"created_at":          file.CreatedAt,
+		"expires_at":          file.ExpiresAt,
+		"downloads":           file.Downloads,
+		"max_downloads":       file.MaxDownloads,
+		"remaining_downloads": file.MaxDownloads - file.Downloads,
+	})
+}
+
+// StoreDownloadHandler 下载暂存文件，支持 Range 断点续传和分段下载
+//
+// 每次下载计入一次下载次数，响应头 X-Download-Token 返回下载令牌；之后的 Range 请求在
+// X-Download-Token 请求头中带上该令牌时属于同一次下载，不重复计数。
+func (h *Handler) StoreDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	download, err := h.storeService.Open(chi.URLParam(r, "code"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer download.Close()
+
+	disableDeadlines(w)
+
+	meta := download.Meta
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.bin"`, meta.Code))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-File-Meta", meta.Meta)
+	http.ServeContent(&downloadWriter{ResponseWriter: w, r: r, download: download}, r, "", meta.CreatedAt, download)
+}
+
+// downloadWriter 在 http.ServeContent 确定发送文件内容（200 或 206）时才开始下载并计入下载次数
+//
+// 是否发送内容由 ServeContent 根据 Range、If-Range 等条件请求头决定，这里只看它实际写出的状态码，
+// HEAD 请求以及 304、412、416 等响应不算作下载。
+type downloadWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	download *services.StoreDownload
+
+	wroteHeader bool
+	err         error // 无法开始下载时的错误，之后的写入全部丢弃
+}
+
+func (dw *downloadWriter) WriteHeader(status int) {
+	if dw.wroteHeader {
+		return
+	}
+	dw.wroteHeader = true
+
+	if dw.r.Method == http.MethodGet && (status == http.StatusOK || status == http.StatusPartialContent) {
+		token, err := dw.download.Begin(dw.r.Header.Get("X-Download-Token"))
+		if err != nil {
+			dw.err = err
+			header := dw.Header()
+			for _, key := range []string{"Content-Length", "Content-Range", "Content-Disposition", "Accept-Ranges", "Last-Modified", "X-File-Meta"} {
+				header.Del(key)
+			}
+			writeError(dw.ResponseWriter, dw.r, err)
+			return
+		}
+		dw.Header().Set("X-Download-Token", token)
+	}
+	dw.ResponseWriter.WriteHeader(status)
+}
+
+func (dw *downloadWriter) Write(p []byte) (int, error) {
+	if !dw.wroteHeader {
+		dw.WriteHeader(http.StatusOK)
+	}
+	if dw.err != nil {
+		return 0, dw.err
+	}
+	return dw.ResponseWriter.Write(p)
+}
+
+// Unwrap 供 http.ResponseController 访问底层连接
+func (dw *downloadWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
+
+// StoreDeleteHandler 使用房主令牌删除暂存文件
+func (h *Handler) StoreDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := h.storeService.Delete(chi.URLParam(r, "code"), r.Header.Get("X-Owner-Token"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "文件已删除",
+	})
+}
+
+// parseOptionalInt 解析可选的整数参数，空字符串返回0
+func parseOptionalInt(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// disableDeadlines 取消服务器为当前请求设置的读写超时，用于大文件传输和长连接
+func disableDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("取消读超时失败: %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("取消写超时失败: %v", err)
+	}
+}
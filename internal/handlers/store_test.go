@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"chuan/internal/config"
+	"chuan/internal/services"
+	"chuan/internal/storage"
+)
+
+const storeTestContent = "0123456789"
+
+// newStoreRouter 上传一个只能下载一次的暂存文件，返回下载路由和取件码
+func newStoreRouter(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	fileStore, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := services.NewStoreService(config.Default().Store, fileStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := s.Upload(strings.NewReader(storeTestContent), services.StoreUploadOptions{Size: int64(len(storeTestContent)), MaxDownloads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(nil, nil, s, nil, nil, nil)
+	r := chi.NewRouter()
+	r.Get("/api/store/{code}", h.StoreDownloadHandler)
+	r.Head("/api/store/{code}", h.StoreDownloadHandler)
+	return r, upload.File.Code
+}
+
+func storeRequest(router http.Handler, method, code string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/store/"+code, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// 只有实际发送文件内容的响应才计入下载次数，是否发送由 http.ServeContent 决定
+func TestStoreDownloadCountsWhatIsSent(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+
+	for _, c := range []struct {
+		name   string
+		method string
+		header map[string]string
+		status int
+		body   string
+	}{
+		// If-Range 不匹配时 Range 被忽略，发送整个文件，必须计数
+		{"If-Range 不匹配", http.MethodGet, map[string]string{"Range": "bytes=999999999-", "If-Range": `"a"`}, http.StatusOK, storeTestContent},
+		{"If-Range 不匹配且 Range 无效", http.MethodGet, map[string]string{"Range": "bytes=x", "If-Range": `"a"`}, http.StatusOK, storeTestContent},
+		{"完整下载", http.MethodGet, nil, http.StatusOK, storeTestContent},
+		{"Range 下载", http.MethodGet, map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234"},
+	} {
+		router, code := newStoreRouter(t)
+		rec := storeRequest(router, c.method, code, c.header)
+		if rec.Code != c.status || rec.Body.String() != c.body {
+			t.Errorf("%s: 状态码 %d、内容 %q，期望 %d、%q", c.name, rec.Code, rec.Body.String(), c.status, c.body)
+			continue
+		}
+		if rec.Header().Get("X-Download-Token") == "" {
+			t.Errorf("%s: 发送了文件内容但没有返回下载令牌", c.name)
+		}
+		if again := storeRequest(router, http.MethodGet, code, nil); again.Code != http.StatusNotFound {
+			t.Errorf("%s: 下载次数用完后再次下载返回 %d，期望 404", c.name, again.Code)
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		method string
+		header map[string]string
+		status int
+	}{
+		{"HEAD", http.MethodHead, nil, http.StatusOK},
+		{"Range 无法满足", http.MethodGet, map[string]string{"Range": "bytes=999999999-"}, http.StatusRequestedRangeNotSatisfiable},
+		{"未修改", http.MethodGet, map[string]string{"If-Modified-Since": future}, http.StatusNotModified},
+	} {
+		router, code := newStoreRouter(t)
+		rec := storeRequest(router, c.method, code, c.header)
+		if rec.Code != c.status {
+			t.Errorf("%s: 状态码 %d，期望 %d", c.name, rec.Code, c.status)
+			continue
+		}
+		if rec.Header().Get("X-Download-Token") != "" {
+			t.Errorf("%s: 没有发送文件内容却开始了下载", c.name)
+		}
+		if again := storeRequest(router, http.MethodGet, code, nil); again.Code != http.StatusOK || again.Body.String() != storeTestContent {
+			t.Errorf("%s: 之后的下载返回 %d，不应计入下载次数", c.name, again.Code)
+		}
+	}
+}
+
+// 下载次数已用完时，即使其他请求仍打开着文件，也返回错误而不是文件内容
+func TestStoreDownloadExhaustedWhileOpen(t *testing.T) {
+	router, code := newStoreRouter(t)
+	if rec := storeRequest(router, http.MethodGet, code, map[string]string{"Range": "bytes=0-0"}); rec.Code != http.StatusPartialContent {
+		t.Fatalf("第一次下载返回 %d", rec.Code)
+	}
+	rec := storeRequest(router, http.MethodGet, code, map[string]string{"Range": "bytes=1-", "If-Range": `"a"`})
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("新的下载返回 %d，期望 404", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), storeTestContent[1:]) || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("下载次数用完后仍发送了文件内容: %q", rec.Body.String())
+	}
+}
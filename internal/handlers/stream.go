@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"chuan/internal/quota"
+	"chuan/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// StreamUploadHandler HTTP 流式中继的发送端：PUT /api/stream/{code}
+//
+// 请求体直接转发给同时 GET 该地址的接收方，服务器不保存文件。
+// 文件名可以通过 ?filename=、X-File-Name 或 Content-Disposition 提供，例如：
+//
+//	curl -T report.pdf "http://host/api/stream/123456?filename=report.pdf"
+func (h *Handler) StreamUploadHandler(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	meta := services.RelayMeta{
+		Size:        r.ContentLength,
+		Filename:    streamFilename(r),
+		ContentType: r.Header.Get("Content-Type"),
+	}
+
+	subject := quotaSubject(r)
+	if err := h.quota.CheckBytes(subject, quota.KindRelay, r.ContentLength); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	disableDeadlines(w)
+
+	written, err := h.relayService.Send(r.Context(), code, meta, h.quota.Reader(subject, quota.KindRelay, r.Body))
+	if err != nil {
+		log.Printf("中继发送失败: 房间=%s, 已发送 %d 字节: %v", code, written, err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"bytes":   written,
+		"message": "传输完成",
+	})
+}
+
+// StreamDownloadHandler HTTP 流式中继的接收端：GET /api/stream/{code}
+func (h *Handler) StreamDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+
+	disableDeadlines(w)
+
+	stream, release, err := h.relayService.Receive(r.Context(), code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer release()
+
+	meta := stream.Meta()
+	contentType := meta.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	filename := meta.Filename
+	if filename == "" {
+		filename = code
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", encodeRFC5987(filename)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	if meta.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(meta.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	flush := func() { rc.Flush() }
+	written, err := h.relayService.Copy(r.Context(), stream, w, flush)
+	if err != nil {
+		log.Printf("中继接收中断: 房间=%s, 已接收 %d 字节: %v", code, written, err)
+		// 中断连接而不是正常结束响应，让客户端知道文件不完整
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// streamFilename 从请求中读取发送方提供的文件名，只保留最后一段路径
+func streamFilename(r *http.Request) string {
+	name := r.URL.Query().Get("filename")
+	if name == "" {
+		name = r.Header.Get("X-File-Name")
+	}
+	if name == "" {
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+			name = params["filename"]
+		}
+	}
+	if name == "" {
+		return ""
+	}
+	name = path.Base(path.Clean("/" + name))
+	if name == "/" || name == "." {
+		return ""
+	}
+	return name
+}
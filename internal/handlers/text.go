@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/services"
+)
+
+// createTextRequest 创建文本请求体
+type createTextRequest struct {
+	Text          string `json:"text"`
+	Content       string `json:"content"`         // text 的别名
+	TTL           int64  `json:"ttl"`             // 有效期（秒），可选
+	MaxViews      int    `json:"max_views"`       // 最大查看次数，0 表示不限
+	BurnAfterRead bool   `json:"burn_after_read"` // 阅后即焚，等同于 max_views=1
+}
+
+// CreateTextRoomHandler 创建文本片段API
+func (h *Handler) CreateTextRoomHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.textService.MaxRequestSize())
+
+	var req createTextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, r, services.ErrTextTooLarge)
+			return
+		}
+		writeError(w, r, apierr.New(apierr.CodeBadRequest))
+		return
+	}
+
+	content := req.Text
+	if content == "" {
+		content = req.Content
+	}
+	maxViews := req.MaxViews
+	if req.BurnAfterRead {
+		maxViews = 1
+	}
+
+	snippet, err := h.textService.Create(content, services.CreateTextOptions{
+		TTL:      time.Duration(req.TTL) * time.Second,
+		MaxViews: maxViews,
+		Creator:  requestActor(r),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"code":       snippet.Code,
+		"expires_at": snippet.ExpiresAt,
+		"max_views":  snippet.MaxViews,
+		"message":    "文本创建成功",
+	})
+}
+
+// GetTextContentHandler 获取文本内容API，支持 ?code= 和 /{code} 两种形式
+func (h *Handler) GetTextContentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	code := requestCode(r)
+	if code == "" {
+		writeError(w, r, apierr.New(apierr.CodeMissingCode))
+		return
+	}
+
+	snippet, err := h.textService.View(code, clientIP(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	remaining := -1
+	if snippet.MaxViews > 0 {
+		remaining = snippet.MaxViews - snippet.Views
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":         true,
+		"code":            snippet.Code,
+		"text":            snippet.Content,
+		"created_at":      snippet.CreatedAt,
+		"expires_at":      snippet.ExpiresAt,
+		"views":           snippet.Views,
+		"max_views":       snippet.MaxViews,
+		"remaining_views": remaining,
+		"deleted":         remaining == 0,
+	})
+}
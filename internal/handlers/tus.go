@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chuan/internal/basepath"
+	"chuan/internal/quota"
+	"chuan/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// tus 1.0 协议常量
+const (
+	tusVersion            = "1.0.0"
+	tusExtensions         = "creation,creation-with-upload,expiration,checksum,termination"
+	tusChecksumAlgorithms = "md5,sha1,sha256"
+	tusContentType        = "application/offset+octet-stream"
+
+	// statusChecksumMismatch tus checksum 扩展定义的状态码
+	statusChecksumMismatch = 460
+)
+
+// tusChecksumHashes 支持的校验和算法
+var tusChecksumHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// TusRoutes 返回 tus 1.0 断点续传上传路由，挂载在 /api/tus/{code}
+//
+// 上传与房间绑定，过期时间与房间一致；上传完成后可以通过 GET 下载。
+func (h *Handler) TusRoutes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(h.tusResumable)
+
+	r.Options("/", h.tusOptions)
+	r.Post("/", h.tusCreate)
+	r.Options("/{id}", h.tusOptions)
+	r.Head("/{id}", h.tusHead)
+	r.Patch("/{id}", h.tusPatch)
+	r.Delete("/{id}", h.tusTerminate)
+	r.Get("/{id}", h.tusDownload)
+
+	return r
+}
+
+// tusResumable 为所有响应设置 Tus-Resumable，并拒绝不支持的协议版本
+func (h *Handler) tusResumable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+
+		// OPTIONS 和普通下载不要求协议头
+		if r.Method != http.MethodOptions && r.Method != http.MethodGet {
+			if v := r.Header.Get("Tus-Resumable"); v != tusVersion {
+				w.Header().Set("Tus-Version", tusVersion)
+				http.Error(w, "不支持的 tus 协议版本", http.StatusPreconditionFailed)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tusOptions 返回服务器支持的协议版本和扩展
+func (h *Handler) tusOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.tusService.MaxSize(), 10))
+	w.Header().Set("Tus-Checksum-Algorithm", tusChecksumAlgorithms)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tusCreate 创建上传（creation 扩展），请求体带数据时同时写入（creation-with-upload 扩展）
+func (h *Handler) tusCreate(w http.ResponseWriter, r *http.Request) {
+	room := chi.URLParam(r, "code")
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "缺少或无效的 Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > h.tusService.MaxSize() {
+		http.Error(w, services.ErrTusTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metadata := r.Header.Get("Upload-Metadata")
+	if !validTusMetadata(metadata) {
+		http.Error(w, "无效的 Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := h.tusService.Create(room, length, metadata)
+	if err != nil {
+		h.tusError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", basepath.Join(r, strings.TrimSuffix(r.URL.Path, "/")+"/"+upload.ID))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	// 超出流量配额时只创建上传、不写入数据，客户端随后的 PATCH 会收到 429
+	subject := quotaSubject(r)
+	if r.Header.Get("Content-Type") == tusContentType && r.ContentLength != 0 &&
+		h.quota.CheckBytes(subject, quota.KindStored, r.ContentLength) == nil {
+		checksum, ok := h.tusChecksum(w, r)
+		if !ok {
+			return
+		}
+		disableDeadlines(w)
+		upload, err = h.tusService.Append(room, upload.ID, 0, h.quota.Reader(subject, quota.KindStored, r.Body), checksum)
+		if err != nil && !errors.Is(err, services.ErrTusChecksumMismatch) {
+			log.Printf("tus 创建时写入数据失败: %v", err)
+		}
+		if upload != nil {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// tusHead 查询上传偏移量
+func (h *Handler) tusHead(w http.ResponseWriter, r *http.Request) {
+	upload, err := h.tusService.Get(chi.URLParam(r, "code"), chi.URLParam(r, "id"))
+	if err != nil {
+		h.tusError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// tusPatch 从指定偏移量追加数据
+func (h *Handler) tusPatch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != tusContentType {
+		http.Error(w, "Content-Type 必须为 "+tusContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "缺少或无效的 Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	checksum, ok := h.tusChecksum(w, r)
+	if !ok {
+		return
+	}
+
+	subject := quotaSubject(r)
+	if err := h.quota.CheckBytes(subject, quota.KindStored, r.ContentLength); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	disableDeadlines(w)
+
+	upload, err := h.tusService.Append(chi.URLParam(r, "code"), chi.URLParam(r, "id"), offset, h.quota.Reader(subject, quota.KindStored, r.Body), checksum)
+	if err != nil {
+		// 超出配额：已写入的数据保留，配额重置后可以继续上传
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			writeError(w, r, err)
+			return
+		}
+		if upload == nil || errors.Is(err, services.ErrTusOffsetMismatch) || errors.Is(err, services.ErrTusChecksumMismatch) {
+			h.tusError(w, err)
+			return
+		}
+		// 连接中断等读取错误：已写入的数据保留，客户端可以通过 HEAD 获取新偏移量
+		log.Printf("tus 写入数据中断: %v", err)
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tusTerminate 删除上传（termination 扩展）
+func (h *Handler) tusTerminate(w http.ResponseWriter, r *http.Request) {
+	if err := h.tusService.Terminate(chi.URLParam(r, "code"), chi.URLParam(r, "id")); err != nil {
+		h.tusError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tusDownload 下载已完成的上传，支持 Range
+func (h *Handler) tusDownload(w http.ResponseWriter, r *http.Request) {
+	file, upload, err := h.tusService.Open(chi.URLParam(r, "code"), chi.URLParam(r, "id"))
+	if err != nil {
+		h.tusError(w, err)
+		return
+	}
+	defer file.Close()
+
+	disableDeadlines(w)
+
+	filename := tusMetadataValue(upload.Metadata, "filename")
+	if filename == "" {
+		filename = upload.ID
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", encodeRFC5987(filename)))
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, "", upload.CreatedAt, file)
+}
+
+// tusChecksum 解析 Upload-Checksum 头（checksum 扩展），格式为 "<算法> <Base64摘要>"
+func (h *Handler) tusChecksum(w http.ResponseWriter, r *http.Request) (*services.TusChecksum, bool) {
+	header := r.Header.Get("Upload-Checksum")
+	if header == "" {
+		return nil, true
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	newHash, supported := tusChecksumHashes[parts[0]]
+	if !supported || len(parts) != 2 {
+		http.Error(w, "不支持的校验和算法", http.StatusBadRequest)
+		return nil, false
+	}
+	expected, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		http.Error(w, "无效的校验和", http.StatusBadRequest)
+		return nil, false
+	}
+	return &services.TusChecksum{Hash: newHash(), Expected: expected}, true
+}
+
+// tusError 将服务错误转换为 tus 协议规定的状态码
+func (h *Handler) tusError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrTusNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrTusOffsetMismatch), errors.Is(err, services.ErrTusBusy), errors.Is(err, services.ErrTusIncomplete):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrTusChecksumMismatch):
+		http.Error(w, err.Error(), statusChecksumMismatch)
+	case errors.Is(err, services.ErrTusTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		log.Printf("tus 请求处理失败: %v", err)
+		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
+	}
+}
+
+// validTusMetadata 校验 Upload-Metadata：逗号分隔的 "键 Base64值" 对，值可以省略
+func validTusMetadata(metadata string) bool {
+	if metadata == "" {
+		return true
+	}
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(metadata, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), " ")
+		if len(parts) > 2 || parts[0] == "" || seen[parts[0]] {
+			return false
+		}
+		seen[parts[0]] = true
+		if len(parts) == 2 {
+			if _, err := base64.StdEncoding.DecodeString(parts[1]); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// tusMetadataValue 读取 Upload-Metadata 中指定键的值
+func tusMetadataValue(metadata string, key string) string {
+	for _, pair := range strings.Split(metadata, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), " ")
+		if parts[0] == key && len(parts) == 2 {
+			value, err := base64.StdEncoding.DecodeString(parts[1])
+			if err == nil {
+				return string(value)
+			}
+		}
+	}
+	return ""
+}
+
+// encodeRFC5987 按 RFC 5987 编码文件名，用于 Content-Disposition 的 filename* 参数
+func encodeRFC5987(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -40,17 +40,44 @@ type ClientInfo struct {
 	UserAgent  string          `json:"user_agent"` // 用户代理
 }
 
+// 房间类型，房间只能用于声明的功能；空类型表示不限，由第一个声明类型的客户端确定
+const (
+	RoomTypeFile    = "file"    // 文件传输
+	RoomTypeText    = "text"    // 文字和图片消息
+	RoomTypeDesktop = "desktop" // 桌面共享
+)
+
+// RoomTypes 所有房间类型
+var RoomTypes = []string{RoomTypeFile, RoomTypeText, RoomTypeDesktop}
+
+// ValidRoomType 是否为已知的房间类型
+func ValidRoomType(t string) bool {
+	for _, known := range RoomTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
 // RoomStatus 房间状态信息
 type RoomStatus struct {
-	Code           string    `json:"code"`
-	SenderOnline   bool      `json:"sender_online"`
-	ReceiverOnline bool      `json:"receiver_online"`
-	CreatedAt      time.Time `json:"created_at"`
+	Code           string               `json:"code"`
+	Type           string               `json:"type,omitempty"` // 房间类型，未声明时为空
+	State          string               `json:"state"`          // 生命周期状态：created、waiting、paired、transferring、completed、closed、expired
+	StateTimes     map[string]time.Time `json:"state_times"`    // 各状态最近一次进入的时间
+	SenderOnline   bool                 `json:"sender_online"`
+	ReceiverOnline bool                 `json:"receiver_online"`
+	CreatedAt      time.Time            `json:"created_at"`
+	ExpiresAt      time.Time            `json:"expires_at"`
+	Closed         bool                 `json:"closed"`           // 房间已关闭或过期
+	Reason         string               `json:"reason,omitempty"` // 关闭原因：closed 或 expired
 }
 
 // ErrorResponse 错误响应结构
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Code    string `json:"code,omitempty"`
+	Success bool                   `json:"success"`
+	Message string                 `json:"message"`
+	Code    string                 `json:"code,omitempty"`    // 稳定的错误码，例如 ROOM_NOT_FOUND
+	Details map[string]interface{} `json:"details,omitempty"` // 附加信息，例如 retry_after
 }
@@ -0,0 +1,319 @@
+package openapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"chuan/internal/config"
+	"chuan/internal/handlers"
+	"chuan/internal/quota"
+	"chuan/internal/services"
+	"chuan/internal/storage"
+)
+
+// contractChecker 检查经过的每个响应是否符合文档，并记录每个操作返回过的成功状态码
+type contractChecker struct {
+	v *Validator
+
+	mu        sync.Mutex
+	errs      []string
+	succeeded map[*Operation]bool
+}
+
+func (c *contractChecker) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, _ := c.v.match(r.Method, r.URL.Path)
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if op == nil {
+			return
+		}
+
+		err := c.check(r, op, rec)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if err != nil {
+			c.errs = append(c.errs, fmt.Sprintf("%s %s -> %d: %v", r.Method, r.URL.Path, rec.status, err))
+		}
+		if rec.status < 300 {
+			c.succeeded[op] = true
+		}
+	})
+}
+
+// check 状态码必须在文档中声明（错误可以使用 default），Content-Type 和 JSON 响应体必须与文档一致
+func (c *contractChecker) check(r *http.Request, op *Operation, rec *responseRecorder) error {
+	resp := op.Responses[strconv.Itoa(rec.status)]
+	if resp == nil && rec.status >= 400 {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return fmt.Errorf("文档未声明状态码 %d", rec.status)
+	}
+	resp, err := c.v.spec.response(resp)
+	if err != nil {
+		return err
+	}
+	if len(resp.Content) > 0 && r.Method != http.MethodHead {
+		mediaType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		if _, ok := resp.Content[mediaType]; !ok {
+			return fmt.Errorf("Content-Type %q 不在文档中", mediaType)
+		}
+	}
+	return c.v.validateResponse(op, rec)
+}
+
+// contractClient 向测试服务器发送请求
+type contractClient struct {
+	t    *testing.T
+	base string
+}
+
+func (c *contractClient) do(ctx context.Context, method, path, body string, header map[string]string) *http.Response {
+	c.t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp
+}
+
+// expect 发送请求并检查状态码，返回解码后的 JSON 响应体
+func (c *contractClient) expect(status int, method, path, body string, header map[string]string) map[string]interface{} {
+	c.t.Helper()
+	resp := c.do(context.Background(), method, path, body, header)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != status {
+		c.t.Fatalf("%s %s: 状态码 %d，期望 %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	var out map[string]interface{}
+	json.Unmarshal(data, &out)
+	return out
+}
+
+// newContractServer 按 cmd/main.go 注册 /api/v1 路由，使用真实的服务和处理器
+func newContractServer(t *testing.T) (*contractClient, *contractChecker, *services.WebRTCService) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Store.Dir = t.TempDir()
+	cfg.Relay.WaitTimeout = config.Duration{Duration: 5 * time.Second}
+
+	webrtcService := services.NewWebRTCService(cfg)
+	textStore, err := storage.OpenTextStore("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	textService := services.NewTextService(cfg.Text, textStore)
+	fileStore, err := storage.NewFileStore(cfg.Store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeService, err := services.NewStoreService(cfg.Store, fileStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayService := services.NewRelayService(cfg.Relay, webrtcService)
+	limiter := quota.New(cfg.Quota, webrtcService.RoomCount)
+	webrtcService.SetQuota(limiter)
+	textService.SetQuota(limiter)
+	h := handlers.NewHandler(webrtcService, textService, storeService, nil, relayService, limiter)
+
+	v, err := New(textService.MaxRequestSize(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := &contractChecker{v: v, succeeded: make(map[*Operation]bool)}
+
+	r := chi.NewRouter()
+	r.Use(checker.wrap)
+	r.Route(v.BasePath(), func(r chi.Router) {
+		r.Use(v.Middleware)
+		r.NotFound(h.APINotFoundHandler)
+		r.MethodNotAllowed(h.APIMethodNotAllowedHandler)
+
+		r.Get("/openapi.json", v.SpecHandler)
+		r.Post("/rooms", h.CreateRoomHandler)
+		r.Post("/rooms/{code}/extend", h.ExtendRoomHandler)
+		r.Get("/quota", h.QuotaHandler)
+		r.Get("/ice-servers", h.ICEServersHandler)
+		r.Get("/rooms/{code}", h.WebRTCRoomStatusHandler)
+		r.Get("/rooms/{code}/events", h.RoomEventsHandler)
+		r.Post("/texts", h.CreateTextRoomHandler)
+		r.Get("/texts/{code}", h.GetTextContentHandler)
+		r.Post("/files", h.StoreUploadHandler)
+		r.Get("/files/{code}", h.StoreDownloadHandler)
+		r.Head("/files/{code}", h.StoreDownloadHandler)
+		r.Get("/files/{code}/info", h.StoreInfoHandler)
+		r.Delete("/files/{code}", h.StoreDeleteHandler)
+		r.Put("/stream/{code}", h.StreamUploadHandler)
+		r.Get("/stream/{code}", h.StreamDownloadHandler)
+		r.Get("/admin/maintenance", h.MaintenanceHandler)
+		r.Put("/admin/maintenance", h.SetMaintenanceHandler)
+		r.Post("/admin/notices", h.NoticeHandler)
+	})
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return &contractClient{t: t, base: srv.URL + v.BasePath()}, checker, webrtcService
+}
+
+// TestHandlersMatchSpec 逐个调用文档中的操作，检查真实处理器的状态码和响应格式与文档一致
+func TestHandlersMatchSpec(t *testing.T) {
+	c, checker, _ := newContractServer(t)
+
+	c.expect(http.StatusOK, http.MethodGet, "/openapi.json", "", nil)
+
+	// 房间
+	room := c.expect(http.StatusOK, http.MethodPost, "/rooms", `{"ttl":600,"type":"file"}`, nil)
+	code, _ := room["code"].(string)
+	owner := map[string]string{"X-Owner-Token": fmt.Sprint(room["owner_token"])}
+	c.expect(http.StatusOK, http.MethodGet, "/rooms/"+code, "", nil)
+	c.expect(http.StatusNotFound, http.MethodGet, "/rooms/000000", "", nil)
+	c.expect(http.StatusOK, http.MethodPost, "/rooms/"+code+"/extend", `{"ttl":60}`, owner)
+	c.expect(http.StatusForbidden, http.MethodPost, "/rooms/"+code+"/extend", `{}`, map[string]string{"X-Owner-Token": "wrong"})
+	c.expect(http.StatusBadRequest, http.MethodPost, "/rooms", `{"type":"video"}`, nil)
+
+	// 房间事件流：读到第一个事件后断开
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	resp := c.do(ctx, http.MethodGet, "/rooms/"+code+"/events", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("事件流状态码 %d", resp.StatusCode)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "event:") && !strings.HasPrefix(line, "data:") && !strings.HasPrefix(line, ":") {
+		t.Fatalf("事件流首行 %q: %v", line, err)
+	}
+	cancel()
+	resp.Body.Close()
+
+	c.expect(http.StatusOK, http.MethodGet, "/quota", "", nil)
+	c.expect(http.StatusOK, http.MethodGet, "/ice-servers", "", nil)
+
+	// 文本
+	text := c.expect(http.StatusOK, http.MethodPost, "/texts", `{"text":"hello","max_views":2}`, nil)
+	textCode, _ := text["code"].(string)
+	c.expect(http.StatusOK, http.MethodGet, "/texts/"+textCode, "", nil)
+	c.expect(http.StatusNotFound, http.MethodGet, "/texts/000000", "", nil)
+	c.expect(http.StatusBadRequest, http.MethodPost, "/texts", `{"text":""}`, nil)
+	c.expect(http.StatusBadRequest, http.MethodPost, "/texts", `{"text":"x","max_views":-1}`, nil)
+
+	// 暂存文件
+	content := "ciphertext-bytes"
+	upload := c.expect(http.StatusOK, http.MethodPost, "/files?max_downloads=5", content, map[string]string{
+		"Content-Type": "application/octet-stream",
+		"X-File-Meta":  "encrypted-meta",
+	})
+	fileCode, _ := upload["code"].(string)
+	fileOwner := map[string]string{"X-Owner-Token": fmt.Sprint(upload["owner_token"])}
+	c.expect(http.StatusOK, http.MethodGet, "/files/"+fileCode+"/info", "", nil)
+	c.expect(http.StatusOK, http.MethodHead, "/files/"+fileCode, "", nil)
+	c.expect(http.StatusPartialContent, http.MethodGet, "/files/"+fileCode, "", map[string]string{"Range": "bytes=2-5"})
+	c.expect(http.StatusOK, http.MethodGet, "/files/"+fileCode, "", nil)
+	c.expect(http.StatusForbidden, http.MethodDelete, "/files/"+fileCode, "", map[string]string{"X-Owner-Token": "wrong"})
+	c.expect(http.StatusOK, http.MethodDelete, "/files/"+fileCode, "", fileOwner)
+	c.expect(http.StatusNotFound, http.MethodGet, "/files/"+fileCode+"/info", "", nil)
+
+	// 流式中继：发送方和接收方同时连接
+	relay := c.expect(http.StatusOK, http.MethodPost, "/rooms", `{}`, nil)
+	relayCode, _ := relay["code"].(string)
+	sent := make(chan map[string]interface{}, 1)
+	go func() {
+		resp := c.do(context.Background(), http.MethodPut, "/stream/"+relayCode+"?filename=a.bin", content, map[string]string{"Content-Type": "application/octet-stream"})
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		out["status"] = resp.StatusCode
+		sent <- out
+	}()
+	resp = c.do(context.Background(), http.MethodGet, "/stream/"+relayCode, "", nil)
+	received, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(received) != content {
+		t.Fatalf("中继接收: %d %q", resp.StatusCode, received)
+	}
+	if out := <-sent; out["status"] != http.StatusOK {
+		t.Fatalf("中继发送: %v", out)
+	}
+	c.expect(http.StatusNotFound, http.MethodGet, "/stream/000000", "", nil)
+
+	// 管理接口
+	c.expect(http.StatusOK, http.MethodGet, "/admin/maintenance", "", nil)
+	c.expect(http.StatusOK, http.MethodPut, "/admin/maintenance", `{"enabled":true,"message":"升级中"}`, nil)
+	c.expect(http.StatusServiceUnavailable, http.MethodPost, "/rooms", `{}`, nil)
+	c.expect(http.StatusOK, http.MethodPut, "/admin/maintenance", `{"enabled":false}`, nil)
+	c.expect(http.StatusBadRequest, http.MethodPut, "/admin/maintenance", `{}`, nil)
+	c.expect(http.StatusOK, http.MethodPost, "/admin/notices", `{"message":"hello","level":"warning"}`, nil)
+	c.expect(http.StatusBadRequest, http.MethodPost, "/admin/notices", `{"message":"hello","level":"loud"}`, nil)
+
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	for _, msg := range checker.errs {
+		t.Error(msg)
+	}
+	for _, rt := range checker.v.routes {
+		if !checker.succeeded[rt.op] {
+			t.Errorf("%s %s 没有测试成功的响应", rt.method, rt.template)
+		}
+	}
+}
+
+// TestOversizedBodyRejected 超过上限的 JSON 请求体返回 413，不交给处理器
+func TestOversizedBodyRejected(t *testing.T) {
+	c, checker, _ := newContractServer(t)
+	limit := checker.v.maxBody
+
+	body := `{"text":"` + strings.Repeat("a", int(limit)) + `"}`
+	out := c.expect(http.StatusRequestEntityTooLarge, http.MethodPost, "/texts", body, nil)
+	if out["code"] != "BODY_TOO_LARGE" {
+		t.Fatalf("错误码 %v", out["code"])
+	}
+
+	// 未声明长度的请求体按实际读取的字节数判断
+	req, err := http.NewRequest(http.MethodPost, c.base+"/rooms", io.MultiReader(strings.NewReader(`{"ttl":1`), strings.NewReader(strings.Repeat(" ", int(limit))+`}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("未声明长度的请求体: 状态码 %d", resp.StatusCode)
+	}
+
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	for _, msg := range checker.errs {
+		t.Error(msg)
+	}
+}
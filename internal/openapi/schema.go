@@ -0,0 +1,200 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError 某个字段不符合文档
+type FieldError struct {
+	Field  string // 字段路径，例如 body.ttl 或 query.max_downloads
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// patterns 编译后的正则表达式缓存
+var patterns sync.Map
+
+// compilePattern 编译并缓存 pattern
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 pattern %q: %w", pattern, err)
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// validateValue 校验 JSON 值，数字需要以 json.Number 表示（解码时使用 UseNumber）
+func (s *Spec) validateValue(schema *Schema, value interface{}, field string) error {
+	schema, err := s.schema(schema)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return &FieldError{field, "不能为 null"}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return &FieldError{field, fmt.Sprintf("取值必须是 %v 之一", schema.Enum)}
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return &FieldError{field, "必须是对象"}
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return &FieldError{joinField(field, name), "缺少必填字段"}
+			}
+		}
+		// 按名称顺序校验，保证错误信息稳定
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if v, ok := obj[name]; ok {
+				if err := s.validateValue(schema.Properties[name], v, joinField(field, name)); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return &FieldError{field, "必须是数组"}
+		}
+		for i, item := range items {
+			if err := s.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", field, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return &FieldError{field, "必须是字符串"}
+		}
+		length := utf8.RuneCountInString(str)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return &FieldError{field, fmt.Sprintf("长度不能小于 %d", *schema.MinLength)}
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return &FieldError{field, fmt.Sprintf("长度不能大于 %d", *schema.MaxLength)}
+		}
+		if schema.Pattern != "" {
+			re, err := compilePattern(schema.Pattern)
+			if err != nil {
+				return err
+			}
+			if !re.MatchString(str) {
+				return &FieldError{field, fmt.Sprintf("格式不符合 %s", schema.Pattern)}
+			}
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return &FieldError{field, "必须是 RFC 3339 时间"}
+			}
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return &FieldError{field, "必须是数字"}
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return &FieldError{field, "必须是数字"}
+		}
+		if schema.Type == "integer" {
+			if _, err := num.Int64(); err != nil {
+				return &FieldError{field, "必须是整数"}
+			}
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			return &FieldError{field, fmt.Sprintf("不能小于 %v", *schema.Minimum)}
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			return &FieldError{field, fmt.Sprintf("不能大于 %v", *schema.Maximum)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return &FieldError{field, "必须是布尔值"}
+		}
+	default:
+		return fmt.Errorf("不支持的类型: %s", schema.Type)
+	}
+	return nil
+}
+
+// parseParameter 按参数类型将字符串转换为 JSON 值
+func (s *Spec) parseParameter(schema *Schema, raw string, field string) (interface{}, error) {
+	schema, err := s.schema(schema)
+	if err != nil || schema == nil {
+		return raw, err
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, &FieldError{field, "必须是数字"}
+		}
+		return json.Number(raw), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, &FieldError{field, "必须是布尔值"}
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// inEnum 判断值是否在枚举中，数字按数值比较
+func inEnum(enum []interface{}, value interface{}) bool {
+	if num, ok := value.(json.Number); ok {
+		f, err := num.Float64()
+		if err != nil {
+			return false
+		}
+		value = f
+	}
+	for _, candidate := range enum {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinField 拼接字段路径
+func joinField(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
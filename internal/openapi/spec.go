@@ -0,0 +1,221 @@
+// Package openapi 提供嵌入的 OpenAPI 3 文档，以及按文档校验请求和响应的中间件
+//
+// 只实现文档中用到的 JSON Schema 子集：type、format(date-time)、properties、required、
+// items、enum、minimum、maximum、minLength、maxLength、pattern、nullable 和本地 $ref。
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:embed openapi.json
+var specJSON []byte
+
+// Spec OpenAPI 文档中校验需要的部分
+type Spec struct {
+	Servers    []Server            `json:"servers"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Server 服务器地址，第一个地址作为路径前缀
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem 路径下按小写 HTTP 方法索引的操作
+type PathItem map[string]*Operation
+
+// Operation 接口操作
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter 路径、查询或请求头参数
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response 响应
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType 某种内容类型的结构
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema JSON Schema 子集
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *Schema            `json:"items"`
+	Enum       []interface{}      `json:"enum"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	Pattern    string             `json:"pattern"`
+	Nullable   bool               `json:"nullable"`
+}
+
+// Components 可复用的定义
+type Components struct {
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+	Schemas    map[string]*Schema    `json:"schemas"`
+}
+
+// Document 返回嵌入的 OpenAPI 文档原文
+func Document() []byte {
+	return specJSON
+}
+
+// Load 解析嵌入的 OpenAPI 文档
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(specJSON, &spec); err != nil {
+		return nil, fmt.Errorf("解析 OpenAPI 文档失败: %w", err)
+	}
+	return &spec, nil
+}
+
+// BasePath 文档中第一个服务器地址，例如 /api/v1
+func (s *Spec) BasePath() string {
+	if len(s.Servers) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(s.Servers[0].URL, "/")
+}
+
+// parameter 解析参数引用
+func (s *Spec) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "#/components/parameters/")
+	if err != nil {
+		return nil, err
+	}
+	resolved := s.Components.Parameters[name]
+	if resolved == nil {
+		return nil, fmt.Errorf("未定义的参数引用: %s", p.Ref)
+	}
+	return resolved, nil
+}
+
+// response 解析响应引用
+func (s *Spec) response(r *Response) (*Response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "#/components/responses/")
+	if err != nil {
+		return nil, err
+	}
+	resolved := s.Components.Responses[name]
+	if resolved == nil {
+		return nil, fmt.Errorf("未定义的响应引用: %s", r.Ref)
+	}
+	return resolved, nil
+}
+
+// schema 解析结构引用
+func (s *Spec) schema(schema *Schema) (*Schema, error) {
+	for schema != nil && schema.Ref != "" {
+		name, err := refName(schema.Ref, "#/components/schemas/")
+		if err != nil {
+			return nil, err
+		}
+		resolved := s.Components.Schemas[name]
+		if resolved == nil {
+			return nil, fmt.Errorf("未定义的结构引用: %s", schema.Ref)
+		}
+		schema = resolved
+	}
+	return schema, nil
+}
+
+// refName 取出本地引用的名称
+func refName(ref string, prefix string) (string, error) {
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("不支持的引用: %s", ref)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// check 检查文档中的所有引用都能解析，启动时调用以便尽早发现文档错误
+func (s *Spec) check() error {
+	for path, item := range s.Paths {
+		for method, op := range item {
+			for _, p := range op.Parameters {
+				resolved, err := s.parameter(p)
+				if err != nil {
+					return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+				}
+				if err := s.checkSchema(resolved.Schema); err != nil {
+					return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+				}
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					if err := s.checkSchema(media.Schema); err != nil {
+						return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+					}
+				}
+			}
+			for _, r := range op.Responses {
+				resolved, err := s.response(r)
+				if err != nil {
+					return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+				}
+				for _, media := range resolved.Content {
+					if err := s.checkSchema(media.Schema); err != nil {
+						return fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkSchema 递归检查结构中的引用和正则表达式
+func (s *Spec) checkSchema(schema *Schema) error {
+	schema, err := s.schema(schema)
+	if err != nil || schema == nil {
+		return err
+	}
+	if schema.Pattern != "" {
+		if _, err := compilePattern(schema.Pattern); err != nil {
+			return err
+		}
+	}
+	for _, prop := range schema.Properties {
+		if err := s.checkSchema(prop); err != nil {
+			return err
+		}
+	}
+	return s.checkSchema(schema.Items)
+}
@@ -0,0 +1,356 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"chuan/internal/apierr"
+	"chuan/internal/basepath"
+)
+
+// defaultMaxBody 默认的 JSON 请求体大小上限
+const defaultMaxBody = 1 << 20
+
+// maxRecordedResponse 校验响应时最多缓存的字节数
+const maxRecordedResponse = 1 << 20
+
+// route 文档中的一个操作
+type route struct {
+	method   string
+	template string   // 文档中的路径模板，例如 /rooms/{code}
+	segments []string // 按 / 分割的模板
+	op       *Operation
+}
+
+// Validator 按 OpenAPI 文档校验请求，可选地校验响应
+type Validator struct {
+	spec              *Spec
+	base              string
+	routes            []route
+	maxBody           int64
+	validateResponses bool
+}
+
+// errBodyTooLarge JSON 请求体超过大小上限
+var errBodyTooLarge = errors.New("请求体超过大小限制")
+
+// New 加载嵌入的文档并创建校验器
+//
+// maxBody 为 JSON 请求体的大小上限，超过时返回 413，不大于 0 时使用 1 MiB。
+// validateResponses 为 true 时检查 JSON 响应是否符合文档，不一致时只记录日志，不影响响应。
+func New(maxBody int64, validateResponses bool) (*Validator, error) {
+	spec, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := spec.check(); err != nil {
+		return nil, fmt.Errorf("OpenAPI 文档无效: %w", err)
+	}
+
+	if maxBody <= 0 {
+		maxBody = defaultMaxBody
+	}
+	v := &Validator{spec: spec, base: spec.BasePath(), maxBody: maxBody, validateResponses: validateResponses}
+	for template, item := range spec.Paths {
+		for method, op := range item {
+			v.routes = append(v.routes, route{
+				method:   strings.ToUpper(method),
+				template: template,
+				segments: strings.Split(strings.Trim(template, "/"), "/"),
+				op:       op,
+			})
+		}
+	}
+	// 固定路径优先于带参数的路径
+	sort.Slice(v.routes, func(i, j int) bool {
+		return strings.Count(v.routes[i].template, "{") < strings.Count(v.routes[j].template, "{")
+	})
+	return v, nil
+}
+
+// BasePath 文档声明的路径前缀
+func (v *Validator) BasePath() string {
+	return v.base
+}
+
+// SpecHandler 返回 OpenAPI 文档：GET /api/v1/openapi.json，部署在子路径下时服务器地址加上前缀
+func (v *Validator) SpecHandler(w http.ResponseWriter, r *http.Request) {
+	doc := Document()
+	if prefix := basepath.From(r); prefix != "" {
+		doc = withServer(doc, prefix+v.base)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(doc)
+}
+
+// withServer 替换文档中的服务器地址，失败时返回原文档
+func withServer(doc []byte, url string) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return doc
+	}
+	fields["servers"], _ = json.Marshal([]map[string]string{{"url": url}})
+	out, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return doc
+	}
+	return out
+}
+
+// Middleware 校验请求参数和 JSON 请求体，不符合文档时返回 400，请求体过大时返回 413
+//
+// 文档中没有的路径和方法直接放行，由路由返回 404 或 405。
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, params := v.match(r.Method, r.URL.Path)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := v.validateRequest(r, op, params); err != nil {
+			if errors.Is(err, errBodyTooLarge) {
+				apierr.Write(w, r, apierr.New(apierr.CodeBodyTooLarge).WithDetail("limit", v.maxBody))
+				return
+			}
+			var fieldErr *FieldError
+			if errors.As(err, &fieldErr) {
+				apierr.Write(w, r, apierr.New(apierr.CodeInvalidParameter, fieldErr.Field).
+					WithDetail("field", fieldErr.Field).
+					WithDetail("reason", fieldErr.Reason))
+				return
+			}
+			apierr.Write(w, r, apierr.Wrap(apierr.CodeBadRequest, err))
+			return
+		}
+
+		if !v.validateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if err := v.validateResponse(op, rec); err != nil {
+			log.Printf("响应与 OpenAPI 文档不一致: %s %s -> %d: %v", r.Method, r.URL.Path, rec.status, err)
+		}
+	})
+}
+
+// match 查找请求对应的操作，返回路径参数
+func (v *Validator) match(method string, path string) (*Operation, map[string]string) {
+	if !strings.HasPrefix(path, v.base+"/") {
+		return nil, nil
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, v.base), "/"), "/")
+
+	for _, rt := range v.routes {
+		if rt.method != method || len(rt.segments) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		matched := true
+		for i, seg := range rt.segments {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				if segments[i] == "" {
+					matched = false
+					break
+				}
+				params[seg[1:len(seg)-1]] = segments[i]
+				continue
+			}
+			if seg != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rt.op, params
+		}
+	}
+	return nil, nil
+}
+
+// validateRequest 校验参数和请求体
+func (v *Validator) validateRequest(r *http.Request, op *Operation, pathParams map[string]string) error {
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		param, err := v.spec.parameter(p)
+		if err != nil {
+			return err
+		}
+
+		var raw string
+		var present bool
+		switch param.In {
+		case "path":
+			raw, present = pathParams[param.Name]
+		case "query":
+			present = query.Has(param.Name)
+			raw = query.Get(param.Name)
+		case "header":
+			raw = r.Header.Get(param.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		field := param.In + "." + param.Name
+		if !present {
+			if param.Required {
+				return &FieldError{field, "缺少必填参数"}
+			}
+			continue
+		}
+		value, err := v.spec.parseParameter(param.Schema, raw, field)
+		if err != nil {
+			return err
+		}
+		if err := v.spec.validateValue(param.Schema, value, field); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	return v.validateJSONBody(r, op.RequestBody.Required, media.Schema)
+}
+
+// validateJSONBody 读取并校验 JSON 请求体，校验后恢复请求体供处理器读取
+//
+// 请求体必须完整校验后才能交给处理器，超过 maxBody 时直接拒绝。
+func (v *Validator) validateJSONBody(r *http.Request, required bool, schema *Schema) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		if required {
+			return &FieldError{"body", "缺少请求体"}
+		}
+		return nil
+	}
+	if r.ContentLength > v.maxBody {
+		return errBodyTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, v.maxBody+1))
+	if err != nil {
+		return fmt.Errorf("读取请求体失败: %w", err)
+	}
+	if int64(len(data)) > v.maxBody {
+		return errBodyTooLarge
+	}
+	r.Body = readCloser{bytes.NewReader(data), r.Body}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if required {
+			return &FieldError{"body", "缺少请求体"}
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return &FieldError{"body", "不是有效的 JSON"}
+	}
+	return v.spec.validateValue(schema, value, "body")
+}
+
+// validateResponse 校验缓存的 JSON 响应
+func (v *Validator) validateResponse(op *Operation, rec *responseRecorder) error {
+	resp := op.Responses[strconv.Itoa(rec.status)]
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return fmt.Errorf("文档未定义状态码 %d", rec.status)
+	}
+	resp, err := v.spec.response(resp)
+	if err != nil {
+		return err
+	}
+	if !rec.json {
+		return nil
+	}
+	media, ok := resp.Content["application/json"]
+	if !ok {
+		return fmt.Errorf("文档未定义状态码 %d 的 JSON 响应", rec.status)
+	}
+	if rec.truncated {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(rec.body.Bytes()))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("响应不是有效的 JSON: %w", err)
+	}
+	return v.spec.validateValue(media.Schema, value, "response")
+}
+
+// readCloser 替换请求体后仍然关闭原始请求体
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseRecorder 透传响应的同时缓存 JSON 响应体用于校验
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	json        bool
+	truncated   bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.wroteHeader = true
+		rec.status = status
+		mediaType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		rec.json = mediaType == "application/json"
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.json && !rec.truncated {
+		if rec.body.Len()+len(p) > maxRecordedResponse {
+			rec.truncated = true
+		} else {
+			rec.body.Write(p)
+		}
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// Flush 支持流式响应
+func (rec *responseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 供 http.ResponseController 访问底层连接
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
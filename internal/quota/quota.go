@@ -0,0 +1,328 @@
+// Package quota 限制单个用户或 IP 可以占用的房间数量和传输流量，以及服务器的房间总数。
+package quota
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/auth"
+	"chuan/internal/config"
+)
+
+// 按天统计的流量类型
+const (
+	KindRelay  = "relay"  // HTTP 流式中继转发的字节数
+	KindStored = "stored" // 暂存文件和断点续传上传保存的字节数
+)
+
+// globalRetryAfter 房间总数达到上限时建议的重试间隔
+const globalRetryAfter = time.Minute
+
+// ExceededError 超出配额
+type ExceededError struct {
+	Quota      string        // 超出的配额名称
+	Limit      int64         // 配额上限
+	RetryAfter time.Duration // 建议的重试间隔
+}
+
+func (e *ExceededError) Error() string {
+	switch e.Quota {
+	case "global_rooms":
+		return "服务器房间数量已达上限，请稍后重试"
+	case "concurrent_rooms":
+		return fmt.Sprintf("同时存在的房间数量已达上限 (%d)", e.Limit)
+	case "rooms_per_hour":
+		return fmt.Sprintf("每小时创建房间数量已达上限 (%d)", e.Limit)
+	case KindRelay + "_bytes":
+		return fmt.Sprintf("今日中继流量已达上限 (%d 字节)", e.Limit)
+	case KindStored + "_bytes":
+		return fmt.Sprintf("今日暂存流量已达上限 (%d 字节)", e.Limit)
+	default:
+		return "超出配额"
+	}
+}
+
+// Subject 配额统计主体：已认证时为用户标识，否则为客户端 IP
+func Subject(id *auth.Identity, ip string) string {
+	if id != nil {
+		return "user:" + id.Subject
+	}
+	return "ip:" + ip
+}
+
+// APIError 转换为 QUOTA_EXCEEDED，客户端可以在 RetryAfter 之后重试
+func (e *ExceededError) APIError() *apierr.Error {
+	return apierr.New(apierr.CodeQuotaExceeded, e.Quota, e.Limit).
+		WithDetail("quota", e.Quota).
+		WithDetail("limit", e.Limit).
+		WithRetryAfter(e.RetryAfter)
+}
+
+// Limiter 配额计数器
+//
+// 配额按主体统计，主体是已认证用户的标识或客户端 IP（由调用方决定）。
+// 房间创建时调用 ReserveRoom 占用名额，房间关闭或过期时通过 ReleaseRoom 归还。
+type Limiter struct {
+	roomCount func() int // 当前房间总数
+
+	mu       sync.Mutex
+	cfg      config.QuotaConfig // 配额上限，由 mu 保护，重新加载配置时更新
+	subjects map[string]*usage
+	rooms    map[string]string // 房间码 -> 占用名额的主体
+}
+
+// usage 单个主体的用量
+type usage struct {
+	rooms   int         // 当前占用的房间数
+	created []time.Time // 最近一小时内的创建时间
+	day     string      // bytes 对应的日期（UTC）
+	bytes   map[string]int64
+}
+
+// New 创建配额计数器，roomCount 返回服务器当前的房间总数
+func New(cfg config.QuotaConfig, roomCount func() int) *Limiter {
+	l := &Limiter{
+		cfg:       cfg,
+		roomCount: roomCount,
+		subjects:  make(map[string]*usage),
+		rooms:     make(map[string]string),
+	}
+	go l.cleanup()
+	return l
+}
+
+// SetConfig 更新配额上限，已有的用量统计保持不变
+func (l *Limiter) SetConfig(cfg config.QuotaConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cfg = cfg
+}
+
+// ReserveRoom 创建房间时为主体占用一个名额并与房间关联，房间关闭时据此归还；超出配额时返回 *ExceededError
+//
+// total 为服务器当前的房间总数。房间服务在持有房间锁时调用，因此由调用方传入总数，
+// 不读取 roomCount，锁顺序与房间事件钩子调用 ReleaseRoom 相同。
+func (l *Limiter) ReserveRoom(code string, subject string, total int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.MaxRooms > 0 && total >= l.cfg.MaxRooms {
+		return &ExceededError{Quota: "global_rooms", Limit: int64(l.cfg.MaxRooms), RetryAfter: globalRetryAfter}
+	}
+
+	now := time.Now()
+	u := l.usageLocked(subject, now)
+	if l.cfg.RoomsPerSubject > 0 && u.rooms >= l.cfg.RoomsPerSubject {
+		return &ExceededError{Quota: "concurrent_rooms", Limit: int64(l.cfg.RoomsPerSubject), RetryAfter: globalRetryAfter}
+	}
+	if l.cfg.RoomsPerHour > 0 && len(u.created) >= l.cfg.RoomsPerHour {
+		return &ExceededError{Quota: "rooms_per_hour", Limit: int64(l.cfg.RoomsPerHour), RetryAfter: u.created[0].Add(time.Hour).Sub(now)}
+	}
+
+	u.rooms++
+	u.created = append(u.created, now)
+	l.rooms[code] = subject
+	return nil
+}
+
+// ReleaseRoom 房间关闭或过期时归还名额，重复调用无副作用
+func (l *Limiter) ReleaseRoom(code string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	subject, ok := l.rooms[code]
+	if !ok {
+		return
+	}
+	delete(l.rooms, code)
+	if u := l.subjects[subject]; u != nil && u.rooms > 0 {
+		u.rooms--
+	}
+}
+
+// CheckBytes 检查主体今日是否还有指定类型的流量，size 为已知的传输大小（未知时传 0）
+func (l *Limiter) CheckBytes(subject string, kind string, size int64) error {
+	if size < 0 {
+		size = 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.byteLimitLocked(kind)
+	if limit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	u := l.usageLocked(subject, now)
+	if u.bytes[kind]+size > limit || u.bytes[kind] >= limit {
+		return l.bytesExceeded(kind, now)
+	}
+	return nil
+}
+
+// Reader 包装数据源，读取的字节计入主体今日流量，超出配额时返回 *ExceededError
+func (l *Limiter) Reader(subject string, kind string, r io.Reader) io.Reader {
+	l.mu.Lock()
+	limit := l.byteLimitLocked(kind)
+	l.mu.Unlock()
+	if limit <= 0 {
+		return r
+	}
+	return &countingReader{limiter: l, subject: subject, kind: kind, r: r}
+}
+
+// consume 记录流量，返回记录后是否超出配额
+func (l *Limiter) consume(subject string, kind string, n int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	u := l.usageLocked(subject, now)
+	u.bytes[kind] += n
+	if limit := l.byteLimitLocked(kind); limit > 0 && u.bytes[kind] > limit {
+		return l.bytesExceeded(kind, now)
+	}
+	return nil
+}
+
+// Allowance 某项配额的用量
+type Allowance struct {
+	Limit     int64      `json:"limit"` // 0 表示不限制
+	Used      int64      `json:"used"`
+	Remaining *int64     `json:"remaining"` // 不限制时为 null
+	ResetsAt  *time.Time `json:"resets_at,omitempty"`
+}
+
+// Report 主体的配额用量
+type Report struct {
+	Subject         string    `json:"subject"`
+	GlobalRooms     Allowance `json:"global_rooms"`
+	ConcurrentRooms Allowance `json:"concurrent_rooms"`
+	RoomsPerHour    Allowance `json:"rooms_per_hour"`
+	RelayBytes      Allowance `json:"relay_bytes_per_day"`
+	StoredBytes     Allowance `json:"stored_bytes_per_day"`
+}
+
+// Report 返回主体当前的配额用量
+func (l *Limiter) Report(subject string) Report {
+	total := l.roomCount()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	u := l.usageLocked(subject, now)
+	tomorrow := nextDay(now)
+
+	report := Report{
+		Subject:         subject,
+		GlobalRooms:     allowance(int64(l.cfg.MaxRooms), int64(total), nil),
+		ConcurrentRooms: allowance(int64(l.cfg.RoomsPerSubject), int64(u.rooms), nil),
+		RelayBytes:      allowance(l.cfg.RelayBytesPerDay, u.bytes[KindRelay], &tomorrow),
+		StoredBytes:     allowance(l.cfg.StoredBytesPerDay, u.bytes[KindStored], &tomorrow),
+	}
+	var hourReset *time.Time
+	if len(u.created) > 0 {
+		t := u.created[0].Add(time.Hour)
+		hourReset = &t
+	}
+	report.RoomsPerHour = allowance(int64(l.cfg.RoomsPerHour), int64(len(u.created)), hourReset)
+	return report
+}
+
+func allowance(limit int64, used int64, resetsAt *time.Time) Allowance {
+	a := Allowance{Limit: limit, Used: used, ResetsAt: resetsAt}
+	if limit > 0 {
+		remaining := limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		a.Remaining = &remaining
+	}
+	return a
+}
+
+// usageLocked 获取主体用量，顺便清理过期的统计（调用方需持有 mu）
+func (l *Limiter) usageLocked(subject string, now time.Time) *usage {
+	u := l.subjects[subject]
+	if u == nil {
+		u = &usage{bytes: make(map[string]int64)}
+		l.subjects[subject] = u
+	}
+
+	cutoff := now.Add(-time.Hour)
+	i := 0
+	for i < len(u.created) && !u.created[i].After(cutoff) {
+		i++
+	}
+	u.created = u.created[i:]
+
+	if day := now.UTC().Format("2006-01-02"); u.day != day {
+		u.day = day
+		u.bytes = make(map[string]int64)
+	}
+	return u
+}
+
+// byteLimitLocked 某类流量的每日上限（调用方需持有 mu）
+func (l *Limiter) byteLimitLocked(kind string) int64 {
+	switch kind {
+	case KindRelay:
+		return l.cfg.RelayBytesPerDay
+	case KindStored:
+		return l.cfg.StoredBytesPerDay
+	}
+	return 0
+}
+
+func (l *Limiter) bytesExceeded(kind string, now time.Time) error {
+	return &ExceededError{Quota: kind + "_bytes", Limit: l.byteLimitLocked(kind), RetryAfter: nextDay(now).Sub(now)}
+}
+
+// cleanup 定期删除没有占用任何配额的主体
+func (l *Limiter) cleanup() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mu.Lock()
+		now := time.Now()
+		for subject := range l.subjects {
+			u := l.usageLocked(subject, now)
+			if u.rooms == 0 && len(u.created) == 0 && len(u.bytes) == 0 {
+				delete(l.subjects, subject)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// nextDay 下一个 UTC 零点，按天统计的流量在此时重置
+func nextDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// countingReader 读取时累计流量
+type countingReader struct {
+	limiter *Limiter
+	subject string
+	kind    string
+	r       io.Reader
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		if qerr := c.limiter.consume(c.subject, c.kind, int64(n)); qerr != nil {
+			return 0, qerr
+		}
+	}
+	return n, err
+}
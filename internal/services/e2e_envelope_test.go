@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"chuan/internal/config"
+)
+
+// recordingConn 记录发送的消息和按 WebSocket 连接的方式编码后的字节
+type recordingConn struct {
+	mu   sync.Mutex
+	msgs []*WebRTCMessage
+	wire [][]byte
+}
+
+func (c *recordingConn) Send(msg *WebRTCMessage) error {
+	data, err := json.Marshal(msg) // 与 websocket.Conn.WriteJSON 的编码一致
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, msg)
+	c.wire = append(c.wire, data)
+	return nil
+}
+
+func (c *recordingConn) Close(code int, reason string) error { return nil }
+
+// find 返回第一条指定类型的消息及其编码
+func (c *recordingConn) find(msgType string) (*WebRTCMessage, []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, msg := range c.msgs {
+		if msg.Type == msgType {
+			return msg, c.wire[i]
+		}
+	}
+	return nil, nil
+}
+
+// envelopePayload 加密信封负载
+//
+// 其中的 A、\/ 转义和非规范的 base64 内容在任何一次解码再编码后都会变化，
+// 因此字节完全一致即说明服务器没有解析负载。
+const envelopePayload = `"A\/bm9uY2UtYW5kLWNpcGhlcnRleHQ-not-json{"`
+
+// rawEnvelope 从原始 JSON 解码消息，与服务器读取 WebSocket 消息的方式一致
+func rawEnvelope(t *testing.T, msgType string) *WebRTCMessage {
+	t.Helper()
+	var msg WebRTCMessage
+	raw := `{"type":"` + msgType + `","envelope":"e2e-v1","payload":` + envelopePayload + `}`
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Payload) != envelopePayload {
+		t.Fatalf("解码后的负载 %s", msg.Payload)
+	}
+	return &msg
+}
+
+// assertOpaque 确认收到的消息负载与发出时字节一致，且与发出的消息共享同一块内存（没有重新编码）
+func assertOpaque(t *testing.T, sent *WebRTCMessage, got *WebRTCMessage, wire []byte) {
+	t.Helper()
+	if got == nil {
+		t.Fatal("没有收到消息")
+	}
+	if got.Envelope != EnvelopeE2EV1 {
+		t.Fatalf("信封格式 %q", got.Envelope)
+	}
+	if string(got.Payload) != envelopePayload {
+		t.Fatalf("负载被修改: %s", got.Payload)
+	}
+	if &got.Payload[0] != &sent.Payload[0] {
+		t.Fatal("负载被复制或重新编码")
+	}
+	if !bytes.Contains(wire, []byte(`"payload":`+envelopePayload)) {
+		t.Fatalf("发送到连接上的负载被修改: %s", wire)
+	}
+}
+
+func newTestService(t *testing.T) (*WebRTCService, string) {
+	t.Helper()
+	ws := NewWebRTCService(config.Default())
+	room, err := ws.CreateNewRoom(CreateRoomOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws, room.Code
+}
+
+func TestValidateMessageAcceptsOpaqueEnvelope(t *testing.T) {
+	msg := rawEnvelope(t, MessageOffer)
+	before := append(json.RawMessage(nil), msg.Payload...)
+	if err := validateMessage(msg); err != nil {
+		t.Fatalf("合法的加密信封被拒绝: %v", err)
+	}
+	if !bytes.Equal(msg.Payload, before) {
+		t.Fatal("校验修改了负载")
+	}
+
+	for _, c := range []struct {
+		name string
+		msg  WebRTCMessage
+	}{
+		{"未知信封格式", WebRTCMessage{Type: MessageOffer, Envelope: "e2e-v2", Payload: json.RawMessage(envelopePayload)}},
+		{"负载不是字符串", WebRTCMessage{Type: MessageOffer, Envelope: EnvelopeE2EV1, Payload: json.RawMessage(`{"sdp":"v=0"}`)}},
+		{"校验消息加密", WebRTCMessage{Type: MessageVerifyFingerprint, Envelope: EnvelopeE2EV1, Payload: json.RawMessage(envelopePayload)}},
+	} {
+		if err := validateMessage(&c.msg); err == nil {
+			t.Errorf("%s: 应被拒绝", c.name)
+		}
+	}
+}
+
+func TestForwardEnvelopeUnchanged(t *testing.T) {
+	ws, code := newTestService(t)
+	senderConn, receiverConn := &recordingConn{}, &recordingConn{}
+	sender, err := ws.Join(code, RoleSender, "", senderConn, PeerInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := ws.Join(code, RoleReceiver, "", receiverConn, PeerInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msgType := range []string{MessageOffer, MessageICECandidate, MessagePAKEOffer} {
+		msg := rawEnvelope(t, msgType)
+		ws.Receive(sender, msg)
+		got, wire := receiverConn.find(msgType)
+		assertOpaque(t, msg, got, wire)
+	}
+
+	msg := rawEnvelope(t, MessageAnswer)
+	ws.Receive(receiver, msg)
+	got, wire := senderConn.find(MessageAnswer)
+	assertOpaque(t, msg, got, wire)
+
+	if errMsg, _ := senderConn.find(MessageError); errMsg != nil {
+		t.Fatalf("发送方收到错误: %s", errMsg.Error)
+	}
+}
+
+func TestBufferedEnvelopeUnchanged(t *testing.T) {
+	ws, code := newTestService(t)
+	sender, err := ws.Join(code, RoleSender, "", &recordingConn{}, PeerInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 接收方加入前发出的 pake-init 和 offer 由服务器保存，加入后补发
+	pakeInit := rawEnvelope(t, MessagePAKEInit)
+	offer := rawEnvelope(t, MessageOffer)
+	ws.Receive(sender, pakeInit)
+	ws.Receive(sender, offer)
+
+	ws.roomsMux.RLock()
+	room := ws.rooms[code]
+	lastPAKE, lastOffer := room.LastPAKE, room.LastOffer
+	ws.roomsMux.RUnlock()
+	if lastPAKE == nil || lastOffer == nil {
+		t.Fatal("没有保存 pake-init 或 offer")
+	}
+	if string(lastPAKE.Payload) != envelopePayload || string(lastOffer.Payload) != envelopePayload {
+		t.Fatal("保存的负载被修改")
+	}
+
+	receiverConn := &recordingConn{}
+	if _, err := ws.Join(code, RoleReceiver, "", receiverConn, PeerInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	got, wire := receiverConn.find(MessagePAKEInit)
+	assertOpaque(t, pakeInit, got, wire)
+	got, wire = receiverConn.find(MessageOffer)
+	assertOpaque(t, offer, got, wire)
+}
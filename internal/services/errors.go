@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+
+	"chuan/internal/apierr"
+)
+
+// errorCodes 服务层错误对应的错误码
+var errorCodes = []struct {
+	err  error
+	code apierr.Code
+}{
+	{ErrRoomNotFound, apierr.CodeRoomNotFound},
+	{ErrInvalidOwnerToken, apierr.CodeInvalidOwnerToken},
+	{ErrTextNotFound, apierr.CodeTextNotFound},
+	{ErrTextEmpty, apierr.CodeTextEmpty},
+	{ErrTextTooLarge, apierr.CodeTextTooLarge},
+	{ErrStoredFileNotFound, apierr.CodeFileNotFound},
+	{ErrStoredFileEmpty, apierr.CodeFileEmpty},
+	{ErrStoredFileTooLarge, apierr.CodeFileTooLarge},
+	{ErrStoreQuotaExceeded, apierr.CodeStorageFull},
+	{ErrRelayBusy, apierr.CodeRelayBusy},
+	{ErrRelayTimeout, apierr.CodeRelayTimeout},
+	{ErrRelayCanceled, apierr.CodeRelayCanceled},
+}
+
+// ToAPIError 将服务层错误转换为带错误码的API错误，供 HTTP 和 gRPC 接口共用
+//
+// 已经是 API 错误（或可以转换为 API 错误，例如超出配额）时原样返回，无法识别的错误视为内部错误。
+func ToAPIError(err error) *apierr.Error {
+	if e, ok := apierr.As(err); ok {
+		return e
+	}
+	for _, m := range errorCodes {
+		if errors.Is(err, m.err) {
+			return apierr.Wrap(m.code, err)
+		}
+	}
+	return apierr.Wrap(apierr.CodeInternal, err)
+}
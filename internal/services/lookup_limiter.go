@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"chuan/internal/apierr"
+)
+
+// 取件码查找失败次数限制，防止逐个尝试取件码
+const (
+	lookupFailureLimit  = 10          // 时间窗口内每个 IP 允许的失败次数
+	lookupFailureWindow = time.Minute // 统计失败次数的时间窗口
+)
+
+// LookupLimitError 同一 IP 查找取件码失败次数过多
+type LookupLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LookupLimitError) Error() string {
+	return fmt.Sprintf("取件码错误次数过多，请在 %s 后重试", e.RetryAfter.Round(time.Second))
+}
+
+// APIError 转换为 RATE_LIMITED
+func (e *LookupLimitError) APIError() *apierr.Error {
+	return apierr.New(apierr.CodeRateLimited).WithRetryAfter(e.RetryAfter)
+}
+
+// lookupLimiter 按 IP 统计取件码查找失败次数，超过上限后在时间窗口结束前拒绝该 IP 的查找
+type lookupLimiter struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time // IP -> 时间窗口内的失败时间
+}
+
+func newLookupLimiter() *lookupLimiter {
+	return &lookupLimiter{failures: make(map[string][]time.Time)}
+}
+
+// check 该 IP 是否还可以查找，超过上限时返回 *LookupLimitError
+func (l *lookupLimiter) check(ip string, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.pruneLocked(ip, now)
+	if len(recent) < lookupFailureLimit {
+		return nil
+	}
+	return &LookupLimitError{RetryAfter: recent[0].Add(lookupFailureWindow).Sub(now)}
+}
+
+// fail 记录一次查找失败
+func (l *lookupLimiter) fail(ip string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failures[ip] = append(l.pruneLocked(ip, now), now)
+}
+
+// cleanup 删除时间窗口外的记录
+func (l *lookupLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ip := range l.failures {
+		l.pruneLocked(ip, now)
+	}
+}
+
+// pruneLocked 丢弃时间窗口外的失败记录并返回剩余记录（调用方需持有 mu）
+func (l *lookupLimiter) pruneLocked(ip string, now time.Time) []time.Time {
+	times := l.failures[ip]
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= lookupFailureWindow {
+		i++
+	}
+	times = times[i:]
+	if len(times) == 0 {
+		delete(l.failures, ip)
+		return nil
+	}
+	l.failures[ip] = times
+	return times
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"chuan/internal/apierr"
+)
+
+// ErrMaintenance 服务器处于维护模式，不再创建房间
+var ErrMaintenance = errors.New("服务器维护中")
+
+// Maintenance 维护模式状态
+type Maintenance struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"` // 提示给用户的说明
+	Since   *time.Time `json:"since,omitempty"`   // 进入维护模式的时间
+}
+
+// 公告级别
+const (
+	NoticeInfo    = "info"
+	NoticeWarning = "warning"
+)
+
+// Notice 运营公告，以 server-notice 消息发送给所有在线客户端
+type Notice struct {
+	Message string `json:"message"`
+	Level   string `json:"level"` // info 或 warning
+}
+
+// 管理操作事件类型
+const (
+	EventMaintenanceEnabled  = "admin.maintenance_enabled"
+	EventMaintenanceDisabled = "admin.maintenance_disabled"
+	EventNoticeSent          = "admin.notice_sent"
+)
+
+// AdminEvent 管理操作事件，用于审计
+type AdminEvent struct {
+	Type    string
+	Actor   Actor             // 执行操作的管理员或信号
+	Details map[string]string // 操作参数和结果
+	Time    time.Time
+}
+
+// AdminHook 管理操作事件钩子，与 RoomHook 一样在持有房间锁时同步调用，不能阻塞
+type AdminHook func(event AdminEvent)
+
+// AddAdminHook 注册管理操作事件钩子，应当在开始处理请求之前调用
+func (ws *WebRTCService) AddAdminHook(hook AdminHook) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	ws.admin = append(ws.admin, hook)
+}
+
+// emitAdmin 触发管理操作事件（调用方需持有 roomsMux）
+func (ws *WebRTCService) emitAdmin(eventType string, actor Actor, details map[string]string) {
+	event := AdminEvent{Type: eventType, Actor: actor, Details: details, Time: time.Now()}
+	for _, hook := range ws.admin {
+		hook(event)
+	}
+}
+
+// SetMaintenance 进入或退出维护模式，actor 为执行操作的管理员
+//
+// 维护期间不再创建房间（包括发送方加入时自动创建），已有房间和正在进行的传输不受影响。
+// 状态没有变化时（重复退出，或以相同说明重复进入）直接返回，不记录审计事件。
+func (ws *WebRTCService) SetMaintenance(enabled bool, message string, actor Actor) Maintenance {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	if enabled == ws.maintenance.Enabled && (!enabled || message == ws.maintenance.Message) {
+		return ws.maintenance
+	}
+
+	if enabled {
+		ws.emitAdmin(EventMaintenanceEnabled, actor, map[string]string{"message": message})
+	} else {
+		ws.emitAdmin(EventMaintenanceDisabled, actor, nil)
+	}
+
+	switch {
+	case enabled && !ws.maintenance.Enabled:
+		now := time.Now()
+		ws.maintenance = Maintenance{Enabled: true, Message: message, Since: &now}
+		log.Printf("进入维护模式: %s", message)
+	case enabled:
+		ws.maintenance.Message = message
+	case ws.maintenance.Enabled:
+		ws.maintenance = Maintenance{}
+		log.Printf("退出维护模式")
+	}
+	return ws.maintenance
+}
+
+// Maintenance 当前维护模式状态
+func (ws *WebRTCService) Maintenance() Maintenance {
+	ws.roomsMux.RLock()
+	defer ws.roomsMux.RUnlock()
+
+	return ws.maintenance
+}
+
+// Broadcast 向所有在线客户端发送 server-notice 消息，返回收到消息的客户端数量；actor 为发送公告的管理员
+func (ws *WebRTCService) Broadcast(notice Notice, actor Actor) int {
+	if notice.Level == "" {
+		notice.Level = NoticeInfo
+	}
+	msg := &WebRTCMessage{
+		Type: MessageNotice,
+		Payload: encodePayload(map[string]interface{}{
+			"message": notice.Message,
+			"level":   notice.Level,
+			"time":    time.Now(),
+		}),
+	}
+
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	delivered := 0
+	for _, room := range ws.rooms {
+		for _, client := range []*WebRTCClient{room.Sender, room.Receiver} {
+			if client == nil || client.Connection == nil {
+				continue
+			}
+			if err := client.Connection.Send(msg); err != nil {
+				log.Printf("发送公告失败: %v", err)
+				continue
+			}
+			delivered++
+		}
+	}
+	log.Printf("已向 %d 个客户端发送公告: %s", delivered, notice.Message)
+	ws.emitAdmin(EventNoticeSent, actor, map[string]string{
+		"message":   notice.Message,
+		"level":     notice.Level,
+		"delivered": strconv.Itoa(delivered),
+	})
+	return delivered
+}
+
+// refuseNewRoomLocked 优雅关闭或维护期间拒绝创建房间（调用方需持有 roomsMux）
+func (ws *WebRTCService) refuseNewRoomLocked() error {
+	if ws.draining {
+		return ws.shutdownError()
+	}
+	if ws.maintenance.Enabled {
+		e := apierr.Wrap(apierr.CodeMaintenance, ErrMaintenance)
+		if message := strings.TrimSpace(ws.maintenance.Message); message != "" {
+			e.WithDetail("notice", message)
+		}
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/auth"
+
+	"github.com/gorilla/websocket"
+)
+
+// 客户端角色
+const (
+	RoleSender   = "sender"
+	RoleReceiver = "receiver"
+)
+
+// 发送队列
+const (
+	peerQueueSize    = 64               // 每个连接最多排队的消息数，队列满时断开该连接
+	peerWriteTimeout = 10 * time.Second // 单条消息的写超时
+	peerCloseTimeout = 5 * time.Second  // 关闭时发送剩余消息的最长时间，超时后直接断开
+)
+
+// errPeerClosed 连接已关闭
+var errPeerClosed = errors.New("信令连接已关闭")
+
+// PeerConn 客户端的信令连接，由 NewPeerConn 创建
+//
+// Send 和 Close 在持有 roomsMux 时调用，不能阻塞也不能进行网络 I/O；
+// Close 结束连接，之后连接的读取方应当尽快调用 Leave。code 为 WebSocket 关闭码，gRPC 流只使用 reason。
+type PeerConn interface {
+	Send(msg *WebRTCMessage) error
+	Close(code int, reason string) error
+}
+
+// PeerWriter 实际写出消息的底层连接，WebSocket 和 gRPC 双向流都实现该接口
+//
+// Write 只在连接自己的写协程中调用，不会并发；Close 可能与 Write 并发调用，用于断开卡住的写入。
+type PeerWriter interface {
+	Write(msg *WebRTCMessage) error
+	Close(code int, reason string) error
+}
+
+// NewPeerConn 为底层连接创建发送队列并启动写协程
+//
+// 消息按顺序由写协程发送，对方停止读取时只会填满它自己的队列，队列满后断开该连接，不影响其他房间。
+func NewPeerConn(w PeerWriter) PeerConn {
+	p := &queuedPeer{
+		w:       w,
+		queue:   make(chan *WebRTCMessage, peerQueueSize),
+		closing: make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// queuedPeer 带发送队列的信令连接
+type queuedPeer struct {
+	w     PeerWriter
+	queue chan *WebRTCMessage
+
+	closeOnce    sync.Once
+	closing      chan struct{} // Close 后关闭
+	code         int
+	reason       string
+	shutdownOnce sync.Once
+}
+
+func (p *queuedPeer) Send(msg *WebRTCMessage) error {
+	select {
+	case <-p.closing:
+		return errPeerClosed
+	default:
+	}
+
+	select {
+	case p.queue <- msg:
+		return nil
+	default:
+		// 对方没有在读取，不再等待发送剩余消息，直接断开（在新协程中进行，调用方持有 roomsMux）
+		log.Printf("信令连接发送队列已满，断开连接")
+		p.Close(websocket.ClosePolicyViolation, "send queue full")
+		go p.shutdown()
+		return errors.New("信令连接发送队列已满")
+	}
+}
+
+// Close 在发送完已排队的消息后关闭连接，peerCloseTimeout 内没有发送完时直接断开
+func (p *queuedPeer) Close(code int, reason string) error {
+	p.closeOnce.Do(func() {
+		p.code, p.reason = code, reason
+		close(p.closing)
+		time.AfterFunc(peerCloseTimeout, p.shutdown)
+	})
+	return nil
+}
+
+// run 写协程：按顺序发送队列中的消息，关闭时发送剩余消息后断开
+func (p *queuedPeer) run() {
+	defer p.shutdown()
+
+	for {
+		select {
+		case msg := <-p.queue:
+			if err := p.w.Write(msg); err != nil {
+				log.Printf("发送%s消息失败，断开连接: %v", msg.Type, err)
+				p.Close(websocket.CloseInternalServerErr, "write failed")
+				return
+			}
+		case <-p.closing:
+			for {
+				select {
+				case msg := <-p.queue:
+					if p.w.Write(msg) != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown 关闭底层连接，只执行一次
+func (p *queuedPeer) shutdown() {
+	p.shutdownOnce.Do(func() {
+		p.w.Close(p.code, p.reason)
+	})
+}
+
+// PeerInfo 连接发起方的信息
+type PeerInfo struct {
+	Identity  *auth.Identity // 已认证的用户身份，未认证时为 nil
+	IP        string
+	UserAgent string
+	Lang      string // 错误消息使用的语言
+	JoinOnly  bool   // 只加入已有的房间：发送方没有创建房间的权限时设置，房间不存在时返回 ROOM_NOT_FOUND
+}
+
+// Join 将连接加入房间，发送方加入不存在的房间时按声明的类型自动创建
+//
+// 参数无效时返回 INVALID_PARAMETER，接收方加入不存在的房间时返回 ROOM_NOT_FOUND，
+// 房间类型不符时返回 ROOM_TYPE_MISMATCH，同一角色已经在线时返回 ROOM_FULL。
+func (ws *WebRTCService) Join(code string, role string, channel string, conn PeerConn, info PeerInfo) (*WebRTCClient, error) {
+	if code == "" || (role != RoleSender && role != RoleReceiver) {
+		return nil, apierr.New(apierr.CodeInvalidParameter, "code/role")
+	}
+	roomType, err := ParseRoomType("channel", channel)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &WebRTCClient{
+		ID:         ws.generateClientID(),
+		Role:       role,
+		Connection: conn,
+		Room:       code,
+		Type:       roomType,
+		Identity:   info.Identity,
+		IP:         info.IP,
+		UserAgent:  info.UserAgent,
+		Lang:       info.Lang,
+		joinOnly:   info.JoinOnly,
+	}
+	if err := ws.addClientToRoom(code, client); err != nil {
+		return nil, err
+	}
+	log.Printf("WebRTC %s连接到房间: %s (客户端ID: %s)", role, code, client.ID)
+	return client, nil
+}
+
+// Receive 处理客户端发来的信令消息：校验后转发给对方，无效消息回复 error
+func (ws *WebRTCService) Receive(client *WebRTCClient, msg *WebRTCMessage) {
+	msg.From = client.ID
+	log.Printf("收到WebRTC信令: 类型=%s, 来自=%s, 房间=%s", msg.Type, client.ID, client.Room)
+
+	if err := validateMessage(msg); err != nil {
+		log.Printf("WebRTC信令无效: %v", err)
+		ws.sendError(client.Room, client.ID, apierr.Wrap(apierr.CodeInvalidMessage, err).WithDetail("reason", err.Error()))
+		return
+	}
+
+	// 转发信令消息给对方
+	ws.forwardMessage(client.Room, client.ID, msg)
+}
+
+// Leave 客户端断开后从房间移除，并通知房间内的对方
+func (ws *WebRTCService) Leave(client *WebRTCClient) {
+	ws.removeClientFromRoom(client.Room, client.ID)
+	log.Printf("WebRTC客户端断开连接: %s (房间: %s)", client.ID, client.Room)
+
+	ws.notifyRoomDisconnection(client.Room, client.ID, client.Role)
+	if client.Connection != nil {
+		client.Connection.Close(websocket.CloseNormalClosure, "")
+	}
+}
+
+// RejectMessage 构造拒绝连接时发送的 error 消息
+func RejectMessage(lang string, err error) *WebRTCMessage {
+	return errorMessage("", lang, apierr.From(err))
+}
+
+// wsPeer WebSocket 信令连接
+type wsPeer struct {
+	conn *websocket.Conn
+}
+
+func (p *wsPeer) Write(msg *WebRTCMessage) error {
+	p.conn.SetWriteDeadline(time.Now().Add(peerWriteTimeout))
+	return p.conn.WriteJSON(msg)
+}
+
+func (p *wsPeer) Close(code int, reason string) error {
+	p.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second))
+	return p.conn.Close()
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// stuckWriter 模拟停止读取的客户端：Write 一直阻塞到连接被关闭
+type stuckWriter struct {
+	release chan struct{} // Close 时关闭，解除阻塞的 Write
+	closed  chan string   // 关闭原因
+}
+
+func (w *stuckWriter) Write(msg *WebRTCMessage) error {
+	<-w.release
+	return errPeerClosed
+}
+
+func (w *stuckWriter) Close(code int, reason string) error {
+	w.closed <- reason
+	close(w.release)
+	return nil
+}
+
+// 对方停止读取时 Send 不阻塞，队列满后断开该连接
+func TestPeerConnDoesNotBlockOnStuckClient(t *testing.T) {
+	w := &stuckWriter{release: make(chan struct{}), closed: make(chan string, 1)}
+	peer := NewPeerConn(w)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < peerQueueSize*2; i++ {
+			peer.Send(&WebRTCMessage{Type: MessageOffer})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Send 被停止读取的客户端阻塞")
+	}
+
+	select {
+	case reason := <-w.closed:
+		if reason != "send queue full" {
+			t.Fatalf("关闭原因 %q", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("队列满后没有断开连接")
+	}
+	if err := peer.Send(&WebRTCMessage{Type: MessageOffer}); err == nil {
+		t.Fatal("连接关闭后 Send 应返回错误")
+	}
+}
+
+// recordingWriter 记录写出的消息和关闭原因
+type recordingWriter struct {
+	msgs   chan string
+	closed chan string
+}
+
+func (w *recordingWriter) Write(msg *WebRTCMessage) error {
+	w.msgs <- msg.Type
+	return nil
+}
+
+func (w *recordingWriter) Close(code int, reason string) error {
+	w.closed <- reason
+	return nil
+}
+
+// Close 之前排队的消息按顺序发送完后才关闭底层连接
+func TestPeerConnFlushesBeforeClose(t *testing.T) {
+	w := &recordingWriter{msgs: make(chan string, 10), closed: make(chan string, 1)}
+	peer := NewPeerConn(w)
+	peer.Send(&WebRTCMessage{Type: MessageOffer})
+	peer.Send(&WebRTCMessage{Type: MessageAnswer})
+	peer.Close(1000, "room expired")
+
+	if reason := <-w.closed; reason != "room expired" {
+		t.Fatalf("关闭原因 %q", reason)
+	}
+	close(w.msgs)
+	var got []string
+	for m := range w.msgs {
+		got = append(got, m)
+	}
+	if len(got) != 2 || got[0] != MessageOffer || got[1] != MessageAnswer {
+		t.Fatalf("发送的消息 %v", got)
+	}
+}
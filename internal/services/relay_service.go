@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+
+	"chuan/internal/config"
+)
+
+// relayChunkSize 中继每次转发的数据块大小
+const relayChunkSize = 32 << 10
+
+var (
+	// ErrRelayBusy 该房间已有同一方向的中继连接
+	ErrRelayBusy = errors.New("该房间已有进行中的传输")
+	// ErrRelayTimeout 等待另一端连接超时
+	ErrRelayTimeout = errors.New("等待对方连接超时")
+	// ErrRelayCanceled 另一端已取消传输
+	ErrRelayCanceled = errors.New("对方已取消传输")
+)
+
+// RelayMeta 发送方提供的文件信息，用于接收方的响应头
+type RelayMeta struct {
+	Size        int64 // 文件大小，-1 表示未知
+	Filename    string
+	ContentType string
+}
+
+// RelayStream 一次流式中继：发送方写入的数据经过有界缓冲区转发给接收方，不落盘
+type RelayStream struct {
+	code   string
+	chunks chan []byte
+
+	senderReady   chan struct{} // 发送方已连接，meta 可读
+	receiverReady chan struct{} // 接收方已连接
+	meta          RelayMeta
+
+	done     chan struct{} // 任意一端取消时关闭
+	doneOnce sync.Once
+	err      error
+}
+
+// Meta 返回发送方提供的文件信息，只能在 WaitSender 成功后调用
+func (s *RelayStream) Meta() RelayMeta {
+	return s.meta
+}
+
+// Abort 取消中继，另一端的读写会立即返回
+func (s *RelayStream) Abort(err error) {
+	s.doneOnce.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+// Err 返回取消原因
+func (s *RelayStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// RelayService HTTP 流式中继服务
+type RelayService struct {
+	cfg   config.RelayConfig
+	rooms *WebRTCService
+
+	mu      sync.Mutex
+	streams map[string]*relayEntry
+}
+
+// relayEntry 记录每个房间的中继及两端是否已连接
+type relayEntry struct {
+	stream   *RelayStream
+	sender   bool
+	receiver bool
+}
+
+// NewRelayService 创建流式中继服务
+func NewRelayService(cfg config.RelayConfig, rooms *WebRTCService) *RelayService {
+	return &RelayService{
+		cfg:     cfg,
+		rooms:   rooms,
+		streams: make(map[string]*relayEntry),
+	}
+}
+
+// Send 作为发送方连接：等待接收方连接后把 body 转发给接收方，返回转发的字节数
+func (s *RelayService) Send(ctx context.Context, code string, meta RelayMeta, body io.Reader) (int64, error) {
+	stream, err := s.attach(code, true)
+	if err != nil {
+		return 0, err
+	}
+	defer s.detach(code, stream, true)
+
+	stream.meta = meta
+	close(stream.senderReady)
+	log.Printf("中继发送方已连接: 房间=%s, 大小=%d", code, meta.Size)
+
+	if err := s.wait(ctx, stream, stream.receiverReady); err != nil {
+		stream.Abort(err)
+		return 0, err
+	}
+
+	var total int64
+	for {
+		buf := make([]byte, relayChunkSize)
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			select {
+			case stream.chunks <- buf[:n]:
+				total += int64(n)
+			case <-stream.done:
+				return total, stream.err
+			case <-ctx.Done():
+				stream.Abort(ErrRelayCanceled)
+				return total, ctx.Err()
+			}
+		}
+		if readErr == io.EOF {
+			close(stream.chunks)
+			log.Printf("中继发送完成: 房间=%s, %d 字节", code, total)
+			return total, nil
+		}
+		if readErr != nil {
+			stream.Abort(ErrRelayCanceled)
+			return total, readErr
+		}
+	}
+}
+
+// Receive 作为接收方连接：等待发送方连接后返回中继，调用方通过 Copy 读取数据
+func (s *RelayService) Receive(ctx context.Context, code string) (*RelayStream, func(), error) {
+	stream, err := s.attach(code, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	close(stream.receiverReady)
+	log.Printf("中继接收方已连接: 房间=%s", code)
+
+	release := func() { s.detach(code, stream, false) }
+	if err := s.wait(ctx, stream, stream.senderReady); err != nil {
+		stream.Abort(err)
+		release()
+		return nil, nil, err
+	}
+	return stream, release, nil
+}
+
+// Copy 把中继数据写入 w，直到发送方完成或任意一端取消
+func (s *RelayService) Copy(ctx context.Context, stream *RelayStream, w io.Writer, flush func()) (int64, error) {
+	var total int64
+	for {
+		select {
+		case chunk, ok := <-stream.chunks:
+			if !ok {
+				return total, nil
+			}
+			n, err := w.Write(chunk)
+			total += int64(n)
+			if err != nil {
+				stream.Abort(ErrRelayCanceled)
+				return total, err
+			}
+			if flush != nil {
+				flush()
+			}
+		case <-stream.done:
+			return total, stream.err
+		case <-ctx.Done():
+			stream.Abort(ErrRelayCanceled)
+			return total, ctx.Err()
+		}
+	}
+}
+
+// attach 连接到房间的中继，不存在时创建
+func (s *RelayService) attach(code string, sender bool) (*RelayStream, error) {
+	if _, err := s.rooms.RoomExpiresAt(code); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.streams[code]
+	if entry == nil {
+		entry = &relayEntry{
+			stream: &RelayStream{
+				code:          code,
+				chunks:        make(chan []byte, max(1, s.cfg.BufferSize/relayChunkSize)),
+				senderReady:   make(chan struct{}),
+				receiverReady: make(chan struct{}),
+				done:          make(chan struct{}),
+			},
+		}
+		s.streams[code] = entry
+	}
+
+	if (sender && entry.sender) || (!sender && entry.receiver) {
+		return nil, ErrRelayBusy
+	}
+	if sender {
+		entry.sender = true
+	} else {
+		entry.receiver = true
+	}
+	return entry.stream, nil
+}
+
+// detach 断开一端；任意一端离开后中继不可复用，新的连接会创建新的中继
+func (s *RelayService) detach(code string, stream *RelayStream, sender bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sender {
+		// 发送方在接收方连接前离开，通知之后连接的接收方
+		select {
+		case <-stream.receiverReady:
+		default:
+			stream.Abort(ErrRelayCanceled)
+		}
+	} else {
+		// 接收方离开时发送方不必继续上传
+		stream.Abort(ErrRelayCanceled)
+	}
+
+	if entry := s.streams[code]; entry != nil && entry.stream == stream {
+		delete(s.streams, code)
+	}
+}
+
+// wait 等待另一端连接
+func (s *RelayService) wait(ctx context.Context, stream *RelayStream, ready <-chan struct{}) error {
+	timeout, cancel := context.WithTimeout(ctx, s.cfg.WaitTimeout.Duration)
+	defer cancel()
+
+	select {
+	case <-ready:
+		return nil
+	case <-stream.done:
+		return stream.err
+	case <-timeout.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrRelayTimeout
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"chuan/internal/models"
+)
+
+// 房间生命周期事件类型
+const (
+	EventRoomCreated  = "room.created"
+	EventPeerJoined   = "peer.joined"
+	EventPeerLeft     = "peer.left"
+	EventRoomExpired  = "room.expired"
+	EventRoomClosed   = "room.closed"
+	EventRoomExtended = "room.extended" // 房主延长了有效期
+
+	EventRoomStateChanged = "room.state_changed" // 房间生命周期状态变化
+)
+
+// RoomEvent 房间生命周期事件
+type RoomEvent struct {
+	Type      string    `json:"type"`
+	Room      string    `json:"room"`
+	RoomType  string    `json:"room_type,omitempty"`  // 房间类型，尚未确定时为空
+	State     string    `json:"state"`                // 事件发生后的房间状态
+	PrevState string    `json:"prev_state,omitempty"` // 状态变化前的状态，仅 room.state_changed 事件
+	Role      string    `json:"role,omitempty"`       // 加入/离开的客户端角色
+	ClientID  string    `json:"client_id,omitempty"`  // 加入/离开的客户端ID
+	Creator   string    `json:"creator,omitempty"`    // 房间创建者（启用认证时）
+	Actor     Actor     `json:"-"`                    // 触发事件的客户端，不发送给 Webhook
+	ExpiresAt time.Time `json:"expires_at"`
+	Time      time.Time `json:"time"`
+}
+
+// RoomHook 房间事件钩子
+//
+// 钩子在持有房间锁时同步调用，实现方不能阻塞，也不能回调 WebRTCService 的方法，
+// 需要耗时处理时应当自行转交给后台协程。
+type RoomHook func(event RoomEvent)
+
+// AddHook 注册房间事件钩子，应当在开始处理请求之前调用
+func (ws *WebRTCService) AddHook(hook RoomHook) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	ws.hooks = append(ws.hooks, hook)
+}
+
+// emit 触发房间事件（调用方需持有 roomsMux）
+//
+// 有客户端时操作者为该客户端；房间创建事件的操作者为创建者；过期、关闭等事件没有操作者。
+func (ws *WebRTCService) emit(eventType string, room *WebRTCRoom, client *WebRTCClient) {
+	var actor Actor
+	if client != nil {
+		actor = client.actor()
+	} else if eventType == EventRoomCreated {
+		actor = room.Creator
+	}
+	ws.emitAs(eventType, room, client, actor)
+}
+
+// emitAs 以指定操作者触发房间事件（调用方需持有 roomsMux）
+func (ws *WebRTCService) emitAs(eventType string, room *WebRTCRoom, client *WebRTCClient, actor Actor) {
+	ws.runHooks(newRoomEvent(eventType, room, client, actor))
+
+	switch eventType {
+	case EventRoomExpired:
+		ws.publishStatus(room, "expired")
+	case EventRoomClosed:
+		ws.publishStatus(room, "closed")
+	default:
+		ws.publishStatus(room, "")
+	}
+}
+
+// emitTransition 触发 room.state_changed 事件（调用方需持有 roomsMux）
+//
+// 转为 closed、expired 时房间随即删除，由之后的关闭、过期事件推送最终状态。
+func (ws *WebRTCService) emitTransition(room *WebRTCRoom, from RoomState, client *WebRTCClient, actor Actor) {
+	event := newRoomEvent(EventRoomStateChanged, room, client, actor)
+	event.PrevState = string(from)
+	ws.runHooks(event)
+
+	if room.State != RoomClosed && room.State != RoomExpired {
+		ws.publishStatus(room, "")
+	}
+}
+
+// newRoomEvent 生成房间事件（调用方需持有 roomsMux）
+func newRoomEvent(eventType string, room *WebRTCRoom, client *WebRTCClient, actor Actor) RoomEvent {
+	event := RoomEvent{
+		Type:      eventType,
+		Room:      room.Code,
+		RoomType:  room.Type,
+		State:     string(room.State),
+		ExpiresAt: room.ExpiresAt,
+		Creator:   room.Creator.String(),
+		Actor:     actor,
+		Time:      time.Now(),
+	}
+	if client != nil {
+		event.Role = client.Role
+		event.ClientID = client.ID
+	}
+	return event
+}
+
+// runHooks 依次调用房间事件钩子（调用方需持有 roomsMux）
+func (ws *WebRTCService) runHooks(event RoomEvent) {
+	for _, hook := range ws.hooks {
+		hook(event)
+	}
+}
+
+// roomSubscriber 房间状态订阅者
+type roomSubscriber struct {
+	ch chan models.RoomStatus
+}
+
+// SubscribeRoom 订阅房间状态变化
+//
+// 返回的通道会立即收到一次当前状态，之后每次状态变化都会收到最新状态；
+// 房间关闭或过期时会收到 Closed 为 true 的最终状态，随后通道被关闭。
+// 订阅者处理过慢时只保留最新状态。调用 cancel 取消订阅。
+func (ws *WebRTCService) SubscribeRoom(code string) (<-chan models.RoomStatus, func(), error) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	room := ws.rooms[code]
+	if room == nil {
+		return nil, nil, ErrRoomNotFound
+	}
+
+	sub := &roomSubscriber{ch: make(chan models.RoomStatus, 1)}
+	if ws.subscribers[code] == nil {
+		ws.subscribers[code] = make(map[*roomSubscriber]struct{})
+	}
+	ws.subscribers[code][sub] = struct{}{}
+	sub.ch <- roomStatus(room)
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			ws.roomsMux.Lock()
+			defer ws.roomsMux.Unlock()
+
+			if subs, ok := ws.subscribers[code]; ok {
+				if _, ok := subs[sub]; ok {
+					delete(subs, sub)
+					close(sub.ch)
+				}
+				if len(subs) == 0 {
+					delete(ws.subscribers, code)
+				}
+			}
+		})
+	}
+
+	return sub.ch, cancel, nil
+}
+
+// publishStatus 向订阅者推送房间最新状态（调用方需持有 roomsMux）
+func (ws *WebRTCService) publishStatus(room *WebRTCRoom, reason string) {
+	subs := ws.subscribers[room.Code]
+	if len(subs) == 0 {
+		return
+	}
+
+	status := roomStatus(room)
+	if reason != "" {
+		status.SenderOnline = false
+		status.ReceiverOnline = false
+		status.Closed = true
+		status.Reason = reason
+	}
+
+	for sub := range subs {
+		// 只保留最新状态：通道已满时丢弃旧状态
+		select {
+		case <-sub.ch:
+		default:
+		}
+		sub.ch <- status
+
+		if status.Closed {
+			close(sub.ch)
+		}
+	}
+	if status.Closed {
+		delete(ws.subscribers, room.Code)
+	}
+}
+
+// roomStatus 生成房间状态快照（调用方需持有 roomsMux）
+func roomStatus(room *WebRTCRoom) models.RoomStatus {
+	return models.RoomStatus{
+		Code:           room.Code,
+		Type:           room.Type,
+		State:          string(room.State),
+		StateTimes:     room.stateTimes(),
+		SenderOnline:   room.Sender != nil,
+		ReceiverOnline: room.Receiver != nil,
+		CreatedAt:      room.CreatedAt,
+		ExpiresAt:      room.ExpiresAt,
+	}
+}
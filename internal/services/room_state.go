@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// RoomState 房间生命周期状态
+type RoomState string
+
+// 房间状态
+//
+// 正常流程为 created → waiting → paired → transferring → completed，
+// 对方离开时从 paired、transferring 回到 waiting。closed（房主关闭或客户端全部离开）和
+// expired（有效期结束）是终止状态，其余状态都可以转为这两种状态。
+// 服务器只能看到信令，transferring 表示双方已经交换 answer 建立了 P2P 连接，
+// completed 由客户端发送 transfer-complete 消息确认。
+const (
+	RoomCreated      RoomState = "created"      // 已创建，还没有客户端加入
+	RoomWaiting      RoomState = "waiting"      // 一方在线，等待对方加入
+	RoomPaired       RoomState = "paired"       // 双方在线，正在协商连接
+	RoomTransferring RoomState = "transferring" // 已建立 P2P 连接，正在传输
+	RoomCompleted    RoomState = "completed"    // 传输已完成
+	RoomClosed       RoomState = "closed"       // 房主关闭或客户端全部离开
+	RoomExpired      RoomState = "expired"      // 有效期结束
+)
+
+// roomTransitions 允许的状态转换
+//
+// completed 之后双方可以继续传输；客户端进出不改变 completed 状态，房间清空时保持 completed 结束。
+var roomTransitions = map[RoomState][]RoomState{
+	RoomCreated:      {RoomWaiting, RoomClosed, RoomExpired},
+	RoomWaiting:      {RoomPaired, RoomClosed, RoomExpired},
+	RoomPaired:       {RoomWaiting, RoomTransferring, RoomClosed, RoomExpired},
+	RoomTransferring: {RoomWaiting, RoomCompleted, RoomClosed, RoomExpired},
+	RoomCompleted:    {RoomTransferring, RoomClosed, RoomExpired},
+}
+
+// canTransition 是否允许从 from 转换到 to
+func canTransition(from RoomState, to RoomState) bool {
+	for _, next := range roomTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition 转换房间状态，记录时间并触发 room.state_changed 事件（调用方需持有 roomsMux）
+//
+// 状态不变时不做任何处理，不允许的转换返回错误且不修改状态。
+func (ws *WebRTCService) transition(room *WebRTCRoom, to RoomState, client *WebRTCClient, actor Actor) error {
+	from := room.State
+	if from == to {
+		return nil
+	}
+	if !canTransition(from, to) {
+		return fmt.Errorf("房间 %s 不能从 %s 转换到 %s", room.Code, from, to)
+	}
+
+	room.State = to
+	room.StateTimes[to] = time.Now()
+	log.Printf("房间状态变化: %s %s → %s", room.Code, from, to)
+
+	ws.emitTransition(room, from, client, actor)
+	return nil
+}
+
+// updatePresence 根据在线的客户端更新房间状态（调用方需持有 roomsMux）
+//
+// 双方在线为 paired，一方在线为 waiting；传输完成后客户端进出不改变状态。
+// 房间清空时由调用方删除房间并转为 closed。
+func (ws *WebRTCService) updatePresence(room *WebRTCRoom, client *WebRTCClient) {
+	if room.State == RoomCompleted {
+		return
+	}
+
+	var to RoomState
+	switch {
+	case room.Sender != nil && room.Receiver != nil:
+		to = RoomPaired
+	case room.Sender != nil || room.Receiver != nil:
+		to = RoomWaiting
+	default:
+		return
+	}
+	if err := ws.transition(room, to, client, client.actor()); err != nil {
+		log.Printf("更新房间状态失败: %v", err)
+	}
+}
+
+// finish 房间删除前转为终止状态：已完成传输的房间保持 completed（调用方需持有 roomsMux）
+func (ws *WebRTCService) finish(room *WebRTCRoom, to RoomState, actor Actor) {
+	if to == RoomClosed && room.State == RoomCompleted {
+		return
+	}
+	if err := ws.transition(room, to, nil, actor); err != nil {
+		log.Printf("更新房间状态失败: %v", err)
+	}
+}
+
+// trackSignal 根据转发的信令推进传输状态（调用方需持有 roomsMux）
+func (ws *WebRTCService) trackSignal(room *WebRTCRoom, client *WebRTCClient, msg *WebRTCMessage) {
+	var to RoomState
+	switch msg.Type {
+	case MessageAnswer, MessagePAKEAnswer:
+		if room.State != RoomPaired && room.State != RoomCompleted {
+			return
+		}
+		to = RoomTransferring
+	case MessageTransferComplete:
+		to = RoomCompleted
+	default:
+		return
+	}
+	if err := ws.transition(room, to, client, client.actor()); err != nil {
+		log.Printf("忽略信令 %s 触发的状态变化: %v", msg.Type, err)
+	}
+}
+
+// stateTimes 各状态最近一次进入的时间
+func (room *WebRTCRoom) stateTimes() map[string]time.Time {
+	times := make(map[string]time.Time, len(room.StateTimes))
+	for state, t := range room.StateTimes {
+		times[string(state)] = t
+	}
+	return times
+}
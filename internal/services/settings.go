@@ -0,0 +1,58 @@
+package services
+
+import (
+	"net/http"
+	"net/url"
+
+	"chuan/internal/config"
+	"chuan/internal/quota"
+)
+
+// SetConfig 替换运行配置，重新加载配置时调用，cfg 需要已经通过校验
+//
+// 新的房间有效期只影响之后创建或延期的房间，已经安排的过期提醒和清理保持不变。
+func (ws *WebRTCService) SetConfig(cfg *config.Config) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	ws.cfg = cfg
+}
+
+// SetQuota 设置房间配额，之后创建的房间占用创建者的名额
+func (ws *WebRTCService) SetQuota(limiter *quota.Limiter) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	ws.quota = limiter
+}
+
+// ICEServers 浏览器建立 P2P 连接使用的 STUN/TURN 服务器
+func (ws *WebRTCService) ICEServers() []config.ICEServer {
+	ws.roomsMux.RLock()
+	defer ws.roomsMux.RUnlock()
+
+	if ws.cfg.ICEServers == nil {
+		return []config.ICEServer{}
+	}
+	return ws.cfg.ICEServers
+}
+
+// OriginAllowed 浏览器请求的来源是否允许跨域访问
+func (ws *WebRTCService) OriginAllowed(origin string) bool {
+	ws.roomsMux.RLock()
+	defer ws.roomsMux.RUnlock()
+
+	return ws.cfg.OriginAllowed(origin)
+}
+
+// checkOrigin WebSocket 握手的来源检查：非浏览器客户端和同源页面始终允许，其余按 allowed_origins 判断
+func (ws *WebRTCService) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+	return ws.OriginAllowed(origin)
+}
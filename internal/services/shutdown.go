@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"chuan/internal/apierr"
+
+	"github.com/gorilla/websocket"
+)
+
+// Drain 优雅关闭信令连接
+//
+// 先向所有在线客户端发送 server-shutdown 消息并停止创建房间，然后等待客户端自行断开，
+// 最长等待配置的 drain 时间或直到 ctx 结束，最后以 1001 (Going Away) 关闭剩余连接并结束房间状态订阅。
+// 加入已有房间仍然允许，新加入的客户端同样会收到 server-shutdown。
+func (ws *WebRTCService) Drain(ctx context.Context) {
+	ws.roomsMux.Lock()
+	ws.draining = true
+	msg := ws.shutdownMessage()
+	for _, room := range ws.rooms {
+		ws.broadcastToRoom(room, msg)
+	}
+	online := ws.onlineClientsLocked()
+	drain := ws.cfg.Shutdown.Drain.Duration
+	ws.roomsMux.Unlock()
+
+	log.Printf("开始优雅关闭: 已通知 %d 个在线客户端，最长等待 %s", online, drain)
+
+	deadline := time.NewTimer(drain)
+	defer deadline.Stop()
+	poll := time.NewTicker(200 * time.Millisecond)
+	defer poll.Stop()
+
+wait:
+	for online > 0 {
+		select {
+		case <-deadline.C:
+			break wait
+		case <-ctx.Done():
+			break wait
+		case <-poll.C:
+			ws.roomsMux.RLock()
+			online = ws.onlineClientsLocked()
+			ws.roomsMux.RUnlock()
+		}
+	}
+
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	if online = ws.onlineClientsLocked(); online > 0 {
+		log.Printf("等待结束，关闭剩余的 %d 个连接", online)
+	}
+	for _, room := range ws.rooms {
+		ws.closeConnections(room, websocket.CloseGoingAway, "server shutdown")
+	}
+	for code, subs := range ws.subscribers {
+		for sub := range subs {
+			close(sub.ch)
+		}
+		delete(ws.subscribers, code)
+	}
+}
+
+// onlineClientsLocked 在线客户端数量（调用方需持有 roomsMux）
+func (ws *WebRTCService) onlineClientsLocked() int {
+	n := 0
+	for _, room := range ws.rooms {
+		if room.Sender != nil {
+			n++
+		}
+		if room.Receiver != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// shutdownMessage 构造 server-shutdown 消息，reconnect_after 为建议客户端断开后等待的秒数
+func (ws *WebRTCService) shutdownMessage() *WebRTCMessage {
+	return &WebRTCMessage{
+		Type: MessageShutdown,
+		Payload: encodePayload(map[string]interface{}{
+			"message":         "服务器即将重启，请稍后重新连接",
+			"reconnect_after": int(ws.cfg.Shutdown.ReconnectDelay.Seconds()),
+			"drain_seconds":   int(ws.cfg.Shutdown.Drain.Seconds()),
+		}),
+	}
+}
+
+// shutdownError 优雅关闭期间拒绝创建房间的错误，Retry-After 为建议的重连等待时间
+func (ws *WebRTCService) shutdownError() error {
+	return apierr.Wrap(apierr.CodeShuttingDown, ErrShuttingDown).WithRetryAfter(ws.cfg.Shutdown.ReconnectDelay.Duration)
+}
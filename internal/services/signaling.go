@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 信令消息类型
+const (
+	MessageOffer        = "offer"
+	MessageAnswer       = "answer"
+	MessageICECandidate = "ice-candidate"
+	MessagePeerJoined   = "peer-joined"
+	MessageDisconnect   = "disconnection"
+	MessageExpiringSoon = "expiring-soon"
+	MessageRoomExpired  = "room-expired"
+	MessageRoomClosed   = "room-closed"     // 房主关闭了房间
+	MessageShutdown     = "server-shutdown" // 服务器即将关闭，负载中的 reconnect_after 为建议的重连等待秒数
+	MessageNotice       = "server-notice"   // 运营公告，负载为 message 和 level
+
+	// MessageTransferComplete 客户端确认传输已完成，服务器转发给对方并将房间转为 completed
+	MessageTransferComplete = "transfer-complete"
+	MessageError            = "error"
+
+	// 短认证字符串（SAS）校验：双方交换 DTLS 指纹并比对派生出的表情/单词
+	MessageVerifyFingerprint = "verify-fingerprint"
+	MessageVerifyConfirm     = "verify-confirm"
+	MessageVerifyReject      = "verify-reject"
+
+	// 基于取件码的 SPAKE2 密钥交换：消息内容对服务器不透明，服务器只负责转发，
+	// 因此无法冒充任何一方进行中间人攻击
+	MessagePAKEInit    = "pake-init"
+	MessagePAKEReply   = "pake-reply"
+	MessagePAKEConfirm = "pake-confirm"
+	MessagePAKEOffer   = "pake-offer"
+	MessagePAKEAnswer  = "pake-answer"
+	MessagePAKEICE     = "pake-ice"
+)
+
+// EnvelopeE2EV1 端到端加密信封格式
+//
+// 负载为 JSON 字符串，内容是 base64url(nonce || AES-256-GCM 密文)，
+// 密钥由分享链接 URL 片段（#）中的密钥派生，片段不会发送给服务器。
+const EnvelopeE2EV1 = "e2e-v1"
+
+// verifyMessagePrefix SAS 校验消息的类型前缀
+const verifyMessagePrefix = "verify-"
+
+// VerifyFingerprintPayload verify-fingerprint 消息负载
+type VerifyFingerprintPayload struct {
+	Algorithm   string `json:"algorithm"`   // 指纹算法，例如 sha-256
+	Fingerprint string `json:"fingerprint"` // SDP 中 a=fingerprint 的值
+}
+
+// VerifyResultPayload verify-confirm / verify-reject 消息负载
+type VerifyResultPayload struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// validateMessage 校验客户端发来的信令消息，服务器只检查 verify-* 消息的结构，其余类型原样转发
+//
+// 加密信封只检查格式是否为 JSON 字符串，不会解码其中的内容。
+func validateMessage(msg *WebRTCMessage) error {
+	if msg.Type == "" {
+		return fmt.Errorf("缺少消息类型")
+	}
+	if msg.Envelope != "" {
+		if msg.Envelope != EnvelopeE2EV1 {
+			return fmt.Errorf("不支持的加密信封格式: %s", msg.Envelope)
+		}
+		if strings.HasPrefix(msg.Type, verifyMessagePrefix) {
+			return fmt.Errorf("校验消息不能使用加密信封")
+		}
+		if len(msg.Payload) < 2 || msg.Payload[0] != '"' {
+			return fmt.Errorf("加密信封的负载必须是字符串")
+		}
+		return nil
+	}
+	if !strings.HasPrefix(msg.Type, verifyMessagePrefix) {
+		return nil
+	}
+
+	switch msg.Type {
+	case MessageVerifyFingerprint:
+		var payload VerifyFingerprintPayload
+		if err := decodePayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+		if payload.Algorithm == "" || payload.Fingerprint == "" {
+			return fmt.Errorf("verify-fingerprint 缺少算法或指纹")
+		}
+	case MessageVerifyConfirm, MessageVerifyReject:
+		if len(msg.Payload) > 0 && string(msg.Payload) != "null" {
+			var payload VerifyResultPayload
+			if err := decodePayload(msg.Payload, &payload); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("未知的校验消息类型: %s", msg.Type)
+	}
+	return nil
+}
+
+// decodePayload 将原始负载解码为指定结构
+func decodePayload(payload json.RawMessage, v interface{}) error {
+	if len(payload) == 0 {
+		return fmt.Errorf("消息负载为空")
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("消息负载无效: %w", err)
+	}
+	return nil
+}
+
+// encodePayload 编码服务器生成的消息负载
+func encodePayload(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// 服务器只编码内部构造的 map，不会失败
+		panic(err)
+	}
+	return data
+}
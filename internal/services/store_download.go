@@ -0,0 +1,179 @@
+package services
+
+import (
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"chuan/internal/storage"
+)
+
+// downloadIdleTimeout 中断的下载超过该时间没有继续时结束，之后使用原令牌视为新的下载
+const downloadIdleTimeout = 30 * time.Minute
+
+// byteRange 左闭右开的字节区间
+type byteRange struct {
+	start, end int64
+}
+
+// downloadSession 一次下载，可以由多个 Range 请求完成（断点续传、分段下载）
+//
+// 只计入一次下载次数；发送过的字节覆盖整个文件后下载完成。
+type downloadSession struct {
+	code     string
+	sent     []byteRange // 已发送的区间，按起点排序且互不相连
+	lastSeen time.Time
+}
+
+// idle 下载是否已中断超时
+func (d *downloadSession) idle(now time.Time) bool {
+	return now.Sub(d.lastSeen) > downloadIdleTimeout
+}
+
+// add 记录发送的区间，返回是否已发送整个文件
+func (d *downloadSession) add(r byteRange, size int64) bool {
+	merged := make([]byteRange, 0, len(d.sent)+1)
+	for _, cur := range d.sent {
+		if cur.end < r.start || r.end < cur.start {
+			merged = append(merged, cur)
+			continue
+		}
+		r.start = min(r.start, cur.start)
+		r.end = max(r.end, cur.end)
+	}
+	merged = append(merged, r)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].start < merged[j].start })
+	d.sent = merged
+	return len(merged) == 1 && merged[0].start <= 0 && merged[0].end >= size
+}
+
+// StoreDownload 打开的暂存文件，实现 io.ReadSeeker 供 http.ServeContent 发送
+//
+// 调用 Begin 之后读取的字节计入这次下载，用于判断下载是否完成。
+type StoreDownload struct {
+	Meta *storage.StoredFile
+
+	s       *StoreService
+	file    *os.File
+	pos     int64
+	token   string
+	session *downloadSession
+	once    sync.Once
+}
+
+// Begin 开始或继续一次下载，返回下载令牌
+//
+// token 为同一文件进行中的下载的令牌时继续该次下载，不计入下载次数；否则开始一次新的下载并计入
+// 下载次数，已达到上限时返回 ErrStoredFileNotFound。
+func (d *StoreDownload) Begin(token string) (string, error) {
+	s := d.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if session, ok := s.downloads[token]; ok && session.code == d.Meta.Code && !session.idle(now) {
+		session.lastSeen = now
+		d.token, d.session = token, session
+		return token, nil
+	}
+
+	// 重新读取元数据，其他请求可能已经修改了下载次数
+	meta, err := s.store.GetMeta(d.Meta.Code)
+	if err != nil {
+		return "", ErrStoredFileNotFound
+	}
+	if meta.Expired(now) || meta.Exhausted() {
+		return "", ErrStoredFileNotFound
+	}
+	meta.Downloads++
+	if err := s.store.UpdateMeta(meta); err != nil {
+		return "", err
+	}
+	log.Printf("暂存文件开始下载: %s (%d/%d)", meta.Code, meta.Downloads, meta.MaxDownloads)
+
+	d.Meta = meta
+	d.token = generateOwnerToken()
+	d.session = &downloadSession{code: meta.Code, lastSeen: now}
+	s.downloads[d.token] = d.session
+	return d.token, nil
+}
+
+// Read 读取文件内容，Begin 之后记录发送的区间
+func (d *StoreDownload) Read(p []byte) (int, error) {
+	n, err := d.file.Read(p)
+	if n > 0 && d.session != nil {
+		d.record(byteRange{d.pos, d.pos + int64(n)})
+	}
+	d.pos += int64(n)
+	return n, err
+}
+
+// Seek 移动读取位置
+func (d *StoreDownload) Seek(offset int64, whence int) (int64, error) {
+	pos, err := d.file.Seek(offset, whence)
+	if err == nil {
+		d.pos = pos
+	}
+	return pos, err
+}
+
+// record 记录发送的区间，整个文件都已发送时结束这次下载
+func (d *StoreDownload) record(r byteRange) {
+	s := d.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d.session.lastSeen = time.Now()
+	if d.session.add(r, d.Meta.Size) {
+		delete(s.downloads, d.token)
+	}
+}
+
+// Close 关闭文件；没有其他请求在读取、也没有进行中的下载时，删除下载次数已用完的文件
+func (d *StoreDownload) Close() error {
+	var err error
+	d.once.Do(func() {
+		err = d.file.Close()
+
+		s := d.s
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		code := d.Meta.Code
+		s.readers[code]--
+		if s.readers[code] > 0 {
+			return
+		}
+		delete(s.readers, code)
+		if s.downloadingLocked(code, time.Now()) {
+			return
+		}
+		meta, metaErr := s.store.GetMeta(code)
+		if metaErr == nil && meta.Exhausted() {
+			s.deleteLocked(meta)
+			log.Printf("暂存文件已达到下载次数上限，已删除: %s", code)
+		}
+	})
+	return err
+}
+
+// downloadingLocked 文件是否有未完成且未超时的下载（调用方需持有 mu）
+func (s *StoreService) downloadingLocked(code string, now time.Time) bool {
+	for _, session := range s.downloads {
+		if session.code == code && !session.idle(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// endDownloadsLocked 结束文件的所有下载（调用方需持有 mu）
+func (s *StoreService) endDownloadsLocked(code string) {
+	for token, session := range s.downloads {
+		if session.code == code {
+			delete(s.downloads, token)
+		}
+	}
+}
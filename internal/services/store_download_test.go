@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"chuan/internal/config"
+	"chuan/internal/storage"
+)
+
+func newTestStore(t *testing.T, maxDownloads int) (*StoreService, string) {
+	t.Helper()
+	cfg := config.Default().Store
+	fileStore, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStoreService(cfg, fileStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := s.Upload(strings.NewReader("0123456789"), StoreUploadOptions{Size: 10, MaxDownloads: maxDownloads})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, upload.File.Code
+}
+
+// readRange 按 http.ServeContent 的方式读取 [start, start+n)
+func readRange(t *testing.T, d *StoreDownload, start, n int64) string {
+	t.Helper()
+	if _, err := d.Seek(start, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d, buf); err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+// 不带令牌的分段请求各自计为一次下载，不能绕过下载次数限制
+func TestStoreDownloadPartialRequestsCounted(t *testing.T) {
+	s, code := newTestStore(t, 1)
+
+	d, err := s.Open(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Begin(""); err != nil {
+		t.Fatal(err)
+	}
+	readRange(t, d, 0, 5)
+	d.Close()
+
+	d, err = s.Open(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Begin(""); !errors.Is(err, ErrStoredFileNotFound) {
+		t.Fatalf("下载次数用完后不带令牌的请求应被拒绝，得到 %v", err)
+	}
+	d.Close()
+}
+
+// 带令牌的续传不重复计数，整个文件发送完后下载结束，次数用完的文件被删除
+func TestStoreDownloadResumeWithToken(t *testing.T) {
+	s, code := newTestStore(t, 1)
+
+	d, err := s.Open(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := d.Begin("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readRange(t, d, 6, 4)
+	d.Close()
+
+	// 下载未完成，文件保留用于续传
+	if _, err := s.Info(code); err != nil {
+		t.Fatalf("下载未完成时文件不应删除: %v", err)
+	}
+
+	d, err = s.Open(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Begin("other-token"); !errors.Is(err, ErrStoredFileNotFound) {
+		t.Fatalf("无效令牌应视为新的下载并被拒绝，得到 %v", err)
+	}
+	resumed, err := d.Begin(token)
+	if err != nil || resumed != token {
+		t.Fatalf("续传失败: %q, %v", resumed, err)
+	}
+	got = readRange(t, d, 0, 6) + got
+	if d.Meta.Downloads != 1 {
+		t.Fatalf("下载次数 %d", d.Meta.Downloads)
+	}
+	d.Close()
+
+	if got != "0123456789" {
+		t.Fatalf("内容 %q", got)
+	}
+	if _, err := s.Info(code); !errors.Is(err, ErrStoredFileNotFound) {
+		t.Fatalf("下载完成后次数用完的文件应删除，得到 %v", err)
+	}
+}
+
+func TestDownloadSessionCoverage(t *testing.T) {
+	var session downloadSession
+	for _, c := range []struct {
+		r    byteRange
+		done bool
+	}{
+		{byteRange{4, 6}, false},
+		{byteRange{0, 2}, false},
+		{byteRange{8, 10}, false},
+		{byteRange{2, 4}, false},
+		{byteRange{5, 9}, true},
+	} {
+		if done := session.add(c.r, 10); done != c.done {
+			t.Fatalf("add(%v) = %v，已发送 %v", c.r, done, session.sent)
+		}
+	}
+}
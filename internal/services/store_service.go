@@ -0,0 +1,298 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"chuan/internal/config"
+	"chuan/internal/storage"
+)
+
+var (
+	// ErrStoredFileNotFound 暂存文件不存在、已过期或已达到下载次数上限
+	ErrStoredFileNotFound = errors.New("文件不存在或已过期")
+	// ErrStoredFileTooLarge 文件超过单个文件大小限制
+	ErrStoredFileTooLarge = errors.New("文件超过大小限制")
+	// ErrStoreQuotaExceeded 服务器暂存空间不足
+	ErrStoreQuotaExceeded = errors.New("服务器暂存空间不足")
+	// ErrStoredFileEmpty 上传内容为空
+	ErrStoredFileEmpty = errors.New("上传内容不能为空")
+)
+
+// StoreService 暂存转发文件服务
+//
+// 文件内容在客户端加密，服务器只负责保存密文、限制大小与次数并在过期后删除。
+type StoreService struct {
+	cfg   config.StoreConfig
+	store *storage.FileStore
+
+	mu        sync.Mutex
+	usedBytes int64                       // 已占用（含上传中预留）的字节数
+	readers   map[string]int              // 正在下载的文件，删除需等待下载结束
+	downloads map[string]*downloadSession // 进行中的下载，按下载令牌索引
+}
+
+// StoreUploadOptions 上传参数
+type StoreUploadOptions struct {
+	Size         int64         // 客户端声明的大小（Content-Length），-1 表示未知
+	Meta         string        // 客户端加密后的文件信息
+	TTL          time.Duration // 有效期，0 表示使用默认值
+	MaxDownloads int           // 最大下载次数，0 表示使用默认值
+}
+
+// StoredUpload 上传结果
+type StoredUpload struct {
+	File       *storage.StoredFile
+	OwnerToken string
+}
+
+// NewStoreService 创建暂存转发文件服务，统计已有文件占用的空间并启动清理任务
+func NewStoreService(cfg config.StoreConfig, store *storage.FileStore) (*StoreService, error) {
+	service := &StoreService{
+		cfg:       cfg,
+		store:     store,
+		readers:   make(map[string]int),
+		downloads: make(map[string]*downloadSession),
+	}
+
+	files, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("读取暂存文件失败: %w", err)
+	}
+	for _, file := range files {
+		service.usedBytes += file.Size
+	}
+	log.Printf("暂存文件模式已启用: %d 个文件, 占用 %d 字节", len(files), service.usedBytes)
+
+	go service.cleanupExpired()
+
+	return service, nil
+}
+
+// Upload 保存上传的密文
+func (s *StoreService) Upload(r io.Reader, opts StoreUploadOptions) (*StoredUpload, error) {
+	if opts.Size == 0 {
+		return nil, ErrStoredFileEmpty
+	}
+	if opts.Size > s.cfg.MaxFileSize {
+		return nil, ErrStoredFileTooLarge
+	}
+
+	// 大小已知时只预留实际大小，否则按单文件上限预留
+	reserve := opts.Size
+	if reserve < 0 {
+		reserve = s.cfg.MaxFileSize
+	}
+	if !s.reserve(reserve) {
+		return nil, ErrStoreQuotaExceeded
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = s.cfg.DefaultTTL.Duration
+	}
+	if ttl > s.cfg.MaxTTL.Duration {
+		ttl = s.cfg.MaxTTL.Duration
+	}
+	maxDownloads := opts.MaxDownloads
+	if maxDownloads <= 0 {
+		maxDownloads = s.cfg.DefaultDownload
+	}
+	if maxDownloads > s.cfg.MaxDownloads {
+		maxDownloads = s.cfg.MaxDownloads
+	}
+
+	token := generateOwnerToken()
+	now := time.Now()
+	meta := &storage.StoredFile{
+		Meta:           opts.Meta,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(ttl),
+		MaxDownloads:   maxDownloads,
+		OwnerTokenHash: hashToken(token),
+	}
+
+	limit := reserve
+	for attempt := 0; ; attempt++ {
+		meta.Code = generateStoreCode()
+		size, err := s.store.Create(meta, r, limit)
+		if err == nil {
+			s.release(reserve - size)
+			log.Printf("暂存文件上传完成: %s (%d 字节, 有效期 %s, 最大下载次数 %d)", meta.Code, size, ttl, maxDownloads)
+			break
+		}
+		if errors.Is(err, storage.ErrExists) && attempt < 10 {
+			continue
+		}
+
+		s.release(reserve)
+		switch {
+		case errors.Is(err, storage.ErrTooLarge):
+			return nil, ErrStoredFileTooLarge
+		case errors.Is(err, storage.ErrExists):
+			return nil, fmt.Errorf("生成文件取件码失败")
+		default:
+			return nil, fmt.Errorf("保存文件失败: %w", err)
+		}
+	}
+
+	if meta.Size == 0 {
+		s.store.Delete(meta.Code)
+		return nil, ErrStoredFileEmpty
+	}
+
+	return &StoredUpload{File: meta, OwnerToken: token}, nil
+}
+
+// Info 读取文件元数据
+func (s *StoreService) Info(code string) (*storage.StoredFile, error) {
+	meta, err := s.store.GetMeta(code)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrStoredFileNotFound
+		}
+		return nil, err
+	}
+	if meta.Expired(time.Now()) {
+		return nil, ErrStoredFileNotFound
+	}
+	return meta, nil
+}
+
+// Open 打开文件用于下载或读取响应头，结束后必须调用 Close
+//
+// 打开文件不计入下载次数，发送文件内容前需要调用 Begin。
+func (s *StoreService) Open(code string) (*StoreDownload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, meta, err := s.store.Open(code)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrStoredFileNotFound
+		}
+		return nil, err
+	}
+	if meta.Expired(time.Now()) {
+		file.Close()
+		return nil, ErrStoredFileNotFound
+	}
+
+	s.readers[code]++
+	return &StoreDownload{Meta: meta, s: s, file: file}, nil
+}
+
+// Delete 使用房主令牌删除文件
+func (s *StoreService) Delete(code string, ownerToken string) error {
+	meta, err := s.Info(code)
+	if err != nil {
+		return err
+	}
+	if ownerToken == "" || subtle.ConstantTimeCompare([]byte(hashToken(ownerToken)), []byte(meta.OwnerTokenHash)) != 1 {
+		return ErrInvalidOwnerToken
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 正在下载时立即让文件不可见，下载结束或清理任务会删除残留
+	meta.ExpiresAt = time.Now()
+	s.endDownloadsLocked(code)
+	if s.readers[code] > 0 {
+		return s.store.UpdateMeta(meta)
+	}
+	return s.deleteLocked(meta)
+}
+
+// deleteLocked 删除文件并释放占用的空间（调用方需持有 mu）
+func (s *StoreService) deleteLocked(meta *storage.StoredFile) error {
+	if err := s.store.Delete(meta.Code); err != nil {
+		log.Printf("删除暂存文件失败 %s: %v", meta.Code, err)
+		return err
+	}
+	s.usedBytes -= meta.Size
+	if s.usedBytes < 0 {
+		s.usedBytes = 0
+	}
+	return nil
+}
+
+// reserve 预留存储空间
+func (s *StoreService) reserve(size int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usedBytes+size > s.cfg.MaxTotalSize {
+		return false
+	}
+	s.usedBytes += size
+	return true
+}
+
+// release 释放预留的存储空间
+func (s *StoreService) release(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.usedBytes -= size
+}
+
+// cleanupExpired 定期清理过期或下载次数已用完的文件
+func (s *StoreService) cleanupExpired() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		files, err := s.store.List()
+		if err != nil {
+			log.Printf("读取暂存文件失败: %v", err)
+			continue
+		}
+
+		now := time.Now()
+		s.mu.Lock()
+		for token, session := range s.downloads {
+			if session.idle(now) {
+				delete(s.downloads, token)
+			}
+		}
+		for _, meta := range files {
+			if s.readers[meta.Code] > 0 {
+				continue
+			}
+			// 下载次数已用完的文件保留到最后一次下载完成或中断超时
+			if meta.Expired(now) || (meta.Exhausted() && !s.downloadingLocked(meta.Code, now)) {
+				s.endDownloadsLocked(meta.Code)
+				if s.deleteLocked(meta) == nil {
+					log.Printf("清理过期暂存文件: %s", meta.Code)
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// hashToken 计算令牌的 SHA-256，磁盘上只保存哈希
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateStoreCode 生成文件取件码
+//
+// 下载不需要其他凭证，取件码必须无法枚举，因此使用 128 位随机数而不是房间那样的6位数字。
+func generateStoreCode() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成文件取件码失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
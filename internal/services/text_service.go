@@ -0,0 +1,226 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"chuan/internal/config"
+	"chuan/internal/quota"
+	"chuan/internal/storage"
+)
+
+var (
+	// ErrTextNotFound 文本不存在、已过期或已达到查看次数上限
+	ErrTextNotFound = errors.New("文本不存在或已过期")
+	// ErrTextEmpty 文本内容为空
+	ErrTextEmpty = errors.New("文本内容不能为空")
+	// ErrTextTooLarge 文本超过大小限制
+	ErrTextTooLarge = errors.New("文本内容超过大小限制")
+)
+
+// TextService 文本片段服务：负责生成取件码、过期和查看次数控制
+type TextService struct {
+	cfg   config.TextConfig
+	store storage.TextStore
+
+	// 查看操作需要读取后更新计数，用同一把锁保证次数准确
+	mu    sync.Mutex
+	quota *quota.Limiter // 房间配额，为 nil 时不限制，由 mu 保护
+
+	lookups *lookupLimiter // 按 IP 限制取件码错误次数
+}
+
+// CreateTextOptions 创建文本片段的参数
+type CreateTextOptions struct {
+	TTL      time.Duration // 有效期，0 表示使用默认值
+	MaxViews int           // 最大查看次数，0 表示不限，1 表示阅后即焚
+	Creator  Actor         // 创建者，用于统计房间配额
+}
+
+// NewTextService 创建文本片段服务并启动过期清理任务
+func NewTextService(cfg config.TextConfig, store storage.TextStore) *TextService {
+	service := &TextService{
+		cfg:     cfg,
+		store:   store,
+		lookups: newLookupLimiter(),
+	}
+
+	go service.cleanupExpired()
+
+	return service
+}
+
+// SetQuota 设置房间配额，之后创建的文本片段与 WebRTC 房间一样占用创建者的房间名额
+func (s *TextService) SetQuota(limiter *quota.Limiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quota = limiter
+}
+
+// MaxSize 单个文本的最大字节数
+func (s *TextService) MaxSize() int {
+	return s.cfg.MaxSize
+}
+
+// MaxRequestSize 创建文本的请求体最大字节数，JSON 转义可能使请求体大于文本本身，预留足够余量
+func (s *TextService) MaxRequestSize() int64 {
+	return int64(s.cfg.MaxSize)*6 + 4096
+}
+
+// Create 保存文本并返回生成的取件码，超出创建者的房间配额时返回 *quota.ExceededError
+func (s *TextService) Create(content string, opts CreateTextOptions) (*storage.TextSnippet, error) {
+	if content == "" {
+		return nil, ErrTextEmpty
+	}
+	if len(content) > s.cfg.MaxSize {
+		return nil, ErrTextTooLarge
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = s.cfg.DefaultTTL.Duration
+	}
+	if ttl > s.cfg.MaxTTL.Duration {
+		ttl = s.cfg.MaxTTL.Duration
+	}
+	maxViews := opts.MaxViews
+	if maxViews < 0 {
+		maxViews = 0
+	}
+
+	now := time.Now()
+	snippet := &storage.TextSnippet{
+		Content:   content,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		MaxViews:  maxViews,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 取件码冲突时重新生成
+	for attempt := 0; attempt < 10; attempt++ {
+		snippet.Code = generateTextCode()
+		err := s.store.Create(snippet)
+		if err == nil {
+			if err := s.reserveLocked(snippet.Code, opts.Creator); err != nil {
+				s.store.Delete(snippet.Code)
+				return nil, err
+			}
+			log.Printf("创建文本片段: %s (%d 字节, 有效期 %s, 最大查看次数 %d)", snippet.Code, len(content), ttl, maxViews)
+			return snippet, nil
+		}
+		if !errors.Is(err, storage.ErrExists) {
+			return nil, fmt.Errorf("保存文本失败: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("生成文本取件码失败")
+}
+
+// View 读取文本并增加查看次数，达到查看次数上限后删除
+//
+// ip 为查看者的 IP，同一 IP 取件码错误次数过多时返回 *LookupLimitError。
+func (s *TextService) View(code, ip string) (*storage.TextSnippet, error) {
+	now := time.Now()
+	if err := s.lookups.check(ip, now); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snippet, err := s.store.Get(code)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			s.lookups.fail(ip, now)
+			return nil, ErrTextNotFound
+		}
+		return nil, err
+	}
+
+	if snippet.Expired(now) {
+		s.store.Delete(code)
+		s.releaseLocked(code)
+		s.lookups.fail(ip, now)
+		return nil, ErrTextNotFound
+	}
+
+	snippet.Views++
+	if snippet.MaxViews > 0 && snippet.Views >= snippet.MaxViews {
+		if err := s.store.Delete(code); err != nil {
+			return nil, err
+		}
+		s.releaseLocked(code)
+		log.Printf("文本片段已达到查看次数上限，已删除: %s", code)
+		return snippet, nil
+	}
+
+	if err := s.store.Update(snippet); err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// cleanupExpired 定期清理过期文本
+func (s *TextService) cleanupExpired() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		codes, err := s.store.DeleteExpired(time.Now())
+		for _, code := range codes {
+			s.releaseLocked(code)
+		}
+		s.mu.Unlock()
+		s.lookups.cleanup(time.Now())
+
+		if err != nil {
+			log.Printf("清理过期文本失败: %v", err)
+			continue
+		}
+		if len(codes) > 0 {
+			log.Printf("清理过期文本: %d 条", len(codes))
+		}
+	}
+}
+
+// textQuotaKey 文本片段在配额中的房间标识，与 WebRTC 房间码区分
+func textQuotaKey(code string) string {
+	return "text:" + code
+}
+
+// reserveLocked 为文本片段占用创建者的房间名额（调用方需持有 mu）
+//
+// 文本片段不计入服务器的房间总数，只受每个主体的并发和每小时配额限制。
+func (s *TextService) reserveLocked(code string, creator Actor) error {
+	if s.quota == nil {
+		return nil
+	}
+	return s.quota.ReserveRoom(textQuotaKey(code), quota.Subject(creator.Identity, creator.IP), 0)
+}
+
+// releaseLocked 文本片段删除后归还名额（调用方需持有 mu）
+func (s *TextService) releaseLocked(code string) {
+	if s.quota != nil {
+		s.quota.ReleaseRoom(textQuotaKey(code))
+	}
+}
+
+// generateTextCode 生成文本取件码
+//
+// 文本以明文保存且读取后可能被删除，取件码必须无法枚举，因此使用 128 位随机数。
+func generateTextCode() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成文本取件码失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
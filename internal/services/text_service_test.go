@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"chuan/internal/config"
+	"chuan/internal/storage"
+)
+
+func TestTextCodeIsNotEnumerable(t *testing.T) {
+	s := NewTextService(config.Default().Text, storage.NewMemoryTextStore())
+	snippet, err := s.Create("hello", CreateTextOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snippet.Code) != 32 {
+		t.Fatalf("取件码 %q 应为 32 位十六进制", snippet.Code)
+	}
+}
+
+func TestTextViewLimitsFailedLookupsPerIP(t *testing.T) {
+	s := NewTextService(config.Default().Text, storage.NewMemoryTextStore())
+	snippet, err := s.Create("hello", CreateTextOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < lookupFailureLimit; i++ {
+		if _, err := s.View("000000", "192.0.2.1"); !errors.Is(err, ErrTextNotFound) {
+			t.Fatalf("第 %d 次查找: got %v, want ErrTextNotFound", i+1, err)
+		}
+	}
+
+	// 超过上限后即使取件码正确也拒绝，文本不会被读取
+	var limitErr *LookupLimitError
+	_, err = s.View(snippet.Code, "192.0.2.1")
+	if !errors.As(err, &limitErr) || limitErr.RetryAfter <= 0 {
+		t.Fatalf("got %v, want *LookupLimitError", err)
+	}
+	if e := ToAPIError(err); e.Status != 429 {
+		t.Fatalf("status = %d, want 429", e.Status)
+	}
+
+	// 其他 IP 不受影响
+	got, err := s.View(snippet.Code, "192.0.2.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Views != 1 {
+		t.Fatalf("views = %d, want 1", got.Views)
+	}
+}
+
+// 磁盘存储中格式无效的取件码按不存在处理，同样计入查找失败次数
+func TestTextViewInvalidCodeOnDisk(t *testing.T) {
+	store, err := storage.NewDiskTextStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTextService(config.Default().Text, store)
+
+	for i := 0; i < lookupFailureLimit; i++ {
+		_, err := s.View("../secret", "192.0.2.1")
+		if !errors.Is(err, ErrTextNotFound) {
+			t.Fatalf("第 %d 次查找: got %v, want ErrTextNotFound", i+1, err)
+		}
+		if e := ToAPIError(err); e.Status != 404 {
+			t.Fatalf("status = %d, want 404", e.Status)
+		}
+	}
+	var limitErr *LookupLimitError
+	if _, err := s.View("a.b", "192.0.2.1"); !errors.As(err, &limitErr) {
+		t.Fatalf("got %v, want *LookupLimitError", err)
+	}
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"chuan/internal/config"
+)
+
+var (
+	// ErrTusNotFound 上传不存在或已过期
+	ErrTusNotFound = errors.New("上传不存在或已过期")
+	// ErrTusTooLarge 上传超过大小限制
+	ErrTusTooLarge = errors.New("上传超过大小限制")
+	// ErrTusOffsetMismatch 客户端的偏移量与服务器不一致
+	ErrTusOffsetMismatch = errors.New("上传偏移量不匹配")
+	// ErrTusChecksumMismatch 数据块校验和不匹配，本次写入已丢弃
+	ErrTusChecksumMismatch = errors.New("校验和不匹配")
+	// ErrTusBusy 同一个上传正在被另一个请求写入
+	ErrTusBusy = errors.New("上传正在进行中")
+	// ErrTusIncomplete 上传尚未完成
+	ErrTusIncomplete = errors.New("上传尚未完成")
+)
+
+// tusIDPattern 房间码和上传ID只允许字母、数字、下划线和短横线，防止路径穿越
+var tusIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// TusUpload 一个可续传的上传
+type TusUpload struct {
+	ID        string    `json:"id"`
+	Room      string    `json:"room"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	Metadata  string    `json:"metadata,omitempty"` // 原始 Upload-Metadata 头
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// RoomCreatedAt 所属房间的创建时间，房间码被新房间重复使用时旧房间的上传不可访问
+	RoomCreatedAt time.Time `json:"room_created_at"`
+}
+
+// Complete 是否已上传完成
+func (u *TusUpload) Complete() bool {
+	return u.Offset >= u.Length
+}
+
+// TusChecksum 数据块校验和
+type TusChecksum struct {
+	Hash     hash.Hash
+	Expected []byte
+}
+
+// TusService 与房间绑定的断点续传上传存储，文件保存在 <dir>/<room>/<id>.bin
+type TusService struct {
+	cfg   config.TusConfig
+	rooms *WebRTCService
+
+	mu   sync.Mutex
+	busy map[string]bool // 正在写入的上传
+}
+
+// NewTusService 创建断点续传上传服务并启动过期清理任务
+func NewTusService(cfg config.TusConfig, rooms *WebRTCService) (*TusService, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("创建上传目录失败: %w", err)
+	}
+
+	service := &TusService{
+		cfg:   cfg,
+		rooms: rooms,
+		busy:  make(map[string]bool),
+	}
+
+	go service.cleanupExpired()
+
+	return service, nil
+}
+
+// MaxSize 单个上传的最大字节数
+func (s *TusService) MaxSize() int64 {
+	return s.cfg.MaxSize
+}
+
+// Create 在房间下创建新的上传，过期时间与房间一致
+func (s *TusService) Create(room string, length int64, metadata string) (*TusUpload, error) {
+	if !tusIDPattern.MatchString(room) {
+		return nil, ErrRoomNotFound
+	}
+	if length < 0 || length > s.cfg.MaxSize {
+		return nil, ErrTusTooLarge
+	}
+
+	roomCreatedAt, expiresAt, err := s.rooms.roomLifetime(room)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &TusUpload{
+		ID:            randomUploadID(),
+		Room:          room,
+		Length:        length,
+		Metadata:      metadata,
+		CreatedAt:     time.Now(),
+		ExpiresAt:     expiresAt,
+		RoomCreatedAt: roomCreatedAt,
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.cfg.Dir, room), 0o700); err != nil {
+		return nil, err
+	}
+	dataPath, _ := s.paths(room, upload.ID)
+	file, err := os.OpenFile(dataPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	if err := s.saveInfo(upload); err != nil {
+		os.Remove(dataPath)
+		return nil, err
+	}
+
+	log.Printf("创建断点续传上传: 房间=%s, ID=%s, 大小=%d", room, upload.ID, length)
+	return upload, nil
+}
+
+// Get 读取上传信息，上传已过期或所属的房间已经结束时返回 ErrTusNotFound
+func (s *TusService) Get(room string, id string) (*TusUpload, error) {
+	if !tusIDPattern.MatchString(room) || !tusIDPattern.MatchString(id) {
+		return nil, ErrTusNotFound
+	}
+	upload, err := s.loadInfo(room, id)
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(upload.ExpiresAt) {
+		return nil, ErrTusNotFound
+	}
+	if createdAt, _, err := s.rooms.roomLifetime(room); err != nil || !createdAt.Equal(upload.RoomCreatedAt) {
+		return nil, ErrTusNotFound
+	}
+	return upload, nil
+}
+
+// Append 从 offset 处追加数据
+//
+// 提供校验和时，数据全部写入后才校验，不匹配则截断回原偏移量；
+// 未提供校验和时，连接中断前已写入的数据会被保留，客户端可以从新的偏移量继续。
+func (s *TusService) Append(room string, id string, offset int64, r io.Reader, checksum *TusChecksum) (*TusUpload, error) {
+	key := room + "/" + id
+	if !s.acquire(key) {
+		return nil, ErrTusBusy
+	}
+	defer s.release(key)
+
+	upload, err := s.Get(room, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return upload, ErrTusOffsetMismatch
+	}
+
+	dataPath, _ := s.paths(room, id)
+	file, err := os.OpenFile(dataPath, os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var w io.Writer = file
+	if checksum != nil {
+		w = io.MultiWriter(file, checksum.Hash)
+	}
+	written, copyErr := io.Copy(w, io.LimitReader(r, upload.Length-offset))
+
+	if checksum != nil {
+		if copyErr != nil || !bytes.Equal(checksum.Hash.Sum(nil), checksum.Expected) {
+			file.Truncate(offset)
+			if copyErr != nil {
+				return upload, copyErr
+			}
+			return upload, ErrTusChecksumMismatch
+		}
+	}
+
+	upload.Offset += written
+	if _, expiresAt, err := s.rooms.roomLifetime(room); err == nil {
+		// 房间延期后上传也随之延期
+		upload.ExpiresAt = expiresAt
+	}
+	if err := s.saveInfo(upload); err != nil {
+		return nil, err
+	}
+	if upload.Complete() {
+		log.Printf("断点续传上传完成: 房间=%s, ID=%s, 大小=%d", room, id, upload.Length)
+	}
+
+	return upload, copyErr
+}
+
+// Open 打开已完成的上传用于下载
+func (s *TusService) Open(room string, id string) (*os.File, *TusUpload, error) {
+	upload, err := s.Get(room, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !upload.Complete() {
+		return nil, upload, ErrTusIncomplete
+	}
+	dataPath, _ := s.paths(room, id)
+	file, err := os.Open(dataPath)
+	if err != nil {
+		return nil, nil, ErrTusNotFound
+	}
+	return file, upload, nil
+}
+
+// Terminate 删除上传
+func (s *TusService) Terminate(room string, id string) error {
+	if _, err := s.Get(room, id); err != nil {
+		return err
+	}
+	dataPath, infoPath := s.paths(room, id)
+	os.Remove(infoPath)
+	if err := os.Remove(dataPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	log.Printf("删除断点续传上传: 房间=%s, ID=%s", room, id)
+	return nil
+}
+
+// DeleteRoom 删除房间结束（关闭或过期）时已存在的上传
+//
+// 房间码可能马上被新房间使用，只删除 endedAt 之前创建的上传，新房间的上传不受影响。
+func (s *TusService) DeleteRoom(room string, endedAt time.Time) {
+	if !tusIDPattern.MatchString(room) {
+		return
+	}
+	if n := s.sweepRoom(room, func(upload *TusUpload) bool {
+		return !upload.CreatedAt.After(endedAt)
+	}); n > 0 {
+		log.Printf("已删除房间的断点续传上传: %s (%d 个)", room, n)
+	}
+}
+
+// cleanupExpired 定期清理过期的上传
+func (s *TusService) cleanupExpired() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.removeExpired(time.Now())
+	}
+}
+
+// removeExpired 删除所有过期的上传
+func (s *TusService) removeExpired(now time.Time) {
+	rooms, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		log.Printf("读取上传目录失败: %v", err)
+		return
+	}
+	for _, roomDir := range rooms {
+		if !roomDir.IsDir() || !tusIDPattern.MatchString(roomDir.Name()) {
+			continue
+		}
+		room := roomDir.Name()
+		if n := s.sweepRoom(room, func(upload *TusUpload) bool {
+			return !now.Before(upload.ExpiresAt)
+		}); n > 0 {
+			log.Printf("清理过期断点续传上传: 房间=%s, %d 个", room, n)
+		}
+	}
+}
+
+// sweepRoom 删除房间目录下 remove 返回 true 的上传（信息无法读取的上传也删除），
+// 正在写入的上传跳过；目录为空时删除目录。返回删除的数量
+func (s *TusService) sweepRoom(room string, remove func(upload *TusUpload) bool) int {
+	dir := filepath.Join(s.cfg.Dir, room)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	removed, remaining := 0, 0
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		upload, err := s.loadInfo(room, id)
+		if err == nil && !remove(upload) {
+			remaining++
+			continue
+		}
+		key := room + "/" + id
+		if !s.acquire(key) {
+			remaining++
+			continue
+		}
+		dataPath, infoPath := s.paths(room, id)
+		os.Remove(dataPath)
+		os.Remove(infoPath)
+		s.release(key)
+		removed++
+	}
+	if remaining == 0 {
+		os.Remove(dir)
+	}
+	return removed
+}
+
+func (s *TusService) acquire(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.busy[key] {
+		return false
+	}
+	s.busy[key] = true
+	return true
+}
+
+func (s *TusService) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.busy, key)
+}
+
+func (s *TusService) paths(room string, id string) (string, string) {
+	base := filepath.Join(s.cfg.Dir, room, id)
+	return base + ".bin", base + ".json"
+}
+
+func (s *TusService) loadInfo(room string, id string) (*TusUpload, error) {
+	_, infoPath := s.paths(room, id)
+	data, err := os.ReadFile(infoPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrTusNotFound
+		}
+		return nil, err
+	}
+	var upload TusUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (s *TusService) saveInfo(upload *TusUpload) error {
+	_, infoPath := s.paths(upload.Room, upload.ID)
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := infoPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, infoPath)
+}
+
+func randomUploadID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成上传ID失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"crypto/sha1"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"chuan/internal/config"
+)
+
+func newTestTus(t *testing.T) (*TusService, *WebRTCService, CreatedRoom) {
+	t.Helper()
+	ws := NewWebRTCService(config.Default())
+	room, err := ws.CreateNewRoom(CreateRoomOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewTusService(config.TusConfig{Enabled: true, Dir: t.TempDir(), MaxSize: 1 << 20}, ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, ws, room
+}
+
+func TestTusRoundTrip(t *testing.T) {
+	s, _, room := newTestTus(t)
+
+	upload, err := s.Create(room.Code, 10, "filename ZmlsZQ==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !upload.ExpiresAt.Equal(room.ExpiresAt) {
+		t.Fatalf("上传过期时间 %s，期望与房间一致 %s", upload.ExpiresAt, room.ExpiresAt)
+	}
+
+	if upload, err = s.Append(room.Code, upload.ID, 0, strings.NewReader("01234"), nil); err != nil || upload.Offset != 5 {
+		t.Fatalf("第一次追加: offset=%d, err=%v", upload.Offset, err)
+	}
+	if _, err := s.Append(room.Code, upload.ID, 0, strings.NewReader("01234"), nil); !errors.Is(err, ErrTusOffsetMismatch) {
+		t.Fatalf("偏移量不匹配: got %v", err)
+	}
+
+	// 校验和不匹配时丢弃本次写入
+	bad := &TusChecksum{Hash: sha1.New(), Expected: make([]byte, sha1.Size)}
+	if _, err := s.Append(room.Code, upload.ID, 5, strings.NewReader("xxxxx"), bad); !errors.Is(err, ErrTusChecksumMismatch) {
+		t.Fatalf("校验和不匹配: got %v", err)
+	}
+	if _, _, err := s.Open(room.Code, upload.ID); !errors.Is(err, ErrTusIncomplete) {
+		t.Fatalf("未完成的上传: got %v", err)
+	}
+
+	sum := sha1.Sum([]byte("56789"))
+	good := &TusChecksum{Hash: sha1.New(), Expected: sum[:]}
+	if upload, err = s.Append(room.Code, upload.ID, 5, strings.NewReader("56789"), good); err != nil || !upload.Complete() {
+		t.Fatalf("第二次追加: offset=%d, err=%v", upload.Offset, err)
+	}
+
+	head, err := s.Get(room.Code, upload.ID)
+	if err != nil || head.Offset != 10 || head.Length != 10 || head.Metadata != "filename ZmlsZQ==" {
+		t.Fatalf("查询上传: %+v, %v", head, err)
+	}
+
+	file, _, err := s.Open(room.Code, upload.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	data, _ := io.ReadAll(file)
+	if string(data) != "0123456789" {
+		t.Fatalf("下载内容 %q", data)
+	}
+}
+
+func TestTusCreateRequiresRoom(t *testing.T) {
+	s, _, _ := newTestTus(t)
+	if _, err := s.Create("999999", 10, ""); !errors.Is(err, ErrRoomNotFound) {
+		t.Fatalf("got %v, want ErrRoomNotFound", err)
+	}
+	if _, err := s.Create("../x", 10, ""); !errors.Is(err, ErrRoomNotFound) {
+		t.Fatalf("got %v, want ErrRoomNotFound", err)
+	}
+}
+
+// 房间关闭后上传被删除；房间码被新房间重复使用时看不到旧房间的上传，删除旧上传也不影响新房间
+func TestTusDeleteRoomOnClose(t *testing.T) {
+	s, ws, room := newTestTus(t)
+
+	old, err := s.Create(room.Code, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.CloseRoom(room.Code, room.OwnerToken, Actor{}); err != nil {
+		t.Fatal(err)
+	}
+	closedAt := time.Now()
+
+	if _, err := s.Get(room.Code, old.ID); !errors.Is(err, ErrTusNotFound) {
+		t.Fatalf("房间关闭后仍能访问上传: %v", err)
+	}
+
+	// 发送方用同一房间码创建新房间
+	client, err := ws.Join(room.Code, RoleSender, "", &recordingConn{}, PeerInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Leave(client)
+	if _, err := s.Get(room.Code, old.ID); !errors.Is(err, ErrTusNotFound) {
+		t.Fatalf("新房间可以访问旧房间的上传: %v", err)
+	}
+	current, err := s.Create(room.Code, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.DeleteRoom(room.Code, closedAt)
+
+	dataPath, infoPath := s.paths(room.Code, old.ID)
+	for _, path := range []string{dataPath, infoPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("旧房间的上传没有删除: %s", path)
+		}
+	}
+	if _, err := s.Get(room.Code, current.ID); err != nil {
+		t.Fatalf("新房间的上传被删除: %v", err)
+	}
+}
+
+func TestTusRemoveExpired(t *testing.T) {
+	s, _, room := newTestTus(t)
+
+	expired, err := s.Create(room.Code, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	if err := s.saveInfo(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	s.removeExpired(time.Now())
+
+	if _, err := os.Stat(filepath.Join(s.cfg.Dir, room.Code)); !os.IsNotExist(err) {
+		t.Fatalf("过期上传清理后房间目录仍然存在: %v", err)
+	}
+}
@@ -1,48 +1,125 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
+	"math/big"
 	"net/http"
 	"sync"
 	"time"
 
+	"chuan/internal/apierr"
+	"chuan/internal/auth"
+	"chuan/internal/clientip"
+	"chuan/internal/config"
+	"chuan/internal/models"
+	"chuan/internal/quota"
+
 	"github.com/gorilla/websocket"
 )
 
+var (
+	// ErrRoomNotFound 房间不存在或已过期
+	ErrRoomNotFound = errors.New("房间不存在或已过期")
+	// ErrInvalidOwnerToken 房主令牌无效
+	ErrInvalidOwnerToken = errors.New("房主令牌无效")
+	// ErrShuttingDown 服务器正在关闭，不再创建房间
+	ErrShuttingDown = errors.New("服务器正在关闭")
+)
+
 type WebRTCService struct {
+	cfg      *config.Config // 由 roomsMux 保护，重新加载配置时整体替换
 	rooms    map[string]*WebRTCRoom
 	roomsMux sync.RWMutex
 	upgrader websocket.Upgrader
+	hooks    []RoomHook
+	admin    []AdminHook
+	quota    *quota.Limiter // 房间配额，为 nil 时不限制，由 roomsMux 保护
+
+	subscribers map[string]map[*roomSubscriber]struct{} // 房间状态订阅者，由 roomsMux 保护
+	draining    bool                                    // 正在优雅关闭，由 roomsMux 保护
+	maintenance Maintenance                             // 维护模式，由 roomsMux 保护
 }
 
 type WebRTCRoom struct {
-	Code      string
-	Sender    *WebRTCClient
-	Receiver  *WebRTCClient
-	CreatedAt time.Time
-	ExpiresAt time.Time      // 添加过期时间
-	LastOffer *WebRTCMessage // 保存最后的offer消息
+	Code       string
+	Type       string // 房间类型，空表示尚未确定
+	State      RoomState
+	StateTimes map[RoomState]time.Time // 各状态最近一次进入的时间
+	Sender     *WebRTCClient
+	Receiver   *WebRTCClient
+	CreatedAt  time.Time
+	ExpiresAt  time.Time      // 添加过期时间
+	LastOffer  *WebRTCMessage // 保存最后的offer消息
+	LastPAKE   *WebRTCMessage // 保存发送方的 pake-init 消息，接收方加入后补发
+	OwnerToken string         // 房主令牌，持有者可以延长房间有效期
+	Creator    Actor          // 创建者
+
+	expiryWarned bool        // 是否已发送过期提醒
+	warnTimer    *time.Timer // 过期提醒定时器
+	expireTimer  *time.Timer // 过期清理定时器
+}
+
+// CreateRoomOptions 创建房间的参数
+type CreateRoomOptions struct {
+	TTL     time.Duration // 申请的有效期，0 表示使用该类型房间的默认值
+	Type    string        // 房间类型，空表示由第一个声明类型的客户端确定
+	Creator Actor         // 创建者
+}
+
+// Actor 触发房间操作的客户端，用于记录创建者和审计
+type Actor struct {
+	Identity  *auth.Identity // 已认证的用户身份，未认证时为 nil
+	IP        string
+	UserAgent string
+	Source    string // 不是来自客户端请求的操作的来源，例如 SIGUSR1
+}
+
+// String 用户身份描述，未认证时为空
+func (a Actor) String() string {
+	return a.Identity.String()
+}
+
+// CreatedRoom 新建房间的结果
+type CreatedRoom struct {
+	Code       string
+	Type       string
+	OwnerToken string
+	ExpiresAt  time.Time
 }
 
 type WebRTCClient struct {
 	ID         string
 	Role       string // "sender" or "receiver"
-	Connection *websocket.Conn
+	Connection PeerConn
 	Room       string
+	Type       string         // 客户端声明的房间类型，空表示不限
+	Identity   *auth.Identity // 已认证的用户身份，未认证时为 nil
+	IP         string
+	UserAgent  string
+	Lang       string // 错误消息使用的语言，取自握手请求的 Accept-Language 或 gRPC metadata
+
+	joinOnly bool // 不能创建房间
+}
+
+// actor 客户端对应的操作者信息
+func (c *WebRTCClient) actor() Actor {
+	return Actor{Identity: c.Identity, IP: c.IP, UserAgent: c.UserAgent}
 }
 
-func NewWebRTCService() *WebRTCService {
+func NewWebRTCService(cfg *config.Config) *WebRTCService {
 	service := &WebRTCService{
-		rooms:    make(map[string]*WebRTCRoom),
-		roomsMux: sync.RWMutex{},
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // 允许所有来源，生产环境应当限制
-			},
-		},
+		cfg:         cfg,
+		rooms:       make(map[string]*WebRTCRoom),
+		roomsMux:    sync.RWMutex{},
+		subscribers: make(map[string]map[*roomSubscriber]struct{}),
 	}
+	service.upgrader = websocket.Upgrader{CheckOrigin: service.checkOrigin}
 
 	// 启动房间清理任务
 	go service.cleanupExpiredRooms()
@@ -50,11 +127,18 @@ func NewWebRTCService() *WebRTCService {
 	return service
 }
 
+// WebRTCMessage 信令消息
+//
+// Payload 保持为原始 JSON，服务器转发和缓存时不解析；
+// Envelope 非空时 Payload 是客户端端到端加密的密文，服务器只能按 Type 路由。
 type WebRTCMessage struct {
-	Type    string      `json:"type"`
-	From    string      `json:"from"`
-	To      string      `json:"to"`
-	Payload interface{} `json:"payload"`
+	Type     string          `json:"type"`
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Payload  json.RawMessage `json:"payload"`
+	Envelope string          `json:"envelope,omitempty"` // 负载的加密信封格式，例如 e2e-v1
+	Error    string          `json:"error,omitempty"`    // error 消息的本地化错误描述
+	Code     string          `json:"code,omitempty"`     // error 消息的错误码，与 HTTP 接口一致
 }
 
 // HandleWebSocket 处理WebRTC信令WebSocket连接
@@ -71,37 +155,26 @@ func (ws *WebRTCService) HandleWebSocket(w http.ResponseWriter, r *http.Request)
 	// 获取房间码和角色
 	code := r.URL.Query().Get("code")
 	role := r.URL.Query().Get("role")
-
-	log.Printf("WebRTC连接参数: code=%s, role=%s", code, role)
-
-	if code == "" || (role != "sender" && role != "receiver") {
-		log.Printf("WebRTC连接参数无效: code=%s, role=%s", code, role)
+	channel := r.URL.Query().Get("channel")
+	lang := apierr.LangFromRequest(r)
+
+	log.Printf("WebRTC连接参数: code=%s, role=%s, channel=%s", code, role, channel)
+
+	// 添加客户端到房间，参数无效、房间类型不符或同一角色已有连接时拒绝
+	client, err := ws.Join(code, role, channel, NewPeerConn(&wsPeer{conn: conn}), PeerInfo{
+		Identity:  auth.FromContext(r.Context()),
+		IP:        clientip.From(r),
+		UserAgent: r.UserAgent(),
+		Lang:      lang,
+	})
+	if err != nil {
+		log.Printf("WebRTC %s加入房间失败: %s: %v", role, code, err)
+		rejectConnection(conn, lang, err)
 		return
 	}
 
-	// 生成客户端ID
-	clientID := ws.generateClientID()
-	client := &WebRTCClient{
-		ID:         clientID,
-		Role:       role,
-		Connection: conn,
-		Room:       code,
-	}
-
-	log.Printf("WebRTC客户端已创建: ID=%s, Role=%s, Room=%s", clientID, role, code)
-
-	// 添加客户端到房间
-	ws.addClientToRoom(code, client)
-	log.Printf("WebRTC %s连接到房间: %s (客户端ID: %s)", role, code, clientID)
-
 	// 连接关闭时清理
-	defer func() {
-		ws.removeClientFromRoom(code, clientID)
-		log.Printf("WebRTC客户端断开连接: %s (房间: %s)", clientID, code)
-
-		// 通知房间内其他客户端对方已断开连接
-		ws.notifyRoomDisconnection(code, clientID, client.Role)
-	}()
+	defer ws.Leave(client)
 
 	// 处理消息
 	for {
@@ -111,44 +184,59 @@ func (ws *WebRTCService) HandleWebSocket(w http.ResponseWriter, r *http.Request)
 			log.Printf("读取WebRTC WebSocket消息失败: %v", err)
 			break
 		}
-
-		msg.From = clientID
-		log.Printf("收到WebRTC信令: 类型=%s, 来自=%s, 房间=%s", msg.Type, clientID, code)
-
-		// 转发信令消息给对方
-		ws.forwardMessage(code, clientID, &msg)
+		ws.Receive(client, &msg)
 	}
 }
 
-// 添加客户端到房间
-func (ws *WebRTCService) addClientToRoom(code string, client *WebRTCClient) {
+// 添加客户端到房间，房间类型不符时返回 ROOM_TYPE_MISMATCH，同一角色已经在线时返回 ROOM_FULL
+//
+// 只有发送方加入不存在的房间时自动创建，接收方只能加入已有的房间，否则返回 ROOM_NOT_FOUND。
+func (ws *WebRTCService) addClientToRoom(code string, client *WebRTCClient) error {
 	ws.roomsMux.Lock()
 	defer ws.roomsMux.Unlock()
 
 	room := ws.rooms[code]
-	if room == nil {
-		room = &WebRTCRoom{
-			Code:      code,
-			CreatedAt: time.Now(),
-			ExpiresAt: time.Now().Add(time.Hour), // 1小时后过期
+	if room != nil {
+		if client.Type != "" && room.Type != "" && client.Type != room.Type {
+			return apierr.New(apierr.CodeRoomTypeMismatch, roomTypeName(room.Type, client.Lang)).
+				WithDetail("room_type", room.Type).
+				WithDetail("requested_type", client.Type)
+		}
+		if (client.Role == RoleSender && room.Sender != nil) || (client.Role == RoleReceiver && room.Receiver != nil) {
+			return apierr.New(apierr.CodeRoomFull, roleName(client.Role, client.Lang)).WithDetail("role", client.Role)
+		}
+		if room.Type == "" && client.Type != "" {
+			room.Type = client.Type
+			log.Printf("房间 %s 的类型确定为 %s", code, room.Type)
+		}
+	} else {
+		if client.Role != RoleSender || client.joinOnly {
+			return apierr.New(apierr.CodeRoomNotFound)
+		}
+		if err := ws.refuseNewRoomLocked(); err != nil {
+			return err
+		}
+		var err error
+		room, err = ws.newRoom(code, client.Type, ws.cfg.ClampRoomTTL(client.Type, 0), client.actor())
+		if err != nil {
+			return err
 		}
-		ws.rooms[code] = room
 		log.Printf("自动创建WebRTC房间: %s", code)
 	}
 
-	if client.Role == "sender" {
+	if client.Role == RoleSender {
 		room.Sender = client
 		// 如果发送方连接，检查是否有接收方在等待，通知接收方
 		if room.Receiver != nil {
 			log.Printf("通知接收方：发送方已连接")
 			peerJoinedMsg := &WebRTCMessage{
-				Type: "peer-joined",
+				Type: MessagePeerJoined,
 				From: client.ID,
-				Payload: map[string]interface{}{
+				Payload: encodePayload(map[string]interface{}{
 					"role": "sender",
-				},
+				}),
 			}
-			room.Receiver.Connection.WriteJSON(peerJoinedMsg)
+			room.Receiver.Connection.Send(peerJoinedMsg)
 		}
 	} else {
 		room.Receiver = client
@@ -156,24 +244,41 @@ func (ws *WebRTCService) addClientToRoom(code string, client *WebRTCClient) {
 		if room.Sender != nil {
 			log.Printf("通知发送方：接收方已连接，可以开始建立P2P连接")
 			peerJoinedMsg := &WebRTCMessage{
-				Type: "peer-joined",
+				Type: MessagePeerJoined,
 				From: client.ID,
-				Payload: map[string]interface{}{
+				Payload: encodePayload(map[string]interface{}{
 					"role": "receiver",
-				},
+				}),
+			}
+			room.Sender.Connection.Send(peerJoinedMsg)
+		}
+
+		// 接收方加入前发送方已发起 PAKE 交换，先补发 pake-init
+		if room.LastPAKE != nil {
+			log.Printf("向新连接的接收方发送保存的pake-init")
+			if err := client.Connection.Send(room.LastPAKE); err != nil {
+				log.Printf("发送保存的pake-init失败: %v", err)
 			}
-			room.Sender.Connection.WriteJSON(peerJoinedMsg)
 		}
 
 		// 如果接收方连接，且有保存的offer，立即发送给接收方
 		if room.LastOffer != nil {
 			log.Printf("向新连接的接收方发送保存的offer")
-			err := client.Connection.WriteJSON(room.LastOffer)
+			err := client.Connection.Send(room.LastOffer)
 			if err != nil {
 				log.Printf("发送保存的offer失败: %v", err)
 			}
 		}
 	}
+
+	ws.emit(EventPeerJoined, room, client)
+	ws.updatePresence(room, client)
+
+	// 优雅关闭期间加入已有房间的客户端同样需要知道服务器即将关闭
+	if ws.draining {
+		client.Connection.Send(ws.shutdownMessage())
+	}
+	return nil
 }
 
 // 从房间移除客户端
@@ -186,17 +291,29 @@ func (ws *WebRTCService) removeClientFromRoom(code string, clientID string) {
 		return
 	}
 
+	var left *WebRTCClient
 	if room.Sender != nil && room.Sender.ID == clientID {
+		left = room.Sender
 		room.Sender = nil
 	}
 	if room.Receiver != nil && room.Receiver.ID == clientID {
+		left = room.Receiver
 		room.Receiver = nil
 	}
+	if left != nil {
+		ws.emit(EventPeerLeft, room, left)
+	}
 
 	// 如果房间为空，删除房间
 	if room.Sender == nil && room.Receiver == nil {
-		delete(ws.rooms, code)
+		ws.finish(room, RoomClosed, Actor{})
+		ws.deleteRoom(room)
+		ws.emit(EventRoomClosed, room, nil)
 		log.Printf("清理WebRTC房间: %s", code)
+		return
+	}
+	if left != nil {
+		ws.updatePresence(room, left)
 	}
 }
 
@@ -211,23 +328,26 @@ func (ws *WebRTCService) forwardMessage(roomCode string, fromClientID string, ms
 	}
 
 	// 如果是offer消息，保存起来
-	if msg.Type == "offer" {
+	if msg.Type == MessageOffer {
 		room.LastOffer = msg
 		log.Printf("保存offer消息，等待接收方连接")
 	}
+	if msg.Type == MessagePAKEInit && room.Sender != nil && room.Sender.ID == fromClientID {
+		room.LastPAKE = msg
+	}
 
-	var targetClient *WebRTCClient
+	var fromClient, targetClient *WebRTCClient
 	if room.Sender != nil && room.Sender.ID == fromClientID {
 		// 消息来自sender，转发给receiver
-		targetClient = room.Receiver
+		fromClient, targetClient = room.Sender, room.Receiver
 	} else if room.Receiver != nil && room.Receiver.ID == fromClientID {
 		// 消息来自receiver，转发给sender
-		targetClient = room.Sender
+		fromClient, targetClient = room.Receiver, room.Sender
 	}
 
 	if targetClient != nil && targetClient.Connection != nil {
 		msg.To = targetClient.ID
-		err := targetClient.Connection.WriteJSON(msg)
+		err := targetClient.Connection.Send(msg)
 		if err != nil {
 			log.Printf("转发WebRTC信令失败: %v", err)
 		} else {
@@ -236,39 +356,380 @@ func (ws *WebRTCService) forwardMessage(roomCode string, fromClientID string, ms
 	} else {
 		log.Printf("目标客户端不在线，消息类型=%s", msg.Type)
 	}
+
+	if fromClient != nil {
+		ws.trackSignal(room, fromClient, msg)
+	}
 }
 
-// CreateRoom 创建或获取房间
-func (ws *WebRTCService) CreateRoom(code string) {
+// sendError 向客户端发送 error 消息
+func (ws *WebRTCService) sendError(roomCode string, clientID string, e *apierr.Error) {
 	ws.roomsMux.Lock()
 	defer ws.roomsMux.Unlock()
 
-	if _, exists := ws.rooms[code]; !exists {
-		ws.rooms[code] = &WebRTCRoom{
-			Code:      code,
-			CreatedAt: time.Now(),
-			ExpiresAt: time.Now().Add(time.Hour), // 1小时后过期
+	room := ws.rooms[roomCode]
+	if room == nil {
+		return
+	}
+	for _, client := range []*WebRTCClient{room.Sender, room.Receiver} {
+		if client != nil && client.ID == clientID && client.Connection != nil {
+			client.Connection.Send(errorMessage(clientID, client.Lang, e))
+		}
+	}
+}
+
+// errorMessage 构造 error 消息，错误码和附加信息与 HTTP 错误响应一致
+func errorMessage(to string, lang string, e *apierr.Error) *WebRTCMessage {
+	var payload json.RawMessage
+	if len(e.Details) > 0 {
+		payload = encodePayload(e.Details)
+	}
+	return &WebRTCMessage{
+		Type:    MessageError,
+		To:      to,
+		Payload: payload,
+		Error:   e.Message(lang),
+		Code:    string(e.Code),
+	}
+}
+
+// rejectConnection 发送 error 消息后以关闭帧结束尚未加入房间的 WebSocket 连接
+func rejectConnection(conn *websocket.Conn, lang string, err error) {
+	e := apierr.From(err)
+	if writeErr := conn.WriteJSON(errorMessage("", lang, e)); writeErr != nil {
+		return
+	}
+	closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, string(e.Code))
+	conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+}
+
+// roleName 角色的本地化名称
+func roleName(role string, lang string) string {
+	if lang == apierr.LangEN {
+		return role
+	}
+	if role == "sender" {
+		return "发送方"
+	}
+	return "接收方"
+}
+
+// roomTypeName 房间类型的本地化名称
+func roomTypeName(roomType string, lang string) string {
+	if lang == apierr.LangEN {
+		switch roomType {
+		case models.RoomTypeFile:
+			return "file transfer"
+		case models.RoomTypeText:
+			return "text messages"
+		case models.RoomTypeDesktop:
+			return "desktop sharing"
 		}
-		log.Printf("创建WebRTC房间: %s", code)
+		return roomType
+	}
+	switch roomType {
+	case models.RoomTypeFile:
+		return "文件传输"
+	case models.RoomTypeText:
+		return "文字传输"
+	case models.RoomTypeDesktop:
+		return "桌面共享"
 	}
+	return roomType
 }
 
-// CreateNewRoom 创建新房间并返回房间码
-func (ws *WebRTCService) CreateNewRoom() string {
+// ParseRoomType 解析客户端声明的房间类型
+//
+// 空值和旧版前端使用的 shared 表示不限类型，未知类型返回 INVALID_PARAMETER。
+func ParseRoomType(field string, value string) (string, error) {
+	if value == "" || value == "shared" {
+		return "", nil
+	}
+	if !models.ValidRoomType(value) {
+		return "", apierr.New(apierr.CodeInvalidParameter, field).WithDetail("field", field)
+	}
+	return value, nil
+}
+
+// CreateNewRoom 创建新房间并返回房间码、房主令牌和过期时间
+//
+// 服务器正在关闭时返回 SERVER_SHUTTING_DOWN，维护期间返回 MAINTENANCE，超出创建者的配额时返回 *quota.ExceededError。
+func (ws *WebRTCService) CreateNewRoom(opts CreateRoomOptions) (CreatedRoom, error) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	ttl := ws.cfg.ClampRoomTTL(opts.Type, opts.TTL)
+
+	if err := ws.refuseNewRoomLocked(); err != nil {
+		return CreatedRoom{}, err
+	}
+
 	code := ws.generatePickupCode()
-	ws.CreateRoom(code)
-	return code
+	for ws.rooms[code] != nil {
+		code = ws.generatePickupCode()
+	}
+
+	room, err := ws.newRoom(code, opts.Type, ttl, opts.Creator)
+	if err != nil {
+		return CreatedRoom{}, err
+	}
+	if opts.Creator.Identity != nil {
+		log.Printf("创建WebRTC房间: %s (有效期 %s, 创建者 %s)", code, ttl, opts.Creator)
+	} else {
+		log.Printf("创建WebRTC房间: %s (有效期 %s)", code, ttl)
+	}
+
+	return CreatedRoom{
+		Code:       room.Code,
+		Type:       room.Type,
+		OwnerToken: room.OwnerToken,
+		ExpiresAt:  room.ExpiresAt,
+	}, nil
+}
+
+// ExtendRoom 使用房主令牌延长房间有效期，从创建时起算的有效期不超过服务器允许的最长值
+func (ws *WebRTCService) ExtendRoom(code string, ownerToken string, extendBy time.Duration, actor Actor) (time.Time, error) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	room := ws.rooms[code]
+	if room == nil {
+		return time.Time{}, ErrRoomNotFound
+	}
+	if ownerToken == "" || subtle.ConstantTimeCompare([]byte(ownerToken), []byte(room.OwnerToken)) != 1 {
+		return time.Time{}, ErrInvalidOwnerToken
+	}
+
+	if extendBy <= 0 {
+		extendBy = ws.cfg.RoomTTL.Duration
+	}
+	expiresAt := room.ExpiresAt.Add(extendBy)
+	// 房间从创建起的总有效期不超过最长值，多次延长也不能超出
+	if limit := room.CreatedAt.Add(ws.cfg.MaxRoomTTLFor(room.Type)); expiresAt.After(limit) {
+		expiresAt = limit
+	}
+
+	room.ExpiresAt = expiresAt
+	room.expiryWarned = false
+	ws.scheduleExpiry(room)
+	ws.emitAs(EventRoomExtended, room, nil, actor)
+	log.Printf("延长WebRTC房间有效期: %s, 新的过期时间 %s", code, expiresAt.Format(time.RFC3339))
+
+	return expiresAt, nil
+}
+
+// RoomExpiresAt 返回房间当前的过期时间
+func (ws *WebRTCService) RoomExpiresAt(code string) (time.Time, error) {
+	ws.roomsMux.RLock()
+	defer ws.roomsMux.RUnlock()
+
+	room := ws.rooms[code]
+	if room == nil {
+		return time.Time{}, ErrRoomNotFound
+	}
+	return room.ExpiresAt, nil
+}
+
+// roomLifetime 房间的创建时间和过期时间，房间不存在时返回 ErrRoomNotFound
+//
+// 房间码会被重复使用，创建时间用于区分同一房间码的不同房间。
+func (ws *WebRTCService) roomLifetime(code string) (time.Time, time.Time, error) {
+	ws.roomsMux.RLock()
+	defer ws.roomsMux.RUnlock()
+
+	room := ws.rooms[code]
+	if room == nil {
+		return time.Time{}, time.Time{}, ErrRoomNotFound
+	}
+	return room.CreatedAt, room.ExpiresAt, nil
+}
+
+// RoomCount 当前房间总数
+func (ws *WebRTCService) RoomCount() int {
+	ws.roomsMux.RLock()
+	defer ws.roomsMux.RUnlock()
+
+	return len(ws.rooms)
+}
+
+// newRoom 创建房间并安排过期处理（调用方需持有 roomsMux）
+//
+// 所有创建房间的途径都经过这里，在此为创建者占用房间配额，房间关闭或过期时由事件钩子归还。
+func (ws *WebRTCService) newRoom(code string, roomType string, ttl time.Duration, creator Actor) (*WebRTCRoom, error) {
+	if ws.quota != nil {
+		if err := ws.quota.ReserveRoom(code, quota.Subject(creator.Identity, creator.IP), len(ws.rooms)); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	room := &WebRTCRoom{
+		Code:       code,
+		Type:       roomType,
+		State:      RoomCreated,
+		StateTimes: map[RoomState]time.Time{RoomCreated: now},
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+		OwnerToken: generateOwnerToken(),
+		Creator:    creator,
+	}
+	ws.rooms[code] = room
+	ws.scheduleExpiry(room)
+	ws.emit(EventRoomCreated, room, nil)
+	return room, nil
+}
+
+// deleteRoom 删除房间并停止其定时器（调用方需持有 roomsMux）
+func (ws *WebRTCService) deleteRoom(room *WebRTCRoom) {
+	room.stopTimers()
+	if ws.rooms[room.Code] == room {
+		delete(ws.rooms, room.Code)
+	}
+}
+
+// scheduleExpiry 按照房间当前的过期时间重新安排提醒和清理（调用方需持有 roomsMux）
+func (ws *WebRTCService) scheduleExpiry(room *WebRTCRoom) {
+	room.stopTimers()
+
+	if !room.expiryWarned && ws.cfg.RoomExpiryWarning.Duration > 0 {
+		warnIn := time.Until(room.ExpiresAt.Add(-ws.cfg.RoomExpiryWarning.Duration))
+		if warnIn < 0 {
+			warnIn = 0
+		}
+		room.warnTimer = time.AfterFunc(warnIn, func() { ws.warnRoomExpiring(room) })
+	}
+	room.expireTimer = time.AfterFunc(time.Until(room.ExpiresAt), func() { ws.expireRoom(room) })
+}
+
+// stopTimers 停止房间的过期定时器
+func (room *WebRTCRoom) stopTimers() {
+	if room.warnTimer != nil {
+		room.warnTimer.Stop()
+		room.warnTimer = nil
+	}
+	if room.expireTimer != nil {
+		room.expireTimer.Stop()
+		room.expireTimer = nil
+	}
+}
+
+// warnRoomExpiring 通知房间内客户端房间即将过期
+func (ws *WebRTCService) warnRoomExpiring(room *WebRTCRoom) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	// 房间已被删除，或在定时器触发前被延期
+	if ws.rooms[room.Code] != room || room.expiryWarned {
+		return
+	}
+	if time.Until(room.ExpiresAt) > ws.cfg.RoomExpiryWarning.Duration {
+		return
+	}
+	room.expiryWarned = true
+
+	remaining := time.Until(room.ExpiresAt)
+	if remaining < 0 {
+		remaining = 0
+	}
+	ws.broadcastToRoom(room, &WebRTCMessage{
+		Type: MessageExpiringSoon,
+		Payload: encodePayload(map[string]interface{}{
+			"expires_at":        room.ExpiresAt,
+			"remaining_seconds": int(remaining.Seconds()),
+			"message":           "房间即将过期",
+		}),
+	})
+	log.Printf("WebRTC房间即将过期: %s (剩余 %s)", room.Code, remaining.Round(time.Second))
+}
+
+// expireRoom 通知房间内客户端房间已过期，然后关闭连接并删除房间
+func (ws *WebRTCService) expireRoom(room *WebRTCRoom) {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	if ws.rooms[room.Code] != room || time.Now().Before(room.ExpiresAt) {
+		return
+	}
+	ws.expireRoomLocked(room)
+}
+
+// expireRoomLocked 执行房间过期处理（调用方需持有 roomsMux）
+func (ws *WebRTCService) expireRoomLocked(room *WebRTCRoom) {
+	ws.broadcastToRoom(room, &WebRTCMessage{
+		Type: MessageRoomExpired,
+		Payload: encodePayload(map[string]interface{}{
+			"message": "房间已过期",
+		}),
+	})
+	ws.closeConnections(room, websocket.CloseNormalClosure, "room expired")
+
+	ws.finish(room, RoomExpired, Actor{})
+	ws.deleteRoom(room)
+	ws.emit(EventRoomExpired, room, nil)
+	log.Printf("WebRTC房间已过期: %s", room.Code)
+}
+
+// CloseRoom 使用房主令牌关闭房间：通知房间内客户端后断开连接并删除房间
+func (ws *WebRTCService) CloseRoom(code string, ownerToken string, actor Actor) error {
+	ws.roomsMux.Lock()
+	defer ws.roomsMux.Unlock()
+
+	room := ws.rooms[code]
+	if room == nil {
+		return ErrRoomNotFound
+	}
+	if ownerToken == "" || subtle.ConstantTimeCompare([]byte(ownerToken), []byte(room.OwnerToken)) != 1 {
+		return ErrInvalidOwnerToken
+	}
+
+	ws.broadcastToRoom(room, &WebRTCMessage{
+		Type: MessageRoomClosed,
+		Payload: encodePayload(map[string]interface{}{
+			"message": "房间已被房主关闭",
+		}),
+	})
+	ws.closeConnections(room, websocket.CloseNormalClosure, "room closed")
+
+	ws.finish(room, RoomClosed, actor)
+	ws.deleteRoom(room)
+	ws.emitAs(EventRoomClosed, room, nil, actor)
+	log.Printf("WebRTC房间已被房主关闭: %s", code)
+	return nil
+}
+
+// closeConnections 断开房间内所有客户端的连接（调用方需持有 roomsMux）
+func (ws *WebRTCService) closeConnections(room *WebRTCRoom, code int, reason string) {
+	for _, client := range []*WebRTCClient{room.Sender, room.Receiver} {
+		if client != nil && client.Connection != nil {
+			client.Connection.Close(code, reason)
+		}
+	}
+}
+
+// broadcastToRoom 向房间内所有在线客户端发送消息（调用方需持有 roomsMux）
+func (ws *WebRTCService) broadcastToRoom(room *WebRTCRoom, msg *WebRTCMessage) {
+	for _, client := range []*WebRTCClient{room.Sender, room.Receiver} {
+		if client == nil || client.Connection == nil {
+			continue
+		}
+		if err := client.Connection.Send(msg); err != nil {
+			log.Printf("发送%s消息失败: %v", msg.Type, err)
+		}
+	}
 }
 
-// generatePickupCode 生成6位取件码
+// generatePickupCode 生成6位取件码，使用 crypto/rand，取件码不能被预测
 func (ws *WebRTCService) generatePickupCode() string {
-	source := rand.NewSource(time.Now().UnixNano())
-	rng := rand.New(source)
-	code := rng.Intn(900000) + 100000
-	return fmt.Sprintf("%d", code)
+	n, err := rand.Int(rand.Reader, big.NewInt(900000))
+	if err != nil {
+		panic(fmt.Sprintf("生成取件码失败: %v", err))
+	}
+	return fmt.Sprintf("%d", n.Int64()+100000)
 }
 
-// cleanupExpiredRooms 定期清理过期房间
+// cleanupExpiredRooms 定期清理过期房间（正常情况下由过期定时器处理）
+//
+// 还没有客户端加入的房间（created 状态）会一直保留到过期，客户端全部离开的房间在离开时已经删除。
 func (ws *WebRTCService) cleanupExpiredRooms() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
@@ -276,11 +737,9 @@ func (ws *WebRTCService) cleanupExpiredRooms() {
 	for range ticker.C {
 		ws.roomsMux.Lock()
 		now := time.Now()
-		for code, room := range ws.rooms {
-			// 房间过期或无客户端连接则删除
-			if now.After(room.ExpiresAt) || (room.Sender == nil && room.Receiver == nil) {
-				delete(ws.rooms, code)
-				log.Printf("清理过期WebRTC房间: %s", code)
+		for _, room := range ws.rooms {
+			if now.After(room.ExpiresAt) {
+				ws.expireRoomLocked(room)
 			}
 		}
 		ws.roomsMux.Unlock()
@@ -289,7 +748,20 @@ func (ws *WebRTCService) cleanupExpiredRooms() {
 
 // generateClientID 生成客户端ID
 func (ws *WebRTCService) generateClientID() string {
-	return fmt.Sprintf("webrtc_client_%d", rand.Int63())
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成客户端ID失败: %v", err))
+	}
+	return "webrtc_client_" + hex.EncodeToString(buf)
+}
+
+// generateOwnerToken 生成房主令牌
+func generateOwnerToken() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("生成房主令牌失败: %v", err))
+	}
+	return hex.EncodeToString(buf)
 }
 
 // 通知房间内客户端有人断开连接
@@ -304,17 +776,17 @@ func (ws *WebRTCService) notifyRoomDisconnection(roomCode string, disconnectedCl
 
 	// 构建断开连接通知消息
 	disconnectionMsg := &WebRTCMessage{
-		Type: "disconnection",
+		Type: MessageDisconnect,
 		From: disconnectedClientID,
-		Payload: map[string]interface{}{
+		Payload: encodePayload(map[string]interface{}{
 			"role":    disconnectedRole,
 			"message": "对方已停止传输",
-		},
+		}),
 	}
 
 	// 通知房间内其他客户端
 	if room.Sender != nil && room.Sender.ID != disconnectedClientID {
-		err := room.Sender.Connection.WriteJSON(disconnectionMsg)
+		err := room.Sender.Connection.Send(disconnectionMsg)
 		if err != nil {
 			log.Printf("通知发送方断开连接失败: %v", err)
 		} else {
@@ -323,7 +795,7 @@ func (ws *WebRTCService) notifyRoomDisconnection(roomCode string, disconnectedCl
 	}
 
 	if room.Receiver != nil && room.Receiver.ID != disconnectedClientID {
-		err := room.Receiver.Connection.WriteJSON(disconnectionMsg)
+		err := room.Receiver.Connection.Send(disconnectionMsg)
 		if err != nil {
 			log.Printf("通知接收方断开连接失败: %v", err)
 		} else {
@@ -331,24 +803,38 @@ func (ws *WebRTCService) notifyRoomDisconnection(roomCode string, disconnectedCl
 		}
 	}
 }
-func (ws *WebRTCService) GetRoomStatus(code string) map[string]interface{} {
+
+// RoomStatus 获取房间状态，房间不存在时返回 ErrRoomNotFound
+func (ws *WebRTCService) RoomStatus(code string) (models.RoomStatus, error) {
 	ws.roomsMux.RLock()
 	defer ws.roomsMux.RUnlock()
 
 	room := ws.rooms[code]
 	if room == nil {
-		return map[string]interface{}{
-			"success": false,
-			"exists":  false,
-			"message": "房间不存在或已过期",
-		}
+		return models.RoomStatus{}, ErrRoomNotFound
+	}
+	return roomStatus(room), nil
+}
+
+// GetRoomStatus 获取房间状态，房间不存在时返回 ErrRoomNotFound
+func (ws *WebRTCService) GetRoomStatus(code string) (map[string]interface{}, error) {
+	ws.roomsMux.RLock()
+	defer ws.roomsMux.RUnlock()
+
+	room := ws.rooms[code]
+	if room == nil {
+		return nil, ErrRoomNotFound
 	}
 
 	return map[string]interface{}{
 		"success":         true,
 		"exists":          true,
+		"type":            room.Type,
+		"state":           room.State,
+		"state_times":     room.stateTimes(),
 		"sender_online":   room.Sender != nil,
 		"receiver_online": room.Receiver != nil,
 		"created_at":      room.CreatedAt,
-	}
+		"expires_at":      room.ExpiresAt,
+	}, nil
 }
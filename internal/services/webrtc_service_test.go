@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"chuan/internal/apierr"
+	"chuan/internal/config"
+	"chuan/internal/quota"
+)
+
+// 接收方不能通过加入不存在的房间来创建房间
+func TestReceiverDoesNotCreateRoom(t *testing.T) {
+	ws := NewWebRTCService(config.Default())
+
+	_, err := ws.Join("123456", RoleReceiver, "", &recordingConn{}, PeerInfo{})
+	var apiErr *apierr.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != apierr.CodeRoomNotFound {
+		t.Fatalf("接收方加入不存在的房间应返回 ROOM_NOT_FOUND，得到 %v", err)
+	}
+	if ws.RoomCount() != 0 {
+		t.Fatalf("接收方创建了房间")
+	}
+
+	if _, err := ws.Join("123456", RoleSender, "", &recordingConn{}, PeerInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.Join("123456", RoleReceiver, "", &recordingConn{}, PeerInfo{}); err != nil {
+		t.Fatalf("接收方应能加入发送方创建的房间: %v", err)
+	}
+}
+
+// 发送方加入时自动创建的房间同样占用配额，房间关闭后归还
+func TestAutoCreatedRoomConsumesQuota(t *testing.T) {
+	cfg := config.Default()
+	cfg.Quota.RoomsPerSubject = 1
+	ws := NewWebRTCService(cfg)
+	limiter := quota.New(cfg.Quota, ws.RoomCount)
+	ws.SetQuota(limiter)
+	ws.AddHook(func(event RoomEvent) {
+		if event.Type == EventRoomClosed || event.Type == EventRoomExpired {
+			limiter.ReleaseRoom(event.Room)
+		}
+	})
+
+	client, err := ws.Join("123456", RoleSender, "", &recordingConn{}, PeerInfo{IP: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exceeded *quota.ExceededError
+	if _, err := ws.Join("654321", RoleSender, "", &recordingConn{}, PeerInfo{IP: "192.0.2.1"}); !errors.As(err, &exceeded) {
+		t.Fatalf("超出配额时应拒绝自动创建房间，得到 %v", err)
+	}
+	if _, err := ws.CreateNewRoom(CreateRoomOptions{Creator: Actor{IP: "192.0.2.1"}}); !errors.As(err, &exceeded) {
+		t.Fatalf("超出配额时应拒绝创建房间，得到 %v", err)
+	}
+	if _, err := ws.Join("654321", RoleSender, "", &recordingConn{}, PeerInfo{IP: "192.0.2.2"}); err != nil {
+		t.Fatalf("其他客户端不受影响: %v", err)
+	}
+
+	ws.Leave(client)
+	if _, err := ws.Join("111111", RoleSender, "", &recordingConn{}, PeerInfo{IP: "192.0.2.1"}); err != nil {
+		t.Fatalf("房间关闭后应归还名额: %v", err)
+	}
+}
+
+// 多次延长后，从创建时起算的有效期仍不超过最长值
+func TestExtendRoomCappedFromCreation(t *testing.T) {
+	cfg := config.Default()
+	ws := NewWebRTCService(cfg)
+	room, err := ws.CreateNewRoom(CreateRoomOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit := cfg.MaxRoomTTLFor("")
+	var expiresAt time.Time
+	for i := 0; i < 3; i++ {
+		if expiresAt, err = ws.ExtendRoom(room.Code, room.OwnerToken, limit, Actor{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if created := room.ExpiresAt.Add(-cfg.ClampRoomTTL("", 0)); expiresAt.After(created.Add(limit)) {
+		t.Fatalf("有效期超过最长值: 创建于 %s，过期时间 %s", created, expiresAt)
+	}
+}
+
+// 维护模式状态没有变化时不记录审计事件
+func TestSetMaintenanceAuditsOnlyChanges(t *testing.T) {
+	ws := NewWebRTCService(config.Default())
+	var events []string
+	ws.AddAdminHook(func(event AdminEvent) {
+		events = append(events, event.Type)
+	})
+
+	ws.SetMaintenance(false, "", Actor{})
+	ws.SetMaintenance(true, "升级", Actor{})
+	ws.SetMaintenance(true, "升级", Actor{})
+	ws.SetMaintenance(true, "升级数据库", Actor{})
+	ws.SetMaintenance(false, "", Actor{})
+	ws.SetMaintenance(false, "", Actor{})
+
+	want := []string{EventMaintenanceEnabled, EventMaintenanceEnabled, EventMaintenanceDisabled}
+	if len(events) != len(want) {
+		t.Fatalf("审计事件 %v，期望 %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("审计事件 %v，期望 %v", events, want)
+		}
+	}
+	if m := ws.Maintenance(); m.Enabled {
+		t.Fatalf("维护模式仍然开启: %+v", m)
+	}
+}
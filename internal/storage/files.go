@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTooLarge 写入的数据超过限制
+var ErrTooLarge = errors.New("数据超过大小限制")
+
+// StoredFile 暂存文件的元数据，文件内容由客户端加密，服务器不解析
+type StoredFile struct {
+	Code           string    `json:"code"`
+	Meta           string    `json:"meta,omitempty"` // 客户端加密后的文件信息（文件名、类型等），服务器原样保存
+	Size           int64     `json:"size"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	MaxDownloads   int       `json:"max_downloads"` // 最大下载次数，0 表示不限
+	Downloads      int       `json:"downloads"`     // 已开始的完整下载次数
+	OwnerTokenHash string    `json:"owner_token_hash"`
+}
+
+// Expired 判断文件是否已过期
+func (f *StoredFile) Expired(now time.Time) bool {
+	return !now.Before(f.ExpiresAt)
+}
+
+// Exhausted 判断文件是否已达到下载次数上限
+func (f *StoredFile) Exhausted() bool {
+	return f.MaxDownloads > 0 && f.Downloads >= f.MaxDownloads
+}
+
+// FileStore 暂存文件的磁盘存储，每个文件对应 <code>.bin 内容文件和 <code>.json 元数据文件
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore 创建暂存文件存储，目录不存在时自动创建
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("创建文件存储目录失败: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Create 从 r 读取最多 maxSize 字节写入新文件，并保存元数据；返回实际写入的字节数
+func (s *FileStore) Create(meta *StoredFile, r io.Reader, maxSize int64) (int64, error) {
+	dataPath, metaPath, err := s.paths(meta.Code)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	if _, err := os.Stat(metaPath); err == nil {
+		s.mu.Unlock()
+		return 0, ErrExists
+	}
+	// 先占位，防止并发上传使用同一个代码
+	if err := os.WriteFile(metaPath, []byte("{}"), 0o600); err != nil {
+		s.mu.Unlock()
+		return 0, err
+	}
+	s.mu.Unlock()
+
+	size, err := s.writeData(dataPath, r, maxSize)
+	if err != nil {
+		os.Remove(dataPath)
+		os.Remove(metaPath)
+		return size, err
+	}
+
+	meta.Size = size
+	if err := s.UpdateMeta(meta); err != nil {
+		os.Remove(dataPath)
+		os.Remove(metaPath)
+		return size, err
+	}
+	return size, nil
+}
+
+// Open 打开文件内容和元数据
+func (s *FileStore) Open(code string) (*os.File, *StoredFile, error) {
+	meta, err := s.GetMeta(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataPath, _, err := s.paths(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(dataPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	return file, meta, nil
+}
+
+// GetMeta 读取元数据
+func (s *FileStore) GetMeta(code string) (*StoredFile, error) {
+	_, metaPath, err := s.paths(code)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, err := readStoredFile(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Code == "" {
+		// 仍在上传中的占位文件
+		return nil, ErrNotFound
+	}
+	return meta, nil
+}
+
+// UpdateMeta 覆盖保存元数据
+func (s *FileStore) UpdateMeta(meta *StoredFile) error {
+	_, metaPath, err := s.paths(meta.Code)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp := metaPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, metaPath)
+}
+
+// Delete 删除文件内容和元数据
+func (s *FileStore) Delete(code string) error {
+	dataPath, metaPath, err := s.paths(code)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(dataPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(metaPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List 列出所有文件的元数据，跳过仍在上传中的文件
+func (s *FileStore) List() ([]*StoredFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*StoredFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		meta, err := readStoredFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil || meta.Code == "" {
+			continue
+		}
+		files = append(files, meta)
+	}
+	return files, nil
+}
+
+func (s *FileStore) writeData(path string, r io.Reader, maxSize int64) (int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+
+	// 多读一个字节用于判断是否超过限制
+	size, err := io.Copy(file, io.LimitReader(r, maxSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return size, err
+	}
+	if size > maxSize {
+		return size, ErrTooLarge
+	}
+	return size, nil
+}
+
+// paths 返回内容文件和元数据文件路径，拒绝包含路径分隔符等非法字符的代码
+func (s *FileStore) paths(code string) (string, string, error) {
+	if code == "" || strings.ContainsAny(code, `/\.`) {
+		return "", "", fmt.Errorf("无效的文件代码: %q", code)
+	}
+	return filepath.Join(s.dir, code+".bin"), filepath.Join(s.dir, code+".json"), nil
+}
+
+func readStoredFile(path string) (*StoredFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var meta StoredFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
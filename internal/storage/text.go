@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound 记录不存在
+	ErrNotFound = errors.New("记录不存在")
+	// ErrExists 记录已存在
+	ErrExists = errors.New("记录已存在")
+)
+
+// TextSnippet 文本片段
+type TextSnippet struct {
+	Code      string    `json:"code"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxViews  int       `json:"max_views"` // 最大查看次数，0 表示不限
+	Views     int       `json:"views"`     // 已查看次数
+}
+
+// Expired 判断文本是否已过期
+func (s *TextSnippet) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// TextStore 文本片段存储
+//
+// 存储只负责持久化，过期、查看次数等业务规则由调用方处理。
+// 实现必须是并发安全的。
+type TextStore interface {
+	// Create 保存新的文本片段，房间码已被占用时返回 ErrExists
+	Create(snippet *TextSnippet) error
+	// Get 读取文本片段，不存在时返回 ErrNotFound
+	Get(code string) (*TextSnippet, error)
+	// Update 更新已存在的文本片段，不存在时返回 ErrNotFound
+	Update(snippet *TextSnippet) error
+	// Delete 删除文本片段，不存在时不返回错误
+	Delete(code string) error
+	// DeleteExpired 删除所有在 now 之前过期的文本片段，返回删除的取件码
+	DeleteExpired(now time.Time) ([]string, error)
+}
+
+// OpenTextStore 按后端名称创建文本存储：memory 或 disk
+func OpenTextStore(backend string, dir string) (TextStore, error) {
+	switch backend {
+	case "", "memory":
+		return NewMemoryTextStore(), nil
+	case "disk":
+		return NewDiskTextStore(dir)
+	default:
+		return nil, fmt.Errorf("未知的文本存储后端: %s", backend)
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskTextStore 磁盘文本存储，每个文本片段保存为目录下的一个 JSON 文件
+type DiskTextStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewDiskTextStore 创建磁盘文本存储，目录不存在时自动创建
+func NewDiskTextStore(dir string) (*DiskTextStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("创建文本存储目录失败: %w", err)
+	}
+	return &DiskTextStore{dir: dir}, nil
+}
+
+func (d *DiskTextStore) Create(snippet *TextSnippet) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path, err := d.path(snippet.Code)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return ErrExists
+	}
+	return d.write(path, snippet)
+}
+
+func (d *DiskTextStore) Get(code string) (*TextSnippet, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path, err := d.path(code)
+	if err != nil {
+		return nil, err
+	}
+	return d.read(path)
+}
+
+func (d *DiskTextStore) Update(snippet *TextSnippet) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path, err := d.path(snippet.Code)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ErrNotFound
+	}
+	return d.write(path, snippet)
+}
+
+func (d *DiskTextStore) Delete(code string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path, err := d.path(code)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (d *DiskTextStore) DeleteExpired(now time.Time) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(d.dir, entry.Name())
+		snippet, err := d.read(path)
+		if err != nil {
+			log.Printf("读取文本片段失败 %s: %v", path, err)
+			continue
+		}
+		if snippet.Expired(now) {
+			if err := os.Remove(path); err == nil {
+				codes = append(codes, snippet.Code)
+			}
+		}
+	}
+	return codes, nil
+}
+
+// path 返回文本片段的文件路径，包含路径分隔符等非法字符的取件码不可能存在，返回 ErrNotFound
+func (d *DiskTextStore) path(code string) (string, error) {
+	if code == "" || strings.ContainsAny(code, `/\.`) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(d.dir, code+".json"), nil
+}
+
+func (d *DiskTextStore) read(path string) (*TextSnippet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var snippet TextSnippet
+	if err := json.Unmarshal(data, &snippet); err != nil {
+		return nil, err
+	}
+	return &snippet, nil
+}
+
+// write 先写入临时文件再重命名，避免读取到写了一半的文件
+func (d *DiskTextStore) write(path string, snippet *TextSnippet) error {
+	data, err := json.Marshal(snippet)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryTextStore 内存文本存储，进程重启后数据丢失
+type MemoryTextStore struct {
+	mu       sync.RWMutex
+	snippets map[string]*TextSnippet
+}
+
+// NewMemoryTextStore 创建内存文本存储
+func NewMemoryTextStore() *MemoryTextStore {
+	return &MemoryTextStore{
+		snippets: make(map[string]*TextSnippet),
+	}
+}
+
+func (m *MemoryTextStore) Create(snippet *TextSnippet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.snippets[snippet.Code]; exists {
+		return ErrExists
+	}
+	copied := *snippet
+	m.snippets[snippet.Code] = &copied
+	return nil
+}
+
+func (m *MemoryTextStore) Get(code string) (*TextSnippet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippet, exists := m.snippets[code]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	copied := *snippet
+	return &copied, nil
+}
+
+func (m *MemoryTextStore) Update(snippet *TextSnippet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.snippets[snippet.Code]; !exists {
+		return ErrNotFound
+	}
+	copied := *snippet
+	m.snippets[snippet.Code] = &copied
+	return nil
+}
+
+func (m *MemoryTextStore) Delete(code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.snippets, code)
+	return nil
+}
+
+func (m *MemoryTextStore) DeleteExpired(now time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var codes []string
+	for code, snippet := range m.snippets {
+		if snippet.Expired(now) {
+			delete(m.snippets, code)
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
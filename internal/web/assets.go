@@ -0,0 +1,162 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minCompressSize 小于该大小的文件不压缩
+const minCompressSize = 1024
+
+// asset 启动时读入内存的前端文件
+//
+// brotli 版本由构建脚本生成（与原文件同名加 .br 后缀），gzip 版本优先使用构建时生成的 .gz 文件，
+// 没有时在启动时压缩。每个版本都有基于内容哈希的强 ETag。
+type asset struct {
+	contentType  string
+	cacheControl string
+	identity     variant
+	br           *variant
+	gzip         *variant
+}
+
+// variant 文件的某种编码版本
+type variant struct {
+	encoding string // Content-Encoding，原文件为空
+	content  []byte
+	etag     string
+}
+
+// loadAssets 读取文件系统中的所有文件并准备压缩版本，.br 和 .gz 文件作为对应原文件的压缩版本
+//
+// compress 为 false 时不在启动时生成 gzip 版本（开发模式下文件频繁变化）。basePath 不为空时改写
+// 文件中的站内地址，被改写的文件不使用构建时生成的压缩版本。
+func loadAssets(fsys fs.FS, compress bool, basePath string) (map[string]*asset, error) {
+	assets := make(map[string]*asset)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := path.Ext(name); ext == ".br" || ext == ".gz" {
+			if _, err := fs.Stat(fsys, strings.TrimSuffix(name, ext)); err == nil {
+				return nil
+			}
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		br, _ := fs.ReadFile(fsys, name+".br")
+		gz, _ := fs.ReadFile(fsys, name+".gz")
+		if rewritten := rewriteBasePath(name, content, basePath); rewritten != nil {
+			content, br, gz = rewritten, nil, nil
+		}
+		assets[name] = newAsset(name, content, br, gz, compress)
+		return nil
+	})
+	return assets, err
+}
+
+// newAsset 创建文件，br 和 gz 为预先生成的压缩版本（可以为 nil），compress 为 true 时缺少的 gzip 版本在此生成
+func newAsset(name string, content []byte, br []byte, gz []byte, compress bool) *asset {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:16])
+
+	a := &asset{
+		contentType:  contentType(name),
+		cacheControl: "no-cache",
+		identity:     variant{content: content, etag: strconv.Quote(hash)},
+	}
+	if shouldCache(name) {
+		a.cacheControl = "public, max-age=31536000" // 1年
+	}
+	if !compressible(a.contentType) || len(content) < minCompressSize {
+		return a
+	}
+
+	if br != nil {
+		a.br = &variant{encoding: "br", content: br, etag: strconv.Quote(hash + "-br")}
+	}
+	if gz == nil && compress {
+		gz = gzipBytes(content)
+	}
+	if gz != nil && len(gz) < len(content) {
+		a.gzip = &variant{encoding: "gzip", content: gz, etag: strconv.Quote(hash + "-gz")}
+	}
+	return a
+}
+
+// serve 按 Accept-Encoding 选择版本并输出，条件请求和 Range 由 http.ServeContent 处理
+func (a *asset) serve(w http.ResponseWriter, r *http.Request) {
+	v := &a.identity
+	accept := r.Header.Get("Accept-Encoding")
+	if a.br != nil && acceptsEncoding(accept, "br") {
+		v = a.br
+	} else if a.gzip != nil && acceptsEncoding(accept, "gzip") {
+		v = a.gzip
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", a.contentType)
+	h.Set("Cache-Control", a.cacheControl)
+	h.Set("ETag", v.etag)
+	if a.br != nil || a.gzip != nil {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if v.encoding != "" {
+		h.Set("Content-Encoding", v.encoding)
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(v.content))
+}
+
+// acceptsEncoding Accept-Encoding 是否接受指定编码（q=0 表示拒绝）
+func acceptsEncoding(header string, encoding string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = parsed
+			}
+		}
+		// 明确列出的编码优先于 *
+		if strings.EqualFold(name, encoding) {
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
+
+// gzipBytes 以最高压缩率压缩
+func gzipBytes(content []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(content)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// compressible 是否值得压缩，图片和字体等已经压缩过的格式除外
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch mediaType {
+	case "text/html", "text/css", "text/plain", "application/javascript", "application/json", "image/svg+xml", "image/x-icon":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"path"
+	"strings"
+)
+
+// rewriteBasePath 为部署在子路径下的前端改写文件中以 / 开头的站内地址，不需要改写时返回 nil
+//
+// 前端按根路径构建：HTML 的 href、src 属性，以及 HTML、脚本、样式和 RSC 数据中引用的 /_next/ 文件
+// 都指向根路径，加上前缀后浏览器才能通过反向代理取到。以 // 开头的协议相对地址不改写。
+func rewriteBasePath(name string, content []byte, prefix string) []byte {
+	if prefix == "" {
+		return nil
+	}
+
+	var pairs []string
+	switch path.Ext(name) {
+	case ".html":
+		pairs = []string{
+			`href="//`, `href="//`,
+			`src="//`, `src="//`,
+			`href="/`, `href="` + prefix + `/`,
+			`src="/`, `src="` + prefix + `/`,
+			`action="/`, `action="` + prefix + `/`,
+		}
+	case ".js", ".css", ".txt":
+	default:
+		return nil
+	}
+	pairs = append(pairs,
+		`"/_next/`, `"`+prefix+`/_next/`,
+		`(/_next/`, `(`+prefix+`/_next/`,
+	)
+
+	rewritten := strings.NewReplacer(pairs...).Replace(string(content))
+	if rewritten == string(content) {
+		return nil
+	}
+	return []byte(rewritten)
+}
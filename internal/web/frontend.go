@@ -1,12 +1,19 @@
 package web
 
 import (
+	"context"
 	"embed"
-	"io"
+	"fmt"
 	"io/fs"
+	"log"
 	"net/http"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"sync"
+
+	"chuan/internal/config"
 )
 
 // 前端文件嵌入 - 这个路径会在构建脚本中被替换
@@ -23,18 +30,49 @@ func hasFrontendFiles() bool {
 	return len(entries) > 0
 }
 
-// CreateFrontendHandler 创建前端文件处理器
-func CreateFrontendHandler() http.Handler {
-	if !hasFrontendFiles() {
-		return &placeholderHandler{}
+// Options 前端文件服务选项
+type Options struct {
+	Dir      string                // 从磁盘目录提供前端文件，为空时使用嵌入的文件
+	Dev      bool                  // 开发模式：监视 Dir 中的文件变化，重新加载并通知已打开的页面刷新
+	BasePath string                // 部署在子路径下时的路径前缀，文件中的站内地址按此改写
+	Config   func() *config.Config // 当前配置，用于生成注入 HTML 页面的运行时配置，nil 表示不注入
+}
+
+// CreateFrontendHandler 创建前端文件处理器，ctx 结束时停止开发模式的文件监视
+func CreateFrontendHandler(ctx context.Context, opts Options) (http.Handler, error) {
+	var fsys fs.FS
+	if opts.Dir != "" {
+		if _, err := os.Stat(filepath.Join(opts.Dir, "index.html")); err != nil {
+			return nil, fmt.Errorf("前端目录 %s 中没有 index.html: %w", opts.Dir, err)
+		}
+		fsys = os.DirFS(opts.Dir)
+	} else {
+		if !hasFrontendFiles() {
+			return &placeholderHandler{}, nil
+		}
+		sub, err := fs.Sub(FrontendFiles, "frontend")
+		if err != nil {
+			return &placeholderHandler{}, nil
+		}
+		fsys = sub
 	}
 
-	frontendFS, err := fs.Sub(FrontendFiles, "frontend")
+	assets, err := loadAssets(fsys, !opts.Dev, opts.BasePath)
 	if err != nil {
-		return &placeholderHandler{}
+		return nil, fmt.Errorf("加载前端文件失败: %w", err)
 	}
+	h := &spaHandler{fsys: fsys, basePath: opts.BasePath, config: opts.Config, assets: assets, pages: make(map[string]*page)}
 
-	return &spaHandler{fs: frontendFS}
+	if opts.Dev && opts.Dir != "" {
+		version, err := fsVersion(fsys)
+		if err != nil {
+			return nil, fmt.Errorf("读取前端目录失败: %w", err)
+		}
+		h.live = newLiveReload(ctx, version)
+		go h.watch(ctx, version)
+		log.Printf("前端开发模式: 监视 %s 中的文件变化", opts.Dir)
+	}
+	return h, nil
 }
 
 // placeholderHandler 占位处理器
@@ -99,126 +137,94 @@ go build -o file-transfer-server ./cmd</pre>
 	`))
 }
 
-// spaHandler SPA 应用处理器
+// spaHandler SPA 应用处理器，文件在启动时读入内存
 type spaHandler struct {
-	fs fs.FS
+	fsys     fs.FS
+	basePath string
+	config   func() *config.Config
+	live     *liveReload // 开发模式的页面刷新通知，非开发模式为 nil
+
+	mu     sync.RWMutex
+	assets map[string]*asset // 相对路径 -> 文件，开发模式下文件变化时整体替换
+	pages  map[string]*page  // 注入脚本后的 HTML 页面
 }
 
 func (h *spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// 清理路径
-	upath := strings.TrimPrefix(r.URL.Path, "/")
+	upath := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
 	if upath == "" {
 		upath = "index.html"
 	}
-
-	// 尝试打开请求的文件
-	file, err := h.fs.Open(upath)
-	if err != nil {
-		// 文件不存在，对于 SPA 应用返回 index.html
-		h.serveIndexHTML(w, r)
+	if h.live != nil && upath == liveReloadPath {
+		h.live.ServeHTTP(w, r)
 		return
 	}
-	defer file.Close()
 
-	// 获取文件信息
-	stat, err := file.Stat()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	h.mu.RLock()
+	assets := h.assets
+	h.mu.RUnlock()
 
-	// 如果是目录，查找 index.html
-	if stat.IsDir() {
-		indexPath := path.Join(upath, "index.html")
-		indexFile, err := h.fs.Open(indexPath)
-		if err != nil {
-			h.serveIndexHTML(w, r)
+	// 请求的文件，或目录下的 index.html
+	for _, name := range []string{upath, path.Join(upath, "index.html")} {
+		if a := assets[name]; a != nil {
+			h.serve(w, r, name, a)
 			return
 		}
-		defer indexFile.Close()
-
-		h.serveFile(w, r, "index.html", indexFile)
-		return
 	}
 
-	// 服务静态文件
-	h.serveFile(w, r, stat.Name(), file)
-}
-
-// serveIndexHTML 服务 index.html 文件
-func (h *spaHandler) serveIndexHTML(w http.ResponseWriter, r *http.Request) {
-	file, err := h.fs.Open("index.html")
-	if err != nil {
+	// 构建产物带有内容哈希，缺失说明页面与服务器版本不一致，返回 index.html 只会导致脚本解析错误
+	if strings.HasPrefix(upath, "_next/static/") {
 		http.NotFound(w, r)
 		return
 	}
-	defer file.Close()
 
-	h.serveFile(w, r, "index.html", file)
-}
-
-// serveFile 服务文件
-func (h *spaHandler) serveFile(w http.ResponseWriter, r *http.Request, filename string, file fs.File) {
-	// 设置 Content-Type
-	setContentType(w, filename)
-
-	// 获取文件信息
-	stat, err := file.Stat()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// 读取文件内容
-	content, err := io.ReadAll(file)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// 其他路径由 SPA 路由处理，返回 index.html
+	if a := assets["index.html"]; a != nil {
+		h.serve(w, r, "index.html", a)
 		return
 	}
+	http.NotFound(w, r)
+}
 
-	// 设置缓存头
-	if shouldCache(filename) {
-		w.Header().Set("Cache-Control", "public, max-age=31536000") // 1年
-	} else {
-		w.Header().Set("Cache-Control", "no-cache")
+// serve 输出文件，HTML 页面注入运行时配置
+func (h *spaHandler) serve(w http.ResponseWriter, r *http.Request, name string, a *asset) {
+	if isPage(name) {
+		a = h.page(name, a)
 	}
-
-	// 服务内容
-	http.ServeContent(w, r, filename, stat.ModTime(), strings.NewReader(string(content)))
+	a.serve(w, r)
 }
 
-// setContentType 设置 Content-Type
-func setContentType(w http.ResponseWriter, filename string) {
-	ext := path.Ext(filename)
-	switch ext {
+// contentType 根据扩展名确定 Content-Type
+func contentType(filename string) string {
+	switch path.Ext(filename) {
 	case ".html":
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		return "text/html; charset=utf-8"
 	case ".css":
-		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		return "text/css; charset=utf-8"
 	case ".js":
-		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		return "application/javascript; charset=utf-8"
 	case ".json":
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		return "application/json; charset=utf-8"
 	case ".png":
-		w.Header().Set("Content-Type", "image/png")
+		return "image/png"
 	case ".jpg", ".jpeg":
-		w.Header().Set("Content-Type", "image/jpeg")
+		return "image/jpeg"
 	case ".gif":
-		w.Header().Set("Content-Type", "image/gif")
+		return "image/gif"
 	case ".svg":
-		w.Header().Set("Content-Type", "image/svg+xml")
+		return "image/svg+xml"
 	case ".ico":
-		w.Header().Set("Content-Type", "image/x-icon")
+		return "image/x-icon"
 	case ".woff":
-		w.Header().Set("Content-Type", "font/woff")
+		return "font/woff"
 	case ".woff2":
-		w.Header().Set("Content-Type", "font/woff2")
+		return "font/woff2"
 	case ".ttf":
-		w.Header().Set("Content-Type", "font/ttf")
+		return "font/ttf"
 	case ".txt":
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		return "text/plain; charset=utf-8"
 	default:
-		w.Header().Set("Content-Type", "application/octet-stream")
+		return "application/octet-stream"
 	}
 }
 
@@ -0,0 +1,164 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// liveReloadPath 开发模式下页面订阅文件变化的 SSE 地址
+const liveReloadPath = "_chuan/livereload"
+
+// liveReloadScript 开发模式注入页面的脚本：收到与首次不同的版本时刷新页面，服务器重启后重连也能发现变化
+func liveReloadScript(basePath string) string {
+	return `<script>(function(){var v;new EventSource("` + basePath + `/` + liveReloadPath + `").addEventListener("version",function(e){if(v&&v!==e.data)location.reload();v=e.data})})()</script>`
+}
+
+// 开发模式的轮询和心跳间隔
+const (
+	watchInterval     = 500 * time.Millisecond
+	liveReloadPingGap = 30 * time.Second
+)
+
+// liveReload 开发模式下向已打开的页面推送文件版本
+type liveReload struct {
+	ctx context.Context // 结束时断开所有连接
+
+	mu      sync.Mutex
+	version string
+	clients map[chan string]struct{}
+}
+
+func newLiveReload(ctx context.Context, version string) *liveReload {
+	return &liveReload{ctx: ctx, version: version, clients: make(map[chan string]struct{})}
+}
+
+// publish 更新版本并通知所有页面
+func (l *liveReload) publish(version string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.version = version
+	for ch := range l.clients {
+		select {
+		case ch <- version:
+		default: // 页面还没有处理上一次通知，稍后刷新时会读取最新版本
+		}
+	}
+}
+
+// subscribe 订阅版本变化，返回当前版本
+func (l *liveReload) subscribe() (chan string, string, func()) {
+	ch := make(chan string, 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clients[ch] = struct{}{}
+	return ch, l.version, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.clients, ch)
+	}
+}
+
+// ServeHTTP 以 SSE 推送文件版本，连接时先发送当前版本
+func (l *liveReload) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	updates, version, cancel := l.subscribe()
+	defer cancel()
+
+	// 长连接不受服务器写超时限制
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("SSE取消写超时失败: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ping := time.NewTicker(liveReloadPingGap)
+	defer ping.Stop()
+
+	for {
+		if version != "" {
+			if _, err := fmt.Fprintf(w, "event: version\ndata: %s\n\n", version); err != nil {
+				return
+			}
+			version = ""
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-l.ctx.Done():
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case version = <-updates:
+		}
+	}
+}
+
+// watch 定期检查文件变化，变化后重新加载文件并通知页面，ctx 结束时停止
+func (h *spaHandler) watch(ctx context.Context, version string) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := fsVersion(h.fsys)
+		if err != nil || current == version {
+			continue
+		}
+		assets, err := loadAssets(h.fsys, false, h.basePath)
+		if err != nil {
+			log.Printf("重新加载前端文件失败: %v", err)
+			continue
+		}
+
+		h.mu.Lock()
+		h.assets = assets
+		h.mu.Unlock()
+		version = current
+		h.live.publish(version)
+		log.Printf("前端文件已变化，已重新加载 %d 个文件", len(assets))
+	}
+}
+
+// fsVersion 根据文件名、大小和修改时间计算目录的版本
+func fsVersion(fsys fs.FS) (string, error) {
+	hash := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:8]), nil
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+
+	"chuan/internal/config"
+)
+
+// RuntimeConfig 注入 HTML 页面的运行时配置，前端通过 window.__CHUAN_CONFIG__ 读取
+type RuntimeConfig struct {
+	APIBaseURL string             `json:"api_base_url,omitempty"` // API 和信令地址，为空时使用页面所在的地址
+	BasePath   string             `json:"base_path,omitempty"`    // 部署在子路径下时的路径前缀
+	ICEServers []config.ICEServer `json:"ice_servers"`
+	Branding   config.Branding    `json:"branding"`
+	Features   map[string]bool    `json:"features"` // 服务器启用的功能
+}
+
+// NewRuntimeConfig 根据服务器配置生成页面的运行时配置，不包含任何密钥
+func NewRuntimeConfig(cfg *config.Config) RuntimeConfig {
+	return RuntimeConfig{
+		APIBaseURL: cfg.Web.APIBaseURL,
+		BasePath:   cfg.BasePath,
+		ICEServers: cfg.ICEServers,
+		Branding:   cfg.Web.Branding,
+		Features: map[string]bool{
+			"store": cfg.Store.Enabled,
+			"tus":   cfg.Tus.Enabled,
+			"relay": cfg.Relay.Enabled,
+			"auth":  cfg.Auth.Enabled,
+		},
+	}
+}
+
+// page 注入运行时配置后的 HTML 页面，原文件和脚本都不变时复用
+type page struct {
+	source *asset
+	script []byte
+	asset  *asset
+}
+
+// isPage 是否为需要注入运行时配置的 HTML 页面
+func isPage(name string) bool {
+	return path.Ext(name) == ".html"
+}
+
+// pageScript 注入页面的脚本：运行时配置和开发模式的自动刷新
+func (h *spaHandler) pageScript() []byte {
+	var buf bytes.Buffer
+	if h.config != nil {
+		// json.Marshal 会转义 <、> 和 &，内容不会提前结束 script 标签
+		data, _ := json.Marshal(NewRuntimeConfig(h.config()))
+		buf.WriteString("<script>window.__CHUAN_CONFIG__=")
+		buf.Write(data)
+		buf.WriteString(";</script>")
+	}
+	if h.live != nil {
+		buf.WriteString(liveReloadScript(h.basePath))
+	}
+	return buf.Bytes()
+}
+
+// page 返回注入脚本后的页面，配置变化后重新生成，ETag 随之变化
+func (h *spaHandler) page(name string, a *asset) *asset {
+	script := h.pageScript()
+	if len(script) == 0 {
+		return a
+	}
+
+	h.mu.RLock()
+	p := h.pages[name]
+	h.mu.RUnlock()
+	if p != nil && p.source == a && bytes.Equal(p.script, script) {
+		return p.asset
+	}
+
+	p = &page{source: a, script: script, asset: newAsset(name, injectScript(a.identity.content, script), nil, nil, true)}
+	h.mu.Lock()
+	h.pages[name] = p
+	h.mu.Unlock()
+	return p.asset
+}
+
+// injectScript 将脚本插入 <head> 之后，保证先于页面自身的脚本执行；没有 <head> 时插入开头
+func injectScript(html []byte, script []byte) []byte {
+	at := 0
+	if i := bytes.Index(bytes.ToLower(html), []byte("<head")); i >= 0 {
+		if end := bytes.IndexByte(html[i:], '>'); end >= 0 {
+			at = i + end + 1
+		}
+	}
+
+	out := make([]byte, 0, len(html)+len(script))
+	out = append(out, html[:at]...)
+	out = append(out, script...)
+	return append(out, html[at:]...)
+}
@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	mrand "math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"chuan/internal/config"
+)
+
+const (
+	// HeaderEvent 事件类型请求头
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderDelivery 投递ID请求头，重试时保持不变，接收方可以据此去重
+	HeaderDelivery = "X-Webhook-Delivery"
+	// HeaderTimestamp 签名时间戳请求头（Unix秒）
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature 签名请求头，格式为 sha256=<hex>
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event Webhook 事件负载
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// delivery 一次待投递的请求
+type delivery struct {
+	endpoint config.WebhookEndpoint
+	event    string
+	id       string
+	body     []byte
+	attempt  int
+}
+
+// endpointQueue 单个端点的待投递队列，由该端点自己的协程投递
+type endpointQueue struct {
+	endpoint config.WebhookEndpoint
+	queue    chan *delivery
+}
+
+// Dispatcher 在后台按端点订阅过滤并投递 Webhook，失败时按指数退避重试
+//
+// 每个端点有独立的队列和投递协程，一个端点响应慢或不可达时不影响其他端点。
+type Dispatcher struct {
+	endpoints  []*endpointQueue
+	maxRetries int
+	client     *http.Client
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewDispatcher 创建 Webhook 分发器，并为每个端点启动后台投递协程
+func NewDispatcher(cfg config.WebhookConfig) *Dispatcher {
+	d := &Dispatcher{
+		maxRetries: cfg.MaxRetries,
+		client:     &http.Client{Timeout: cfg.Timeout.Duration},
+		done:       make(chan struct{}),
+	}
+
+	for _, ep := range cfg.Endpoints {
+		q := &endpointQueue{
+			endpoint: ep,
+			queue:    make(chan *delivery, cfg.QueueSize),
+		}
+		d.endpoints = append(d.endpoints, q)
+
+		d.wg.Add(1)
+		go d.worker(q)
+	}
+
+	return d
+}
+
+// Enabled 是否配置了任何 Webhook 端点
+func (d *Dispatcher) Enabled() bool {
+	return len(d.endpoints) > 0
+}
+
+// Publish 将事件加入投递队列，不会阻塞调用方；队列已满时丢弃事件
+func (d *Dispatcher) Publish(eventType string, data interface{}) {
+	if !d.Enabled() {
+		return
+	}
+
+	event := Event{
+		ID:        "evt_" + randomID(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("序列化Webhook事件失败: %v", err)
+		return
+	}
+
+	for _, q := range d.endpoints {
+		if !subscribed(q.endpoint, eventType) {
+			continue
+		}
+		d.enqueue(q, &delivery{
+			endpoint: q.endpoint,
+			event:    eventType,
+			id:       event.ID,
+			body:     body,
+		})
+	}
+}
+
+// Close 停止接收新事件，并等待后台协程退出
+func (d *Dispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.done)
+	})
+	d.wg.Wait()
+}
+
+// enqueue 非阻塞加入端点的队列
+func (d *Dispatcher) enqueue(q *endpointQueue, dl *delivery) {
+	select {
+	case <-d.done:
+		return
+	default:
+	}
+
+	select {
+	case q.queue <- dl:
+	default:
+		log.Printf("Webhook队列已满，丢弃事件: %s -> %s", dl.event, dl.endpoint.URL)
+	}
+}
+
+// worker 端点的后台投递协程
+func (d *Dispatcher) worker(q *endpointQueue) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case dl := <-q.queue:
+			d.deliver(q, dl)
+		}
+	}
+}
+
+// deliver 投递一次，失败时在退避时间后重新加入端点的队列，等待期间不占用投递协程
+func (d *Dispatcher) deliver(q *endpointQueue, dl *delivery) {
+	err := d.send(dl)
+	if err == nil {
+		log.Printf("Webhook投递成功: %s -> %s", dl.event, dl.endpoint.URL)
+		return
+	}
+
+	if dl.attempt >= d.maxRetries {
+		log.Printf("Webhook投递失败，已放弃: %s -> %s (尝试 %d 次): %v", dl.event, dl.endpoint.URL, dl.attempt+1, err)
+		return
+	}
+
+	wait := backoff(dl.attempt)
+	dl.attempt++
+	log.Printf("Webhook投递失败，%s 后重试: %s -> %s: %v", wait, dl.event, dl.endpoint.URL, err)
+	time.AfterFunc(wait, func() { d.enqueue(q, dl) })
+}
+
+// send 发送签名后的 HTTP 请求
+func (d *Dispatcher) send(dl *delivery) error {
+	req, err := http.NewRequest(http.MethodPost, dl.endpoint.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chuan-webhook/1.0")
+	req.Header.Set(HeaderEvent, dl.event)
+	req.Header.Set(HeaderDelivery, dl.id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(dl.endpoint.Secret, timestamp, dl.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("接收端返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body)，接收方用同样方式校验
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// subscribed 判断端点是否订阅了该事件
+func subscribed(ep config.WebhookEndpoint, eventType string) bool {
+	if len(ep.Events) == 0 {
+		return true
+	}
+	for _, e := range ep.Events {
+		if e == eventType || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 计算第 attempt 次失败后的等待时间：1s、2s、4s…，最长 5 分钟，附加随机抖动
+func backoff(attempt int) time.Duration {
+	wait := time.Second << uint(attempt)
+	if wait <= 0 || wait > 5*time.Minute {
+		wait = 5 * time.Minute
+	}
+	jitter := time.Duration(mrand.Int63n(int64(wait) / 4))
+	return wait + jitter
+}
+
+// randomID 生成随机ID
+func randomID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chuan/internal/config"
+)
+
+func TestSlowEndpointDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	received := make(chan string, 10)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(HeaderEvent)
+	}))
+	defer fast.Close()
+
+	cfg := config.Default().Webhook
+	cfg.Endpoints = []config.WebhookEndpoint{
+		{URL: slow.URL, Secret: "slow"},
+		{URL: fast.URL, Secret: "fast"},
+	}
+	d := NewDispatcher(cfg)
+
+	// 慢端点卡在第一个事件上时，快端点仍能依次收到所有事件
+	for _, event := range []string{"room.created", "room.closed"} {
+		d.Publish(event, nil)
+		select {
+		case got := <-received:
+			if got != event {
+				t.Fatalf("收到 %s，期望 %s", got, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s 没有投递到快端点", event)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package client 是文件快传信令服务器的 Go 客户端，
+// 用于在浏览器之外（命令行工具、自动化脚本、服务端程序）加入房间并交换 WebRTC 信令。
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// 角色
+const (
+	RoleSender   = "sender"
+	RoleReceiver = "receiver"
+)
+
+// Message 信令消息，与服务器的消息格式一致
+type Message struct {
+	Type     string          `json:"type"`
+	From     string          `json:"from,omitempty"`
+	To       string          `json:"to,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Envelope string          `json:"envelope,omitempty"` // 非空表示 Payload 为端到端加密的密文
+	Error    string          `json:"error,omitempty"`    // error 消息的错误描述
+	Code     string          `json:"code,omitempty"`     // error 消息的错误码，例如 ROOM_FULL
+}
+
+// Decode 将消息负载解码到 v
+func (m *Message) Decode(v interface{}) error {
+	if len(m.Payload) == 0 {
+		return fmt.Errorf("消息 %s 没有负载", m.Type)
+	}
+	return json.Unmarshal(m.Payload, v)
+}
+
+// Client 信令连接
+type Client struct {
+	conn *websocket.Conn
+	code string
+	role string
+
+	writeMu sync.Mutex
+}
+
+// Dial 连接信令服务器并加入房间
+//
+// serverURL 为服务器根地址，例如 https://transfer.example.com，
+// 会自动转换为对应的 ws:// 或 wss:// 地址。
+func Dial(ctx context.Context, serverURL string, code string, role string) (*Client, error) {
+	return DialWithHeader(ctx, serverURL, code, role, nil)
+}
+
+// DialWithHeader 携带额外请求头连接信令服务器，
+// 服务器启用身份认证时可以通过 Authorization: Bearer <JWT> 登录
+func DialWithHeader(ctx context.Context, serverURL string, code string, role string, header http.Header) (*Client, error) {
+	if role != RoleSender && role != RoleReceiver {
+		return nil, fmt.Errorf("无效的角色: %s", role)
+	}
+
+	wsURL, err := signalingURL(serverURL, code, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if header == nil {
+		header = http.Header{}
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("连接信令服务器失败 (HTTP %d): %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("连接信令服务器失败: %w", err)
+	}
+
+	return &Client{conn: conn, code: code, role: role}, nil
+}
+
+// Code 房间码
+func (c *Client) Code() string {
+	return c.code
+}
+
+// Role 当前角色
+func (c *Client) Role() string {
+	return c.role
+}
+
+// Send 发送信令消息，payload 会被编码为 JSON
+func (c *Client) Send(msgType string, payload interface{}) error {
+	msg := Message{Type: msgType}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = data
+	}
+	return c.SendMessage(&msg)
+}
+
+// SendMessage 发送原始信令消息
+func (c *Client) SendMessage(msg *Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.conn.WriteJSON(msg)
+}
+
+// Receive 阻塞读取下一条信令消息
+func (c *Client) Receive() (*Message, error) {
+	var msg Message
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	c.writeMu.Lock()
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMu.Unlock()
+
+	return c.conn.Close()
+}
+
+// signalingURL 根据服务器地址构造信令 WebSocket 地址
+func signalingURL(serverURL string, code string, role string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("无效的服务器地址: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("不支持的协议: %s", u.Scheme)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws/webrtc"
+	query := url.Values{}
+	query.Set("code", code)
+	query.Set("role", role)
+	u.RawQuery = query.Encode()
+	u.Fragment = "" // URL 片段中可能包含端到端加密密钥，不能发送给服务器
+	return u.String(), nil
+}
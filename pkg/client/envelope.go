@@ -0,0 +1,117 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// EnvelopeE2EV1 端到端加密信封格式，与服务器一致
+//
+// 负载为 JSON 字符串，内容是 base64url(nonce || AES-256-GCM 密文)，
+// 消息类型作为附加数据。服务器仍按消息类型路由和缓存 offer，但看不到 SDP 和 ICE 候选。
+const EnvelopeE2EV1 = "e2e-v1"
+
+// secretSize URL 片段密钥的字节数
+const secretSize = 32
+
+// EnvelopeKey 信令负载加密密钥
+type EnvelopeKey struct {
+	aead cipher.AEAD
+}
+
+// GenerateSecret 生成放在分享链接 URL 片段中的随机密钥
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// SplitSecret 拆分分享链接，返回去掉片段的地址和片段中的密钥
+//
+// 浏览器不会把 URL 片段发送给服务器，密钥只在收发双方之间传递。
+func SplitSecret(rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("无效的链接: %w", err)
+	}
+	secret := u.Fragment
+	u.Fragment = ""
+	return u.String(), secret, nil
+}
+
+// NewEnvelopeKey 由 URL 片段密钥和房间码派生加密密钥
+func NewEnvelopeKey(secret string, code string) (*EnvelopeKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("无效的密钥: %w", err)
+	}
+	if len(raw) < 16 {
+		return nil, fmt.Errorf("密钥过短")
+	}
+
+	block, err := aes.NewCipher(hkdf(raw, []byte(code), []byte("chuan-e2e-v1"), 32))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &EnvelopeKey{aead: aead}, nil
+}
+
+// Seal 加密 v 的 JSON 编码，返回可直接作为消息负载的 JSON 字符串
+func (k *EnvelopeKey) Seal(msgType string, v interface{}) (json.RawMessage, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := k.aead.Seal(nonce, nonce, plaintext, []byte(msgType))
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(sealed))
+}
+
+// Open 解密加密信封中的负载并解码到 v
+func (k *EnvelopeKey) Open(msg *Message, v interface{}) error {
+	if msg.Envelope != EnvelopeE2EV1 {
+		return fmt.Errorf("消息 %s 未加密", msg.Type)
+	}
+	var encoded string
+	if err := json.Unmarshal(msg.Payload, &encoded); err != nil {
+		return fmt.Errorf("加密负载格式无效: %w", err)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("加密负载格式无效: %w", err)
+	}
+
+	nonceSize := k.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return fmt.Errorf("加密数据过短")
+	}
+	plaintext, err := k.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(msg.Type))
+	if err != nil {
+		return fmt.Errorf("解密失败: %w", err)
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+// SendEncrypted 加密负载后发送信令消息，例如 SendEncrypted(key, "offer", offer)
+func (c *Client) SendEncrypted(key *EnvelopeKey, msgType string, payload interface{}) error {
+	sealed, err := key.Seal(msgType, payload)
+	if err != nil {
+		return err
+	}
+	return c.SendMessage(&Message{Type: msgType, Payload: sealed, Envelope: EnvelopeE2EV1})
+}
@@ -0,0 +1,452 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"filippo.io/nistec"
+)
+
+// PAKE 相关的信令消息类型，服务器只转发不解析
+const (
+	MessagePAKEInit    = "pake-init"    // 发送方的 SPAKE2 消息
+	MessagePAKEReply   = "pake-reply"   // 接收方的 SPAKE2 消息和密钥确认
+	MessagePAKEConfirm = "pake-confirm" // 发送方的密钥确认
+	MessagePAKEOffer   = "pake-offer"   // 加密后的 SDP offer
+	MessagePAKEAnswer  = "pake-answer"  // 加密后的 SDP answer
+	MessagePAKEICE     = "pake-ice"     // 加密后的 ICE 候选
+)
+
+// ErrPAKEConfirmation 密钥确认失败：口令不一致，或信令服务器篡改了消息
+var ErrPAKEConfirmation = errors.New("PAKE 密钥确认失败")
+
+// SPAKE2 (RFC 9382) 在 P-256 上使用的 M、N 常量（压缩格式）
+const (
+	spake2M = "02886e2f97ace46e55ba9dd7242579f2993b64e16ef3dcab95afd497333d8fa12f"
+	spake2N = "03d8bbd6c639c62937b04d997f38c3770719c629d7014d49a24b4f98baa1292b49"
+)
+
+// spake2MPoint、spake2NPoint 解码后的 M、N，只作为运算的输入，不能修改
+var (
+	spake2MPoint = mustPoint(spake2M)
+	spake2NPoint = mustPoint(spake2N)
+)
+
+// p256Order P-256 的阶 n
+var p256Order, _ = new(big.Int).SetString("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551", 16)
+
+// scalarSize 标量和域元素的字节数
+const scalarSize = 32
+
+// 取件码口令的字符集（Crockford base32，去掉易混淆的 i、l、o、u）和长度，共 40 位
+const (
+	secretAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+	secretLength   = 8
+)
+
+// PickupCode 分享给接收方的取件码，格式为 <房间码>-<口令>
+//
+// 房间码（nameplate）用于在信令服务器上找到房间，会出现在 /ws/webrtc?code= 中；
+// 口令只在收发双方之间传递，从不发送给服务器，是 PAKE 唯一的密码。
+// 因此信令服务器即使同时扮演双方，也无法通过密钥确认。
+type PickupCode struct {
+	Nameplate string
+	Secret    string
+}
+
+// NewPickupCode 为房间生成随机口令，得到完整的取件码
+func NewPickupCode(nameplate string) (PickupCode, error) {
+	if nameplate == "" {
+		return PickupCode{}, fmt.Errorf("房间码不能为空")
+	}
+	b := make([]byte, secretLength)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return PickupCode{}, err
+	}
+	for i := range b {
+		b[i] = secretAlphabet[b[i]%byte(len(secretAlphabet))]
+	}
+	return PickupCode{Nameplate: nameplate, Secret: string(b)}, nil
+}
+
+// ParsePickupCode 解析 <房间码>-<口令> 形式的取件码，口令不区分大小写
+func ParsePickupCode(s string) (PickupCode, error) {
+	nameplate, secret, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok || nameplate == "" || secret == "" {
+		return PickupCode{}, fmt.Errorf("无效的取件码 %q，格式应为 <房间码>-<口令>", s)
+	}
+	return PickupCode{Nameplate: nameplate, Secret: strings.ToLower(secret)}, nil
+}
+
+// String 完整的取件码
+func (c PickupCode) String() string {
+	return c.Nameplate + "-" + c.Secret
+}
+
+// PAKE 一次 SPAKE2 交换
+//
+// 取件码中的口令作为低熵共享密钥：双方各发送一条消息后得到相同的会话密钥，
+// 信令服务器即使转发了全部消息也无法得到密钥，篡改消息会导致密钥确认失败。
+// 发送方为 SPAKE2 中的 A，接收方为 B。
+type PAKE struct {
+	role string
+	idA  []byte
+	idB  []byte
+	w    []byte // 由口令派生的标量
+	x    []byte // 本端随机数
+	msg  []byte // 本端发出的消息
+}
+
+// PAKESession PAKE 完成后的会话，用于密钥确认和加密信令
+type PAKESession struct {
+	aead         cipher.AEAD
+	localConfirm []byte
+	peerConfirm  []byte
+}
+
+// NewPAKE 创建 PAKE 交换，房间码只作为双方的身份标识，口令作为密码
+func NewPAKE(role string, code PickupCode) (*PAKE, error) {
+	if code.Secret == "" {
+		return nil, fmt.Errorf("取件码缺少口令，口令不能只使用房间码")
+	}
+	idA := []byte("chuan/" + code.Nameplate + "/" + RoleSender)
+	idB := []byte("chuan/" + code.Nameplate + "/" + RoleReceiver)
+
+	// w = H(idA || idB || 口令) mod n
+	h := sha256.New()
+	writeLengthPrefixed(h, []byte("chuan-spake2-w"))
+	writeLengthPrefixed(h, idA)
+	writeLengthPrefixed(h, idB)
+	writeLengthPrefixed(h, []byte(code.Secret))
+	w := new(big.Int).Mod(new(big.Int).SetBytes(h.Sum(nil)), p256Order).FillBytes(make([]byte, scalarSize))
+
+	x, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	return newSPAKE2(role, idA, idB, w, x)
+}
+
+// newSPAKE2 使用给定的标量 w 和随机数 x 创建交换，x 和 w 均为 32 字节大端编码
+func newSPAKE2(role string, idA, idB, w, x []byte) (*PAKE, error) {
+	if role != RoleSender && role != RoleReceiver {
+		return nil, fmt.Errorf("无效的角色: %s", role)
+	}
+
+	// A: pA = x*G + w*M，B: pB = y*G + w*N
+	blind := spake2MPoint
+	if role == RoleReceiver {
+		blind = spake2NPoint
+	}
+	xG, err := nistec.NewP256Point().ScalarBaseMult(x)
+	if err != nil {
+		return nil, err
+	}
+	wM, err := nistec.NewP256Point().ScalarMult(blind, w)
+	if err != nil {
+		return nil, err
+	}
+	msg := nistec.NewP256Point().Add(xG, wM).Bytes()
+
+	return &PAKE{role: role, idA: idA, idB: idB, w: w, x: x, msg: msg}, nil
+}
+
+// Message 本端需要发送给对方的 SPAKE2 消息
+func (p *PAKE) Message() []byte {
+	return p.msg
+}
+
+// Finish 处理对方的 SPAKE2 消息，得到会话密钥
+func (p *PAKE) Finish(peerMessage []byte) (*PAKESession, error) {
+	peer, err := nistec.NewP256Point().SetBytes(peerMessage)
+	if err != nil || len(peerMessage) == 1 {
+		return nil, fmt.Errorf("无效的 PAKE 消息")
+	}
+
+	// 去掉对方消息中的 w*N（或 w*M），K = x * (Q - w*N)
+	blind := spake2NPoint
+	pA, pB := p.msg, peerMessage
+	if p.role == RoleReceiver {
+		blind = spake2MPoint
+		pA, pB = peerMessage, p.msg
+	}
+	// -w*N 用标量 (n - w) mod n 计算
+	negW := new(big.Int).Mod(new(big.Int).Neg(new(big.Int).SetBytes(p.w)), p256Order).FillBytes(make([]byte, scalarSize))
+	negWN, err := nistec.NewP256Point().ScalarMult(blind, negW)
+	if err != nil {
+		return nil, err
+	}
+	t := nistec.NewP256Point().Add(peer, negWN)
+	k, err := nistec.NewP256Point().ScalarMult(t, p.x)
+	if err != nil {
+		return nil, err
+	}
+	kBytes := k.Bytes()
+	if len(kBytes) == 1 {
+		return nil, fmt.Errorf("无效的 PAKE 消息")
+	}
+
+	tt := spake2Transcript(p.idA, p.idB, pA, pB, kBytes, p.w)
+	keys := spake2KeySchedule(tt)
+
+	block, err := aes.NewCipher(hkdf(keys.ke, nil, []byte("chuan-pake-signaling"), 32))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &PAKESession{aead: aead}
+	if p.role == RoleSender {
+		session.localConfirm, session.peerConfirm = keys.confirmA, keys.confirmB
+	} else {
+		session.localConfirm, session.peerConfirm = keys.confirmB, keys.confirmA
+	}
+	return session, nil
+}
+
+// spake2Transcript TT = len||idA || len||idB || len||pA || len||pB || len||K || len||w（RFC 9382 第 3.3 节）
+func spake2Transcript(idA, idB, pA, pB, k, w []byte) []byte {
+	var tt bytes.Buffer
+	for _, field := range [][]byte{idA, idB, pA, pB, k, w} {
+		writeLengthPrefixed(&tt, field)
+	}
+	return tt.Bytes()
+}
+
+// spake2Keys 由 TT 派生的密钥和双方的密钥确认值
+type spake2Keys struct {
+	ke, ka             []byte
+	kcA, kcB           []byte
+	confirmA, confirmB []byte
+}
+
+// spake2KeySchedule Ke || Ka = Hash(TT)，KcA || KcB = KDF(Ka, nil, "ConfirmationKeys")，
+// cA = MAC(KcA, TT)，cB = MAC(KcB, TT)（RFC 9382 第 4 节）
+func spake2KeySchedule(tt []byte) spake2Keys {
+	sum := sha256.Sum256(tt)
+	keys := spake2Keys{ke: sum[:16], ka: sum[16:]}
+
+	confirmKeys := hkdf(keys.ka, nil, []byte("ConfirmationKeys"), 32)
+	keys.kcA, keys.kcB = confirmKeys[:16], confirmKeys[16:]
+	keys.confirmA = hmacSHA256(keys.kcA, tt)
+	keys.confirmB = hmacSHA256(keys.kcB, tt)
+	return keys
+}
+
+// Confirmation 本端的密钥确认值，发送给对方校验
+func (s *PAKESession) Confirmation() []byte {
+	return s.localConfirm
+}
+
+// VerifyPeer 校验对方的密钥确认值
+func (s *PAKESession) VerifyPeer(confirmation []byte) error {
+	if !hmac.Equal(confirmation, s.peerConfirm) {
+		return ErrPAKEConfirmation
+	}
+	return nil
+}
+
+// Seal 用会话密钥加密 v 的 JSON 编码，msgType 作为附加数据防止不同类型的消息互相替换
+func (s *PAKESession) Seal(msgType string, v interface{}) ([]byte, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(msgType)), nil
+}
+
+// Open 解密 Seal 生成的数据并解码到 v
+func (s *PAKESession) Open(msgType string, sealed []byte, v interface{}) error {
+	nonceSize := s.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return fmt.Errorf("加密数据过短")
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(msgType))
+	if err != nil {
+		return fmt.Errorf("解密失败: %w", err)
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+// pakeMessage pake-* 消息负载
+type pakeMessage struct {
+	Message      []byte `json:"msg,omitempty"`
+	Confirmation []byte `json:"confirm,omitempty"`
+	Sealed       []byte `json:"sealed,omitempty"`
+	Failed       bool   `json:"failed,omitempty"` // 发送方密钥确认失败，通知接收方终止
+}
+
+// ExchangePAKE 通过信令连接完成 PAKE 交换和密钥确认
+//
+// secret 为取件码中的口令（PickupCode.Secret），连接使用的房间码作为身份标识。
+// 发送方发送 pake-init 后等待 pake-reply，接收方等待 pake-init 后回复；
+// 交换期间收到的 peer-joined 等通知消息会被忽略。
+func (c *Client) ExchangePAKE(secret string) (*PAKESession, error) {
+	pake, err := NewPAKE(c.role, PickupCode{Nameplate: c.code, Secret: secret})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.role == RoleSender {
+		if err := c.Send(MessagePAKEInit, pakeMessage{Message: pake.Message()}); err != nil {
+			return nil, err
+		}
+		reply, err := c.waitPAKE(MessagePAKEReply)
+		if err != nil {
+			return nil, err
+		}
+		session, err := pake.Finish(reply.Message)
+		if err != nil {
+			return nil, err
+		}
+		if err := session.VerifyPeer(reply.Confirmation); err != nil {
+			c.Send(MessagePAKEConfirm, pakeMessage{Failed: true})
+			return nil, err
+		}
+		if err := c.Send(MessagePAKEConfirm, pakeMessage{Confirmation: session.Confirmation()}); err != nil {
+			return nil, err
+		}
+		return session, nil
+	}
+
+	init, err := c.waitPAKE(MessagePAKEInit)
+	if err != nil {
+		return nil, err
+	}
+	session, err := pake.Finish(init.Message)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Send(MessagePAKEReply, pakeMessage{Message: pake.Message(), Confirmation: session.Confirmation()}); err != nil {
+		return nil, err
+	}
+	confirm, err := c.waitPAKE(MessagePAKEConfirm)
+	if err != nil {
+		return nil, err
+	}
+	if confirm.Failed {
+		return nil, ErrPAKEConfirmation
+	}
+	if err := session.VerifyPeer(confirm.Confirmation); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SendSealed 加密后发送信令，例如 SendSealed(session, MessagePAKEOffer, offer)
+func (c *Client) SendSealed(session *PAKESession, msgType string, v interface{}) error {
+	sealed, err := session.Seal(msgType, v)
+	if err != nil {
+		return err
+	}
+	return c.Send(msgType, pakeMessage{Sealed: sealed})
+}
+
+// OpenSealed 解密收到的 pake-offer / pake-answer / pake-ice 消息
+func OpenSealed(session *PAKESession, msg *Message, v interface{}) error {
+	var payload pakeMessage
+	if err := msg.Decode(&payload); err != nil {
+		return err
+	}
+	return session.Open(msg.Type, payload.Sealed, v)
+}
+
+// waitPAKE 等待指定类型的 pake 消息
+func (c *Client) waitPAKE(msgType string) (*pakeMessage, error) {
+	for {
+		msg, err := c.Receive()
+		if err != nil {
+			return nil, err
+		}
+		switch msg.Type {
+		case msgType:
+			var payload pakeMessage
+			if err := msg.Decode(&payload); err != nil {
+				return nil, err
+			}
+			return &payload, nil
+		case "error":
+			return nil, fmt.Errorf("信令服务器错误: %s", msg.Error)
+		case "disconnection", "room-expired":
+			return nil, fmt.Errorf("PAKE 交换中断: %s", msg.Type)
+		}
+	}
+}
+
+// randomScalar 生成 [1, n) 范围内的随机数，32 字节大端编码
+func randomScalar() ([]byte, error) {
+	for {
+		k, err := rand.Int(rand.Reader, p256Order)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() > 0 {
+			return k.FillBytes(make([]byte, scalarSize)), nil
+		}
+	}
+}
+
+// writeLengthPrefixed 写入 8 字节小端长度前缀和数据（RFC 9382 的 TT 编码）
+func writeLengthPrefixed(w io.Writer, data []byte) {
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(data)))
+	w.Write(length[:])
+	w.Write(data)
+}
+
+// hkdf HKDF-SHA256（RFC 5869）
+func hkdf(secret []byte, salt []byte, info []byte, length int) []byte {
+	if salt == nil {
+		salt = make([]byte, sha256.Size)
+	}
+	prk := hmacSHA256(salt, secret)
+
+	var out, block []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(block)
+		mac.Write(info)
+		mac.Write([]byte{counter})
+		block = mac.Sum(nil)
+		out = append(out, block...)
+	}
+	return out[:length]
+}
+
+func hmacSHA256(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// mustPoint 解码压缩格式的常量点
+func mustPoint(s string) *nistec.P256Point {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	p, err := nistec.NewP256Point().SetBytes(data)
+	if err != nil {
+		panic("无效的 SPAKE2 常量")
+	}
+	return p
+}
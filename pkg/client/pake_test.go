@@ -0,0 +1,234 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("无效的十六进制: %v", err)
+	}
+	return b
+}
+
+// RFC 9382 附录 B：SPAKE2-P256-SHA256-HKDF-HMAC，A='server'，B='client'
+func TestSPAKE2RFC9382Vector(t *testing.T) {
+	idA, idB := []byte("server"), []byte("client")
+	w := mustHex(t, "2ee57912099d31560b3a44b1184b9b4866e904c49d12ac5042c97dca461b1a5f")
+	x := mustHex(t, "43dd0fd7215bdcb482879fca3220c6a968e66d70b1356cac18bb26c84a78d729")
+	y := mustHex(t, "dcb60106f276b02606d8ef0a328c02e4b629f84f89786af5befb0bc75b6e66be")
+	wantPA := mustHex(t, "04a56fa807caaa53a4d28dbb9853b9815c61a411118a6fe516a8798434751470f9010153ac33d0d5f2047ffdb1a3e42c9b4e6be662766e1eeb4116988ede5f912c")
+	wantPB := mustHex(t, "0406557e482bd03097ad0cbaa5df82115460d951e3451962f1eaf4367a420676d09857ccbc522686c83d1852abfa8ed6e4a1155cf8f1543ceca528afb591a1e0b7")
+	wantK := mustHex(t, "0412af7e89717850671913e6b469ace67bd90a4df8ce45c2af19010175e37eed69f75897996d539356e2fa6a406d528501f907e04d97515fbe83db277b715d3325")
+	wantKe := mustHex(t, "0e0672dc86f8e45565d338b0540abe69")
+	wantKa := mustHex(t, "15bdf72e2b35b5c9e5663168e960a91b")
+	wantKcA := mustHex(t, "00c12546835755c86d8c0db7851ae86f")
+	wantKcB := mustHex(t, "a9fa3406c3b781b93d804485430ca27a")
+	wantCA := mustHex(t, "58ad4aa88e0b60d5061eb6b5dd93e80d9c4f00d127c65b3b35b1b5281fee38f0")
+	wantCB := mustHex(t, "d3e2e547f1ae04f2dbdbf0fc4b79f8ecff2dff314b5d32fe9fcef2fb26dc459b")
+
+	a, err := newSPAKE2(RoleSender, idA, idB, w, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newSPAKE2(RoleReceiver, idA, idB, w, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Message(), wantPA) {
+		t.Fatalf("pA = %x，期望 %x", a.Message(), wantPA)
+	}
+	if !bytes.Equal(b.Message(), wantPB) {
+		t.Fatalf("pB = %x，期望 %x", b.Message(), wantPB)
+	}
+
+	keys := spake2KeySchedule(spake2Transcript(idA, idB, wantPA, wantPB, wantK, w))
+	for _, c := range []struct {
+		name      string
+		got, want []byte
+	}{
+		{"Ke", keys.ke, wantKe},
+		{"Ka", keys.ka, wantKa},
+		{"KcA", keys.kcA, wantKcA},
+		{"KcB", keys.kcB, wantKcB},
+		{"cA", keys.confirmA, wantCA},
+		{"cB", keys.confirmB, wantCB},
+	} {
+		if !bytes.Equal(c.got, c.want) {
+			t.Errorf("%s = %x，期望 %x", c.name, c.got, c.want)
+		}
+	}
+
+	// 双方由对方的消息得到的共享点 K 与向量一致，确认值也一致
+	sessionA, err := a.Finish(b.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionB, err := b.Finish(a.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sessionA.Confirmation(), wantCA) || !bytes.Equal(sessionB.Confirmation(), wantCB) {
+		t.Fatalf("确认值与向量不一致: cA=%x cB=%x", sessionA.Confirmation(), sessionB.Confirmation())
+	}
+}
+
+// exchange 在内存中完成一次交换，返回双方的会话
+func exchange(t *testing.T, senderCode, receiverCode PickupCode) (*PAKESession, *PAKESession) {
+	t.Helper()
+	sender, err := NewPAKE(RoleSender, senderCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := NewPAKE(RoleReceiver, receiverCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := sender.Finish(receiver.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := receiver.Finish(sender.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, r
+}
+
+func TestPAKERoundTrip(t *testing.T) {
+	code, err := NewPickupCode("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, r := exchange(t, code, code)
+	if err := s.VerifyPeer(r.Confirmation()); err != nil {
+		t.Fatalf("发送方校验失败: %v", err)
+	}
+	if err := r.VerifyPeer(s.Confirmation()); err != nil {
+		t.Fatalf("接收方校验失败: %v", err)
+	}
+
+	sealed, err := s.Seal(MessagePAKEOffer, map[string]string{"sdp": "v=0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	if err := r.Open(MessagePAKEOffer, sealed, &got); err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if got["sdp"] != "v=0" {
+		t.Fatalf("解密结果 %v", got)
+	}
+	// 消息类型作为附加数据，换成其他类型无法解密
+	if err := r.Open(MessagePAKEAnswer, sealed, &got); err == nil {
+		t.Fatal("不同消息类型的密文不应能解密")
+	}
+}
+
+func TestPAKEWrongPassword(t *testing.T) {
+	s, r := exchange(t, PickupCode{Nameplate: "123456", Secret: "abcd2345"}, PickupCode{Nameplate: "123456", Secret: "abcd2346"})
+	if err := s.VerifyPeer(r.Confirmation()); !errors.Is(err, ErrPAKEConfirmation) {
+		t.Fatalf("口令不同时发送方应校验失败，得到 %v", err)
+	}
+	if err := r.VerifyPeer(s.Confirmation()); !errors.Is(err, ErrPAKEConfirmation) {
+		t.Fatalf("口令不同时接收方应校验失败，得到 %v", err)
+	}
+}
+
+// 服务器只知道房间码：用房间码猜测口令，或把房间码当作口令，都无法通过确认
+func TestPAKENameplateIsNotPassword(t *testing.T) {
+	code := PickupCode{Nameplate: "123456", Secret: "abcd2345"}
+	s, r := exchange(t, code, PickupCode{Nameplate: "123456", Secret: "123456"})
+	if err := s.VerifyPeer(r.Confirmation()); !errors.Is(err, ErrPAKEConfirmation) {
+		t.Fatalf("只知道房间码的一方不应通过确认，得到 %v", err)
+	}
+	if _, err := NewPAKE(RoleSender, PickupCode{Nameplate: "123456"}); err == nil {
+		t.Fatal("缺少口令时应拒绝创建 PAKE")
+	}
+}
+
+func TestPAKETamperedMessage(t *testing.T) {
+	code := PickupCode{Nameplate: "123456", Secret: "abcd2345"}
+	sender, err := NewPAKE(RoleSender, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := NewPAKE(RoleReceiver, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务器用自己的 SPAKE2 消息（不知道口令）替换接收方的消息
+	attacker, err := NewPAKE(RoleReceiver, PickupCode{Nameplate: "123456", Secret: "guess000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := sender.Finish(attacker.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := receiver.Finish(sender.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyPeer(r.Confirmation()); !errors.Is(err, ErrPAKEConfirmation) {
+		t.Fatalf("消息被替换后应校验失败，得到 %v", err)
+	}
+
+	// 修改点的编码：不在曲线上的点和无穷远点直接拒绝
+	tampered := append([]byte(nil), receiver.Message()...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := sender.Finish(tampered); err == nil {
+		t.Fatal("不在曲线上的点应被拒绝")
+	}
+	if _, err := sender.Finish([]byte{0}); err == nil {
+		t.Fatal("无穷远点应被拒绝")
+	}
+
+	// 修改确认值
+	s, r = exchange(t, code, code)
+	confirm := append([]byte(nil), r.Confirmation()...)
+	confirm[0] ^= 1
+	if err := s.VerifyPeer(confirm); !errors.Is(err, ErrPAKEConfirmation) {
+		t.Fatalf("确认值被修改后应校验失败，得到 %v", err)
+	}
+
+	// 修改密文
+	sealed, err := s.Seal(MessagePAKEICE, "candidate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	var v string
+	if err := r.Open(MessagePAKEICE, sealed, &v); err == nil {
+		t.Fatal("被修改的密文不应能解密")
+	}
+}
+
+func TestParsePickupCode(t *testing.T) {
+	code, err := NewPickupCode("654321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code.Secret) != secretLength {
+		t.Fatalf("口令长度 %d", len(code.Secret))
+	}
+	parsed, err := ParsePickupCode(" " + code.String() + " ")
+	if err != nil || parsed != code {
+		t.Fatalf("ParsePickupCode(%q) = %+v, %v", code.String(), parsed, err)
+	}
+	upper, err := ParsePickupCode("654321-ABCD2345")
+	if err != nil || upper.Secret != "abcd2345" {
+		t.Fatalf("口令应不区分大小写: %+v, %v", upper, err)
+	}
+	for _, s := range []string{"654321", "654321-", "-abcd2345", ""} {
+		if _, err := ParsePickupCode(s); err == nil {
+			t.Errorf("ParsePickupCode(%q) 应返回错误", s)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// 短认证字符串（SAS）校验相关的信令消息类型
+const (
+	MessageVerifyFingerprint = "verify-fingerprint"
+	MessageVerifyConfirm     = "verify-confirm"
+	MessageVerifyReject      = "verify-reject"
+)
+
+// sasInfo SAS 派生时使用的域分隔字符串，修改会导致新旧客户端无法互相校验
+const sasInfo = "chuan-sas-v1"
+
+// sasEmojiCount SAS 中的表情数量，每个表情6位，共42位
+const sasEmojiCount = 7
+
+// SASEmoji SAS 表情及其对应的单词
+type SASEmoji struct {
+	Emoji string
+	Word  string
+}
+
+// sasEmojis 64个表情，与 Matrix SAS 使用的表情表一致，便于用户辨认
+var sasEmojis = [64]SASEmoji{
+	{"🐶", "dog"}, {"🐱", "cat"}, {"🦁", "lion"}, {"🐎", "horse"},
+	{"🦄", "unicorn"}, {"🐷", "pig"}, {"🐘", "elephant"}, {"🐰", "rabbit"},
+	{"🐼", "panda"}, {"🐓", "rooster"}, {"🐧", "penguin"}, {"🐢", "turtle"},
+	{"🐟", "fish"}, {"🐙", "octopus"}, {"🦋", "butterfly"}, {"🌷", "flower"},
+	{"🌳", "tree"}, {"🌵", "cactus"}, {"🍄", "mushroom"}, {"🌏", "globe"},
+	{"🌙", "moon"}, {"☁️", "cloud"}, {"🔥", "fire"}, {"🍌", "banana"},
+	{"🍎", "apple"}, {"🍓", "strawberry"}, {"🌽", "corn"}, {"🍕", "pizza"},
+	{"🎂", "cake"}, {"❤️", "heart"}, {"😀", "smiley"}, {"🤖", "robot"},
+	{"🎩", "hat"}, {"👓", "glasses"}, {"🔧", "spanner"}, {"🎅", "santa"},
+	{"👍", "thumbs up"}, {"☂️", "umbrella"}, {"⌛", "hourglass"}, {"⏰", "clock"},
+	{"🎁", "gift"}, {"💡", "light bulb"}, {"📕", "book"}, {"✏️", "pencil"},
+	{"📎", "paperclip"}, {"✂️", "scissors"}, {"🔒", "lock"}, {"🔑", "key"},
+	{"🔨", "hammer"}, {"☎️", "telephone"}, {"🏁", "flag"}, {"🚂", "train"},
+	{"🚲", "bicycle"}, {"✈️", "aeroplane"}, {"🚀", "rocket"}, {"🏆", "trophy"},
+	{"⚽", "ball"}, {"🎸", "guitar"}, {"🎺", "trumpet"}, {"🔔", "bell"},
+	{"⚓", "anchor"}, {"🎧", "headphones"}, {"📁", "folder"}, {"📌", "pin"},
+}
+
+// SAS 短认证字符串，双方比对任意一种形式即可
+type SAS struct {
+	Emojis  []SASEmoji
+	Decimal [3]int // 三组 1000-9191 之间的数字
+}
+
+// EmojiString 以空格分隔的表情
+func (s SAS) EmojiString() string {
+	parts := make([]string, len(s.Emojis))
+	for i, e := range s.Emojis {
+		parts[i] = e.Emoji
+	}
+	return strings.Join(parts, " ")
+}
+
+// Words 表情对应的单词
+func (s SAS) Words() []string {
+	words := make([]string, len(s.Emojis))
+	for i, e := range s.Emojis {
+		words[i] = e.Word
+	}
+	return words
+}
+
+// DecimalString 三组数字，以短横线分隔
+func (s SAS) DecimalString() string {
+	return fmt.Sprintf("%d-%d-%d", s.Decimal[0], s.Decimal[1], s.Decimal[2])
+}
+
+// Fingerprint 从 SDP 中提取 DTLS 指纹，返回算法和指纹值
+//
+// 优先使用会话级的 a=fingerprint，没有时使用第一个媒体段中的值。
+func Fingerprint(sdp string) (algorithm string, fingerprint string, err error) {
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "a=fingerprint:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "a=fingerprint:"))
+		if len(fields) != 2 {
+			return "", "", fmt.Errorf("无效的指纹行: %s", line)
+		}
+		return strings.ToLower(fields[0]), strings.ToUpper(fields[1]), nil
+	}
+	return "", "", fmt.Errorf("SDP 中没有 DTLS 指纹")
+}
+
+// ComputeSAS 根据 offer 和 answer 双方的 DTLS 指纹计算短认证字符串
+//
+// 两端都以 offer 方指纹在前、answer 方指纹在后的顺序计算，结果一致说明
+// 双方看到的是同一组 DTLS 证书，信令服务器没有替换 SDP 中的指纹。
+func ComputeSAS(offerFingerprint string, answerFingerprint string) SAS {
+	h := sha256.New()
+	writeField := func(s string) {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(s)))
+		h.Write(length[:])
+		h.Write([]byte(s))
+	}
+	writeField(sasInfo)
+	writeField(normalizeFingerprint(offerFingerprint))
+	writeField(normalizeFingerprint(answerFingerprint))
+	sum := h.Sum(nil)
+
+	var sas SAS
+
+	// 前 42 位：7 个表情，每个 6 位
+	bits := binary.BigEndian.Uint64(sum[:8])
+	for i := 0; i < sasEmojiCount; i++ {
+		index := (bits >> (58 - 6*uint(i))) & 0x3f
+		sas.Emojis = append(sas.Emojis, sasEmojis[index])
+	}
+
+	// 前 39 位：3 组数字，每组 13 位，加 1000 避免前导零
+	for i := 0; i < 3; i++ {
+		sas.Decimal[i] = int((bits>>(51-13*uint(i)))&0x1fff) + 1000
+	}
+
+	return sas
+}
+
+// ComputeSASFromSDP 从 offer 和 answer 的 SDP 中提取指纹并计算短认证字符串
+func ComputeSASFromSDP(offerSDP string, answerSDP string) (SAS, error) {
+	_, offerFingerprint, err := Fingerprint(offerSDP)
+	if err != nil {
+		return SAS{}, fmt.Errorf("offer: %w", err)
+	}
+	_, answerFingerprint, err := Fingerprint(answerSDP)
+	if err != nil {
+		return SAS{}, fmt.Errorf("answer: %w", err)
+	}
+	return ComputeSAS(offerFingerprint, answerFingerprint), nil
+}
+
+// SendFingerprint 发送本端的 DTLS 指纹，供对方计算 SAS
+func (c *Client) SendFingerprint(sdp string) error {
+	algorithm, fingerprint, err := Fingerprint(sdp)
+	if err != nil {
+		return err
+	}
+	return c.Send(MessageVerifyFingerprint, map[string]string{
+		"algorithm":   algorithm,
+		"fingerprint": fingerprint,
+	})
+}
+
+// normalizeFingerprint 统一指纹格式：去掉空白并转为大写
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToUpper(strings.TrimSpace(fingerprint))
+}